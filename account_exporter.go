@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+// AccountExporter collects metrics for a Cloudflare account.
+type AccountExporter struct {
+	account    collector.Account
+	collectors []collector.AccountCollector
+	breakers   map[string]*collector.Breaker
+	schedules  map[string]*collector.Schedule
+	timeouts   map[string]time.Duration
+
+	componentProcessingTime *prometheus.Desc
+	breakerState            *prometheus.Desc
+	dataAge                 *prometheus.Desc
+}
+
+// NewAccountExporter returns an initialized AccountExporter running the named
+// account collectors, or all registered account collectors if none are named.
+func NewAccountExporter(api collector.API, account collector.Account, opts collector.Options, collectorNames ...string) (*AccountExporter, error) {
+	collectors, err := collector.NewAccount(api, account, opts, collectorNames...)
+	if err != nil {
+		return nil, err
+	}
+
+	constantLabels := collector.AccountLabels(account)
+
+	breakers := make(map[string]*collector.Breaker, len(collectors))
+	schedules := make(map[string]*collector.Schedule, len(collectors))
+	for _, c := range collectors {
+		breakers[c.Name()] = collector.NewBreaker(opts.Breaker)
+		schedules[c.Name()] = opts.Schedule(c.Name())
+	}
+
+	return &AccountExporter{
+		account:    account,
+		collectors: collectors,
+		breakers:   breakers,
+		schedules:  schedules,
+		timeouts:   opts.Timeouts,
+		componentProcessingTime: prometheus.NewDesc(
+			"cloudflare_exporter_account_component_processing_time_seconds",
+			"Account component processing time in seconds",
+			[]string{"component"},
+			constantLabels,
+		),
+		breakerState: prometheus.NewDesc(
+			"cloudflare_exporter_account_circuit_breaker_state",
+			"State of the circuit breaker guarding each account component (0 closed, 1 open, 2 half-open)",
+			[]string{"component"},
+			constantLabels,
+		),
+		dataAge: prometheus.NewDesc(
+			"cloudflare_exporter_account_data_age_seconds",
+			"How long ago the metrics served for each account component with an interval were collected",
+			[]string{"component"},
+			constantLabels,
+		),
+	}, nil
+}
+
+// Describe describes all the metrics exported by the AccountExporter. It
+// implements prometheus.Collector.
+func (e *AccountExporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range e.collectors {
+		c.Describe(ch)
+	}
+
+	ch <- e.componentProcessingTime
+	ch <- e.breakerState
+	ch <- e.dataAge
+}
+
+// Collect fetches the statistics for the configured Cloudflare account, and
+// delivers them as Prometheus metrics. It implements prometheus.Collector.
+func (e *AccountExporter) Collect(ch chan<- prometheus.Metric) {
+	e.collectOnly(context.Background(), ch, nil)
+}
+
+// collectOnly is Collect running only the collectors in only, or all of
+// them if only is nil, within the deadline of ctx.
+func (e *AccountExporter) collectOnly(ctx context.Context, ch chan<- prometheus.Metric, only map[string]bool) {
+	log.Debugf("Getting data for account %s (%s)", e.account.Name, e.account.ID)
+
+	for _, c := range e.collectors {
+		if only != nil && !only[c.Name()] {
+			continue
+		}
+		breaker := e.breakers[c.Name()]
+		schedule := e.schedules[c.Name()]
+		componentStart := time.Now()
+		if !schedule.Due(componentStart) {
+			log.Debugf("Serving last run of %s collector for account %s, next run is not due yet", c.Name(), e.account.Name)
+			schedule.Replay(ch)
+		} else if ctx.Err() != nil {
+			log.Debugf("Skipping %s collector for account %s, scrape timeout reached", c.Name(), e.account.Name)
+		} else if !breaker.Allow(componentStart) {
+			log.Debugf("Skipping %s collector for account %s, circuit is open", c.Name(), e.account.Name)
+		} else if kept, err := e.collect(ctx, c, schedule != nil, ch); err == errScrapeTimeout {
+			log.Warnf("Abandoned %s collector for account %s, scrape timeout reached", c.Name(), e.account.Name)
+		} else if err != nil {
+			breaker.Failure(time.Now())
+			collectorErrors.reportAccount(e.account.Name, c.Name(), err)
+		} else {
+			breaker.Success()
+			schedule.Record(componentStart, kept)
+			collectorErrors.resolveAccount(e.account.Name, c.Name())
+			ch <- prometheus.MustNewConstMetric(e.componentProcessingTime, prometheus.GaugeValue, time.Since(componentStart).Seconds(), c.Name())
+		}
+		ch <- prometheus.MustNewConstMetric(e.breakerState, prometheus.GaugeValue, float64(breaker.State(time.Now())), c.Name())
+		if age, ok := schedule.Age(time.Now()); ok {
+			ch <- prometheus.MustNewConstMetric(e.dataAge, prometheus.GaugeValue, age.Seconds(), c.Name())
+		}
+	}
+}
+
+// collect runs c within its timeout, forwarding its metrics to ch. If keep
+// is set, the metrics are also returned. Like collectCounted, c is abandoned
+// with what it sent so far when the scrape deadline or its timeout passes,
+// returning errScrapeTimeout or errCollectorTimeout respectively.
+func (e *AccountExporter) collect(ctx context.Context, c collector.AccountCollector, keep bool, ch chan<- prometheus.Metric) ([]prometheus.Metric, error) {
+	collectorCtx, cancel := collectorContext(ctx, e.timeouts[c.Name()])
+	defer cancel()
+	timeout := func() error {
+		if ctx.Err() != nil {
+			return errScrapeTimeout
+		}
+		return errCollectorTimeout
+	}
+
+	var kept []prometheus.Metric
+	forwarded := make(chan prometheus.Metric)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Collect(collectorCtx, e.account, forwarded)
+		close(forwarded)
+	}()
+	for {
+		select {
+		case m, ok := <-forwarded:
+			if !ok {
+				err := <-errc
+				if err != nil && collectorCtx.Err() != nil {
+					return kept, timeout()
+				}
+				return kept, err
+			}
+			ch <- m
+			if keep {
+				kept = append(kept, m)
+			}
+		case <-collectorCtx.Done():
+			go func() {
+				for range forwarded {
+				}
+			}()
+			return kept, timeout()
+		}
+	}
+}
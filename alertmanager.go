@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// Alerts forwarded to Alertmanager resolve after alertResolveAfter, as
+// Cloudflare does not notify when most conditions end. Forwarding gives up
+// after alertForwardTimeout.
+const (
+	alertResolveAfter   = time.Hour
+	alertForwardTimeout = 10 * time.Second
+)
+
+// defaultAlertSeverity is the severity of forwarded alerts if no alert
+// types are mapped to severities.
+const defaultAlertSeverity = "warning"
+
+// alertmanagerAlert is an alert as posted to the Alertmanager v2 API.
+type alertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+}
+
+// alertForwarder forwards Cloudflare notifications received by webhook to
+// Alertmanager, so they page through the existing routes.
+type alertForwarder struct {
+	url    string
+	client *http.Client
+	// severities maps alert types to the severity label of their alerts.
+	// If not empty, other alert types are not forwarded.
+	severities map[string]string
+
+	forwarded *prometheus.CounterVec
+}
+
+// newAlertForwarder returns a forwarder posting alerts to the Alertmanager
+// at url, and registers its metrics in reg.
+func newAlertForwarder(url string, client *http.Client, severities map[string]string, reg prometheus.Registerer) *alertForwarder {
+	f := &alertForwarder{
+		url:        strings.TrimSuffix(url, "/") + "/api/v2/alerts",
+		client:     client,
+		severities: severities,
+		forwarded: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cloudflare_exporter_alertmanager_forwards_total",
+				Help: "Cloudflare notifications forwarded to Alertmanager, by result (success, error or skipped).",
+			},
+			[]string{"result"},
+		),
+	}
+	reg.MustRegister(f.forwarded)
+	return f
+}
+
+// alert returns the alert for n, sent at sent, and false if its alert type
+// is not forwarded.
+func (f *alertForwarder) alert(n webhookNotification, sent time.Time) (alertmanagerAlert, bool) {
+	severity := defaultAlertSeverity
+	if len(f.severities) > 0 {
+		var ok bool
+		if severity, ok = f.severities[n.AlertType]; !ok {
+			return alertmanagerAlert{}, false
+		}
+	}
+	labels := map[string]string{
+		"alertname":  "CloudflareNotification",
+		"alert_type": n.AlertType,
+		"severity":   severity,
+	}
+	if zone := n.zoneName(); zone != "" {
+		labels["zone_name"] = zone
+	}
+	return alertmanagerAlert{
+		Labels:      labels,
+		Annotations: map[string]string{"summary": n.Name, "description": n.Text},
+		StartsAt:    sent,
+		EndsAt:      sent.Add(alertResolveAfter),
+	}, true
+}
+
+// forward posts the alert for n to Alertmanager. Failures are logged and
+// counted, as Cloudflare has already been answered.
+func (f *alertForwarder) forward(n webhookNotification, sent time.Time) {
+	alert, ok := f.alert(n, sent)
+	if !ok {
+		f.forwarded.WithLabelValues("skipped").Inc()
+		return
+	}
+	if err := f.post([]alertmanagerAlert{alert}); err != nil {
+		f.forwarded.WithLabelValues("error").Inc()
+		log.Errorf("failed to forward %s notification to Alertmanager: %s", n.AlertType, err)
+		return
+	}
+	f.forwarded.WithLabelValues("success").Inc()
+}
+
+func (f *alertForwarder) post(alerts []alertmanagerAlert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, f.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgentHeader)
+	ctx, cancel := context.WithTimeout(context.Background(), alertForwardTimeout)
+	defer cancel()
+	res, err := f.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+// apiIDPattern matches the IDs of zones, accounts and other objects in API
+// paths, which are replaced to keep the endpoint label bounded.
+var apiIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// apiCallCounter counts the calls made to the Cloudflare API by zone and
+// endpoint, for planning capacity against the account rate limit. Cached
+// responses are not counted, as they make no call.
+type apiCallCounter struct {
+	host         string
+	zoneIdentity string
+	calls        *prometheus.CounterVec
+	// unknownZone are the zone label values of calls not about a monitored
+	// zone.
+	unknownZone []string
+
+	mu    sync.RWMutex
+	zones map[string]cloudflare.Zone
+}
+
+// newAPICallCounter returns a counter of the calls to the API at host,
+// labelling zones by the labels zoneIdentity selects.
+func newAPICallCounter(host, zoneIdentity string) *apiCallCounter {
+	labels := collector.ZoneIdentityLabels(zoneIdentity)
+	return &apiCallCounter{
+		host:         host,
+		zoneIdentity: zoneIdentity,
+		calls: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cloudflare_exporter_api_calls_total",
+				Help: "Calls made to the Cloudflare API by zone and endpoint. Calls not about a single zone have empty zone labels.",
+			},
+			append(labels, "endpoint"),
+		),
+		unknownZone: make([]string, len(labels)),
+		zones:       map[string]cloudflare.Zone{},
+	}
+}
+
+// setZones makes calls about zones be counted under their labels.
+func (c *apiCallCounter) setZones(zones []cloudflare.Zone) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, zone := range zones {
+		c.zones[zone.ID] = zone
+	}
+}
+
+// roundTripper returns a RoundTripper counting the API calls made through
+// next. Calls to other hosts, such as the status page, are not counted.
+func (c *apiCallCounter) roundTripper(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == c.host {
+			zoneID, endpoint := apiCallEndpoint(req)
+			c.mu.RLock()
+			zone, ok := c.zones[zoneID]
+			c.mu.RUnlock()
+			values := c.unknownZone
+			if ok {
+				values = collector.ZoneIdentityValues(zone, c.zoneIdentity)
+			}
+			c.calls.WithLabelValues(append(values, endpoint)...).Inc()
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+// apiCallEndpoint returns the zone req is about, if any, and its endpoint:
+// the path after the API version with IDs replaced by ":id". GraphQL calls
+// are about the zone in their zoneTag variable, and their endpoint is
+// "graphql".
+func apiCallEndpoint(req *http.Request) (zoneID, endpoint string) {
+	path := strings.TrimPrefix(req.URL.Path, "/client/v4")
+	if strings.HasSuffix(path, "/graphql") && req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err == nil {
+			var request struct {
+				Variables struct {
+					ZoneTag string `json:"zoneTag"`
+				} `json:"variables"`
+			}
+			json.Unmarshal(body, &request)
+			zoneID = request.Variables.ZoneTag
+		}
+		return zoneID, "graphql"
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if apiIDPattern.MatchString(segment) {
+			if i > 0 && segments[i-1] == "zones" {
+				zoneID = segment
+			}
+			segments[i] = ":id"
+		}
+	}
+	return zoneID, "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+func TestAPICallCounter(t *testing.T) {
+	// Zones of the same name in two accounts.
+	zones := []cloudflare.Zone{
+		{ID: "023e105f4ecef8ad9ca31a8372d0c353", Name: "example.com"},
+		{ID: "9a7806061c88ada191ed06f989cc3dac", Name: "example.com"},
+	}
+	c := newAPICallCounter("api.cloudflare.com", collector.ZoneIdentityBoth)
+	c.setZones(zones)
+	rt := c.roundTripper(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return httptest.NewRecorder().Result(), nil
+	}))
+	for _, path := range []string{
+		"/client/v4/zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records",
+		"/client/v4/zones/9a7806061c88ada191ed06f989cc3dac/dns_records",
+		"/client/v4/zones/9a7806061c88ada191ed06f989cc3dac/dns_records",
+		"/client/v4/zones",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "https://api.cloudflare.com"+path, nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := `# HELP cloudflare_exporter_api_calls_total Calls made to the Cloudflare API by zone and endpoint. Calls not about a single zone have empty zone labels.
+# TYPE cloudflare_exporter_api_calls_total counter
+cloudflare_exporter_api_calls_total{endpoint="/zones",zone_id="",zone_name=""} 1
+cloudflare_exporter_api_calls_total{endpoint="/zones/:id/dns_records",zone_id="023e105f4ecef8ad9ca31a8372d0c353",zone_name="example.com"} 1
+cloudflare_exporter_api_calls_total{endpoint="/zones/:id/dns_records",zone_id="9a7806061c88ada191ed06f989cc3dac",zone_name="example.com"} 2
+`
+	if err := testutil.CollectAndCompare(c.calls, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+var apiErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cloudflare_exporter_api_errors_total",
+		Help: "Failed collections by component and class of Cloudflare API error (auth, rate_limit, not_entitled, timeout, server_error or other).",
+	},
+	[]string{"component", "class"},
+)
+
+func init() {
+	registry.MustRegister(apiErrors)
+}
+
+// collectorErrors logs and counts collector failures of all zones and
+// accounts. main replaces it by one labelling zones with the configured
+// identity.
+var collectorErrors = newErrorReporter(collector.ZoneIdentityBoth)
+
+// errorReporter logs collector failures. Failures because a zone or account
+// does not have a product are expected to persist, so they are only logged
+// once, at info level, and exported as unavailable features instead.
+type errorReporter struct {
+	zoneIdentity string
+	// zones and accounts flag the collectors that fail because a zone or
+	// account does not have their product, until they succeed again.
+	zones    *prometheus.GaugeVec
+	accounts *prometheus.GaugeVec
+
+	mu sync.Mutex
+	// flagged holds the label values of the unavailable features by zone or
+	// account and component, so they are cleared even after a rename.
+	flagged map[string][]string
+}
+
+// newErrorReporter returns an errorReporter labelling zones by the labels
+// zoneIdentity selects, as on zone metrics.
+func newErrorReporter(zoneIdentity string) *errorReporter {
+	return &errorReporter{
+		zoneIdentity: zoneIdentity,
+		zones: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloudflare_exporter_feature_unavailable",
+				Help: "Components that fail because the zone's plan does not include their product, with a constant '1' value.",
+			},
+			append(collector.ZoneIdentityLabels(zoneIdentity), "feature"),
+		),
+		accounts: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloudflare_exporter_account_feature_unavailable",
+				Help: "Account components that fail because the account does not include their product, with a constant '1' value.",
+			},
+			[]string{"account", "feature"},
+		),
+		flagged: map[string][]string{},
+	}
+}
+
+func (r *errorReporter) Describe(ch chan<- *prometheus.Desc) {
+	r.zones.Describe(ch)
+	r.accounts.Describe(ch)
+}
+
+func (r *errorReporter) Collect(ch chan<- prometheus.Metric) {
+	r.zones.Collect(ch)
+	r.accounts.Collect(ch)
+}
+
+// reportZone records that component failed for zone.
+func (r *errorReporter) reportZone(zone cloudflare.Zone, component string, err error) {
+	r.report("zone "+zone.Name, "zone/"+zone.ID, r.zones, collector.ZoneIdentityValues(zone, r.zoneIdentity), component, err)
+}
+
+// resolveZone records that component succeeded for zone.
+func (r *errorReporter) resolveZone(zone cloudflare.Zone, component string) {
+	r.resolve("zone/"+zone.ID, r.zones, component)
+}
+
+// reportAccount records that component failed for the account called name.
+func (r *errorReporter) reportAccount(name, component string, err error) {
+	r.report("account "+name, "account/"+name, r.accounts, []string{name}, component, err)
+}
+
+// resolveAccount records that component succeeded for the account called
+// name.
+func (r *errorReporter) resolveAccount(name, component string) {
+	r.resolve("account/"+name, r.accounts, component)
+}
+
+// report records that component failed for subject, a zone or account
+// identified by key and flagged in unavailable with the label values
+// before feature.
+func (r *errorReporter) report(subject, key string, unavailable *prometheus.GaugeVec, values []string, component string, err error) {
+	class := collector.ClassifyError(err)
+	apiErrors.WithLabelValues(component, class).Inc()
+	if class != collector.ErrorClassNotEntitled {
+		log.Errorf("%s collector failed for %s: %s", component, subject, err)
+		return
+	}
+
+	values = append(values, component)
+	key += "/" + component
+	r.mu.Lock()
+	previous, logged := r.flagged[key]
+	r.flagged[key] = values
+	r.mu.Unlock()
+	if logged {
+		// The zone may have been renamed since.
+		unavailable.DeleteLabelValues(previous...)
+	}
+	unavailable.WithLabelValues(values...).Set(1)
+	if logged {
+		log.Debugf("%s collector failed for %s: %s", component, subject, err)
+		return
+	}
+	log.Infof("%s collector is not available for %s, further failures are only logged at debug level: %s", component, subject, err)
+}
+
+// resolve records that component succeeded for the zone or account
+// identified by key, so it is no longer unavailable and losing its product
+// is logged again.
+func (r *errorReporter) resolve(key string, unavailable *prometheus.GaugeVec, component string) {
+	key += "/" + component
+	r.mu.Lock()
+	values, logged := r.flagged[key]
+	delete(r.flagged, key)
+	r.mu.Unlock()
+	if logged {
+		unavailable.DeleteLabelValues(values...)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+func TestErrorReporterZoneLabels(t *testing.T) {
+	notEntitled := errors.New("error from makeRequest: this zone is not entitled to use this feature")
+	zone := cloudflare.Zone{ID: "023e105f4ecef8ad9ca31a8372d0c353", Name: "example.com"}
+	for identity, labels := range map[string]string{
+		collector.ZoneIdentityBoth: `feature="waf",zone_id="023e105f4ecef8ad9ca31a8372d0c353",zone_name="example.com"`,
+		collector.ZoneIdentityName: `feature="waf",zone_name="example.com"`,
+		collector.ZoneIdentityID:   `feature="waf",zone_id="023e105f4ecef8ad9ca31a8372d0c353"`,
+	} {
+		r := newErrorReporter(identity)
+		r.reportZone(zone, "waf", notEntitled)
+		want := `# HELP cloudflare_exporter_feature_unavailable Components that fail because the zone's plan does not include their product, with a constant '1' value.
+# TYPE cloudflare_exporter_feature_unavailable gauge
+cloudflare_exporter_feature_unavailable{` + labels + `} 1
+`
+		if err := testutil.CollectAndCompare(r.zones, strings.NewReader(want)); err != nil {
+			t.Errorf("identity %s: %s", identity, err)
+		}
+
+		// Unavailable features are cleared by the zone's ID, even if it
+		// was renamed since.
+		renamed := zone
+		renamed.Name = "example.org"
+		r.resolveZone(renamed, "waf")
+		if err := testutil.CollectAndCompare(r.zones, strings.NewReader("")); err != nil {
+			t.Errorf("identity %s: %s", identity, err)
+		}
+	}
+}
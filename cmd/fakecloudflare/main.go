@@ -0,0 +1,25 @@
+// Command fakecloudflare serves the canned responses from the fakeapi
+// package so the exporter can be run locally without Cloudflare credentials:
+//
+//	go run ./cmd/fakecloudflare --listen-address=:9198
+//	./cloudflare_exporter --cloudflare.api-key=fake --cloudflare.api-email=fake@example.com \
+//	  --cloudflare.api-url=http://localhost:9198/client/v4 \
+//	  --status.summary-url=http://localhost:9198/api/v2/summary.json
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare_exporter/internal/fakeapi"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+func main() {
+	listenAddress := kingpin.Flag("listen-address", "Address to serve the fake API on").Default(":9198").String()
+	kingpin.HelpFlag.Short('h')
+	kingpin.Parse()
+
+	log.Infoln("Serving fake Cloudflare API on", *listenAddress)
+	log.Fatal(http.ListenAndServe(*listenAddress, fakeapi.Handler()))
+}
@@ -0,0 +1,133 @@
+// Command genpops generates the built-in PoP catalog of the collector
+// package from the Cloudflare status page. It is run by go generate:
+//
+//	go generate ./collector
+//
+// PoPs are listed on the status page as "<name> - (<code>)" components,
+// grouped by region. Codes are upper-cased and the catalog is sorted by
+// code, so regenerating only changes the file when the PoPs do.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var popIDRegex = regexp.MustCompile(`^(.*) - \((.*)\)$`)
+
+type pop struct {
+	Name   string
+	Code   string
+	Region string
+}
+
+// extraPops are colo IDs that analytics report but the status page may not
+// list as components. They are only added when the page lacks their code,
+// so the page's own name wins once it lists them.
+var extraPops = []pop{
+	// SFO is reported by zone analytics for traffic served in San
+	// Francisco, which the status page folds into its San Jose component.
+	{Name: "San Francisco, CA, United States", Code: "SFO", Region: "North America"},
+	// SJC-PIG is the second San Jose facility. Analytics break it out
+	// under its own colo ID, but it has no status page component.
+	{Name: "San Jose (Alternate), CA, United States", Code: "SJC-PIG", Region: "North America"},
+}
+
+type summary struct {
+	Components []struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Group   bool   `json:"group"`
+		GroupID string `json:"group_id"`
+	} `json:"components"`
+}
+
+func main() {
+	url := flag.String("url", "https://www.cloudflarestatus.com/api/v2/summary.json", "URL of the Cloudflare status page summary")
+	output := flag.String("o", "pops_generated.go", "File to write the catalog to")
+	flag.Parse()
+
+	pops, err := fetchPops(*url)
+	if err != nil {
+		log.Fatalf("failed to get PoPs: %s", err)
+	}
+	src, err := render(pops)
+	if err != nil {
+		log.Fatalf("failed to render PoPs: %s", err)
+	}
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		log.Fatalf("failed to write PoPs: %s", err)
+	}
+	log.Printf("Wrote %d PoPs to %s", len(pops), *output)
+}
+
+// fetchPops returns the PoPs listed on the status page at url, with
+// extraPops added, sorted by code.
+func fetchPops(url string) ([]pop, error) {
+	res, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	var s summary
+	if err := json.NewDecoder(res.Body).Decode(&s); err != nil {
+		return nil, err
+	}
+
+	regions := map[string]string{}
+	for _, c := range s.Components {
+		if c.Group {
+			regions[c.ID] = strings.TrimSpace(c.Name)
+		}
+	}
+	byCode := map[string]pop{}
+	for _, c := range s.Components {
+		matches := popIDRegex.FindStringSubmatch(strings.TrimSpace(c.Name))
+		if c.Group || matches == nil {
+			continue
+		}
+		code := strings.ToUpper(strings.TrimSpace(matches[2]))
+		byCode[code] = pop{Name: strings.TrimSpace(matches[1]), Code: code, Region: regions[c.GroupID]}
+	}
+	if len(byCode) == 0 {
+		return nil, fmt.Errorf("no PoPs found in the status page summary")
+	}
+	for _, p := range extraPops {
+		if _, ok := byCode[p.Code]; !ok {
+			byCode[p.Code] = p
+		}
+	}
+
+	pops := make([]pop, 0, len(byCode))
+	for _, p := range byCode {
+		pops = append(pops, p)
+	}
+	sort.Slice(pops, func(i, j int) bool { return pops[i].Code < pops[j].Code })
+	return pops, nil
+}
+
+// render returns the gofmt'ed source of the catalog.
+func render(pops []pop) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by genpops from the Cloudflare status page; DO NOT EDIT.\n\n")
+	buf.WriteString("package collector\n\n")
+	buf.WriteString("// builtinPops are the PoPs known when the exporter was built.\n")
+	buf.WriteString("var builtinPops = []Pop{\n")
+	for _, p := range pops {
+		fmt.Fprintf(&buf, "\t{Name: %q, Code: %q, Region: %q},\n", p.Name, p.Code, p.Region)
+	}
+	buf.WriteString("}\n")
+	return format.Source(buf.Bytes())
+}
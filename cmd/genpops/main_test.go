@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const testSummary = `{"components": [
+	{"id": "eu", "name": "Europe", "group": true},
+	{"id": "na", "name": "North America", "group": true},
+	{"id": "api", "name": "Cloudflare API"},
+	{"id": "ams", "name": "Amsterdam, Netherlands - (AMS)", "group_id": "eu"},
+	{"id": "sfo", "name": "San Francisco, CA, United States - (sfo) ", "group_id": "na"}
+]}`
+
+func TestFetchPops(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testSummary))
+	}))
+	defer server.Close()
+
+	pops, err := fetchPops(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pop{
+		{Name: "Amsterdam, Netherlands", Code: "AMS", Region: "Europe"},
+		{Name: "San Francisco, CA, United States", Code: "SFO", Region: "North America"},
+		{Name: "San Jose (Alternate), CA, United States", Code: "SJC-PIG", Region: "North America"},
+	}
+	if !reflect.DeepEqual(pops, want) {
+		t.Errorf("got %+v, want %+v", pops, want)
+	}
+}
+
+func TestFetchPopsEmpty(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"components": []}`))
+	}))
+	defer server.Close()
+
+	if _, err := fetchPops(server.URL); err == nil {
+		t.Error("expected an error for a summary without PoPs")
+	}
+}
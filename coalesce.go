@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// flight coalesces concurrent runs of a zone's collector, so that scrapes
+// arriving together, e.g. from a pair of HA Prometheus servers, share one
+// run and its API calls, and get identical metrics.
+type flight struct {
+	mu   sync.Mutex
+	call *flightCall
+}
+
+// flightCall is a run in progress. metrics and err are set before done is
+// closed.
+type flightCall struct {
+	done    chan struct{}
+	metrics []prometheus.Metric
+	err     error
+}
+
+// do runs collect, which sends metrics to ch and appends them to the slice
+// it is passed, unless a run is already in progress. In that case it waits
+// for that run and sends its metrics to ch instead, or gives up with
+// errScrapeTimeout when ctx is done first. Either way, the metrics sent are
+// appended to kept if it is not nil. It returns their number and whether
+// the run was another scrape's, whose outcome that scrape accounts for.
+func (f *flight) do(ctx context.Context, ch chan<- prometheus.Metric, kept *[]prometheus.Metric, collect func(chan<- prometheus.Metric, *[]prometheus.Metric) (int, error)) (int, bool, error) {
+	f.mu.Lock()
+	if call := f.call; call != nil {
+		f.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return 0, true, errScrapeTimeout
+		}
+		for _, m := range call.metrics {
+			ch <- m
+		}
+		if kept != nil {
+			*kept = append(*kept, call.metrics...)
+		}
+		return len(call.metrics), true, call.err
+	}
+	call := &flightCall{done: make(chan struct{})}
+	f.call = call
+	f.mu.Unlock()
+
+	metrics := []prometheus.Metric{}
+	n, err := collect(ch, &metrics)
+	if kept != nil {
+		*kept = append(*kept, metrics...)
+	}
+	call.metrics, call.err = metrics, err
+	f.mu.Lock()
+	f.call = nil
+	f.mu.Unlock()
+	close(call.done)
+	return n, false, err
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+// collectParam selects the collectors to run for a scrape, e.g.
+// /metrics?collect[]=dns_analytics&collect[]=dashboard_analytics, so
+// separate Prometheus jobs can scrape cheap collectors often and expensive
+// ones rarely. statusCollector selects the status page.
+const (
+	collectParam    = "collect[]"
+	statusCollector = "status"
+)
+
+// selectableExporter is an exporter that can run a subset of its
+// collectors.
+type selectableExporter interface {
+	prometheus.Collector
+	collectOnly(ctx context.Context, ch chan<- prometheus.Metric, only map[string]bool)
+}
+
+// exporterSelection collects the selected collectors of an exporter within
+// the deadline of ctx.
+type exporterSelection struct {
+	ctx      context.Context
+	exporter selectableExporter
+	only     map[string]bool
+}
+
+func (s exporterSelection) Describe(ch chan<- *prometheus.Desc) { s.exporter.Describe(ch) }
+
+func (s exporterSelection) Collect(ch chan<- prometheus.Metric) {
+	s.exporter.collectOnly(s.ctx, ch, s.only)
+}
+
+// collectorSelector serves scrapes from the collectors of its exporters,
+// registered anew for each scrape so they collect within its deadline, see
+// withScrapeDeadline. Scrapes with collect[] parameters only run the
+// selected collectors. They are not shared with concurrent scrapes, leave
+// out the exporter's own metrics and are limited to opts.MaxConcurrent
+// separately from other scrapes.
+type collectorSelector struct {
+	exporters []selectableExporter
+	status    selectableExporter
+}
+
+// registry returns a registry running the collectors in only, or all of
+// them if only is nil, within the deadline of ctx. The status page is
+// included if only is nil or selects statusCollector.
+func (s *collectorSelector) registry(ctx context.Context, only map[string]bool) (*prometheus.Registry, error) {
+	reg := prometheus.NewPedanticRegistry()
+	for _, e := range s.exporters {
+		if err := reg.Register(exporterSelection{ctx: ctx, exporter: e, only: only}); err != nil {
+			return nil, err
+		}
+	}
+	if s.status != nil && (only == nil || only[statusCollector]) {
+		if err := reg.Register(exporterSelection{ctx: ctx, exporter: s.status}); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+// handler serves scrapes, along with the metrics in own for those without
+// collect[] parameters.
+func (s *collectorSelector) handler(own *prometheus.Registry, opts scrapeOpts) http.Handler {
+	full := metricsHandler(own, opts, func(r *http.Request) (prometheus.Gatherer, error) {
+		reg, err := s.registry(r.Context(), nil)
+		if err != nil {
+			return nil, err
+		}
+		return prometheus.Gatherers{own, reg}, nil
+	})
+	selected := scrapeOpts{
+		MaxConcurrent:          opts.MaxConcurrent,
+		TimeoutOffset:          opts.TimeoutOffset,
+		DisableExporterMetrics: true,
+		MaxResponseSize:        opts.MaxResponseSize,
+		WarnResponseSize:       opts.WarnResponseSize,
+	}
+	selective := metricsHandler(nil, selected, func(r *http.Request) (prometheus.Gatherer, error) {
+		only := map[string]bool{}
+		for _, name := range r.URL.Query()[collectParam] {
+			only[name] = true
+		}
+		return s.registry(r.Context(), only)
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names := r.URL.Query()[collectParam]
+		if len(names) == 0 {
+			full.ServeHTTP(w, r)
+			return
+		}
+		// A misspelt collector would otherwise make for a successful,
+		// empty scrape.
+		valid := s.names()
+		var unknown []string
+		for _, name := range names {
+			if i := sort.SearchStrings(valid, name); i == len(valid) || valid[i] != name {
+				unknown = append(unknown, name)
+			}
+		}
+		if len(unknown) > 0 {
+			http.Error(w, fmt.Sprintf("unknown collectors %s, valid collectors are %s", strings.Join(unknown, ", "), strings.Join(valid, ", ")), http.StatusBadRequest)
+			return
+		}
+		selective.ServeHTTP(w, r)
+	})
+}
+
+// names returns the collectors collect[] can select, sorted.
+func (s *collectorSelector) names() []string {
+	names := append(collector.Names(), collector.AccountNames()...)
+	if s.status != nil {
+		names = append(names, statusCollector)
+	}
+	sort.Strings(names)
+	return names
+}
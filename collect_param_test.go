@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollectParamUnknown(t *testing.T) {
+	s := &collectorSelector{}
+	h := s.handler(prometheus.NewRegistry(), scrapeOpts{})
+	r := httptest.NewRequest(http.MethodGet, "/metrics?collect[]=dns_analytics&collect[]=dns_analytcs", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d for a misspelt collector, want %d", w.Code, http.StatusBadRequest)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "unknown collectors dns_analytcs,") || !strings.Contains(body, "dashboard_analytics") {
+		t.Errorf("got body %q, want the unknown and the valid collectors", body)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/metrics?collect[]=dns_analytics", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("got status %d for a valid collector, want %d", w.Code, http.StatusOK)
+	}
+}
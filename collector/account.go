@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+// Account identifies a Cloudflare account owning monitored zones.
+type Account struct {
+	ID   string
+	Name string
+}
+
+// AccountCollector collects a single family of metrics for a Cloudflare
+// account. Account-wide data is collected once per account rather than once
+// per zone, which would duplicate it.
+type AccountCollector interface {
+	// Name returns the name the collector is registered under.
+	Name() string
+	// Describe sends the descriptors of every metric the collector emits.
+	Describe(ch chan<- *prometheus.Desc)
+	// Collect fetches data for account and sends the resulting metrics to ch.
+	Collect(ctx context.Context, account Account, ch chan<- prometheus.Metric) error
+}
+
+// AccountFactory builds an AccountCollector for an account.
+type AccountFactory func(api API, account Account, opts Options) AccountCollector
+
+var accountFactories = make(map[string]AccountFactory)
+
+// RegisterAccount makes an account collector available under name. It is
+// meant to be called from init functions and panics if name is already
+// taken.
+func RegisterAccount(name string, factory AccountFactory) {
+	if _, ok := accountFactories[name]; ok {
+		panic(fmt.Sprintf("account collector %q registered twice", name))
+	}
+	accountFactories[name] = factory
+}
+
+// AccountNames returns the names of all registered account collectors,
+// sorted.
+func AccountNames() []string {
+	names := make([]string, 0, len(accountFactories))
+	for name := range accountFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewAccount builds the named account collectors for account. All
+// registered account collectors are built when no names are given.
+func NewAccount(api API, account Account, opts Options, names ...string) ([]AccountCollector, error) {
+	if len(names) == 0 {
+		names = AccountNames()
+	}
+	if opts.State == nil {
+		opts.State, _ = OpenStateStore("")
+	}
+	collectors := make([]AccountCollector, 0, len(names))
+	for _, name := range names {
+		factory, ok := accountFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown account collector %q", name)
+		}
+		collectors = append(collectors, factory(api, account, opts))
+	}
+	return collectors, nil
+}
+
+// Accounts returns the distinct accounts owning zones, in order of first
+// appearance.
+func Accounts(zones []cloudflare.Zone) []Account {
+	seen := map[string]bool{}
+	accounts := []Account{}
+	for _, zone := range zones {
+		if zone.Account.ID == "" || seen[zone.Account.ID] {
+			continue
+		}
+		seen[zone.Account.ID] = true
+		accounts = append(accounts, Account{ID: zone.Account.ID, Name: zone.Account.Name})
+	}
+	return accounts
+}
+
+// AccountLabels returns the constant labels identifying account on every
+// metric.
+func AccountLabels(account Account) prometheus.Labels {
+	return prometheus.Labels{
+		"account_id":   account.ID,
+		"account_name": account.Name,
+	}
+}
@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterAccount("ai", newAICollector)
+}
+
+const workersAIQuery = `query ($accountTag: string, $since: Time, $until: Time) {
+  viewer {
+    accounts(filter: {accountTag: $accountTag}) {
+      groups: aiInferenceAdaptiveGroups(limit: 10000, filter: {datetime_geq: $since, datetime_lt: $until}) {
+        count
+        sum {
+          totalInputTokens
+          totalOutputTokens
+        }
+        dimensions {
+          modelId
+        }
+      }
+    }
+  }
+}`
+
+const aiGatewayQuery = `query ($accountTag: string, $since: Time, $until: Time) {
+  viewer {
+    accounts(filter: {accountTag: $accountTag}) {
+      groups: aiGatewayRequestsAdaptiveGroups(limit: 10000, filter: {datetime_geq: $since, datetime_lt: $until}) {
+        count
+        dimensions {
+          gateway
+          provider
+          model
+          cached
+        }
+      }
+    }
+  }
+}`
+
+// aiCollector collects Workers AI inference requests and tokens by model,
+// and AI Gateway requests by gateway, provider, model and whether they were
+// served from the gateway's cache, to keep an eye on AI spend. It only runs
+// with Options.AI, as it adds two queries per account.
+type aiCollector struct {
+	gql     *GraphQLClient
+	enabled bool
+	descs   []*prometheus.Desc
+
+	inferenceRequests *prometheus.Desc
+	inferenceTokens   *prometheus.Desc
+	gatewayRequests   *prometheus.Desc
+}
+
+func newAICollector(api API, account Account, opts Options) AccountCollector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: AccountLabels(account),
+	}
+	c := &aiCollector{gql: opts.GraphQL, enabled: opts.AI}
+	c.descs = descTable{
+		{&c.inferenceRequests, metricDef{"workers_ai", "requests", "Number of Workers AI inference requests in the last 5 minutes", []string{"model"}}},
+		{&c.inferenceTokens, metricDef{"workers_ai", "tokens", "Number of tokens Workers AI read or generated in the last 5 minutes", []string{"model", "direction"}}},
+		{&c.gatewayRequests, metricDef{"ai_gateway", "requests", "Number of requests through AI Gateway in the last 5 minutes, by whether they were served from its cache", []string{"gateway", "provider", "model", "cached"}}},
+	}.build(set)
+	return c
+}
+
+func (c *aiCollector) Name() string { return "ai" }
+
+func (c *aiCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *aiCollector) Collect(ctx context.Context, account Account, ch chan<- prometheus.Metric) error {
+	if !c.enabled || c.gql == nil {
+		return nil
+	}
+	inference, err := c.gql.accountGroups(ctx, account.ID, workersAIQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get workers ai analytics from cloudflare: %s", err)
+	}
+	for _, g := range inference {
+		model := g.dimension("modelId")
+		ch <- prometheus.MustNewConstMetric(c.inferenceRequests, prometheus.GaugeValue, g.Count, model)
+		ch <- prometheus.MustNewConstMetric(c.inferenceTokens, prometheus.GaugeValue, g.Sum["totalInputTokens"], model, "input")
+		ch <- prometheus.MustNewConstMetric(c.inferenceTokens, prometheus.GaugeValue, g.Sum["totalOutputTokens"], model, "output")
+	}
+
+	gateway, err := c.gql.accountGroups(ctx, account.ID, aiGatewayQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get ai gateway analytics from cloudflare: %s", err)
+	}
+	for _, g := range gateway {
+		ch <- prometheus.MustNewConstMetric(c.gatewayRequests, prometheus.GaugeValue, g.Count, g.dimension("gateway"), g.dimension("provider"), g.dimension("model"), g.dimension("cached"))
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("always_online", newAlwaysOnlineCollector)
+	RequireFeature("always_online", FeatureProxied)
+}
+
+// waybackURL is the Wayback Machine availability API, which Always Online
+// serves archived pages from when the origin is down.
+const waybackURL = "https://archive.org/wayback/available"
+
+// waybackTimestamp is the layout of Wayback Machine snapshot timestamps.
+const waybackTimestamp = "20060102150405"
+
+// alwaysOnlineCollector collects whether Always Online is enabled and, with
+// Options.Wayback, when the Internet Archive last crawled the zone's apex,
+// as Always Online can only serve pages that were archived.
+type alwaysOnlineCollector struct {
+	cf      API
+	wayback *http.Client
+	descs   []*prometheus.Desc
+
+	enabled   *prometheus.Desc
+	lastCrawl *prometheus.Desc
+}
+
+func newAlwaysOnlineCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &alwaysOnlineCollector{cf: api, wayback: opts.Wayback}
+	c.descs = descTable{
+		{&c.enabled, metricDef{"always_online", "enabled", "Whether Always Online serves archived pages when the origin is down", nil}},
+		{&c.lastCrawl, metricDef{"always_online", "last_crawl_timestamp_seconds", "When the Internet Archive last archived the zone's apex, which Always Online serves from", nil}},
+	}.build(set)
+	return c
+}
+
+func (c *alwaysOnlineCollector) Name() string { return "always_online" }
+
+func (c *alwaysOnlineCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *alwaysOnlineCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	raw, err := c.cf.Raw(http.MethodGet, "/zones/"+zone.ID+"/settings/always_online", nil)
+	if err != nil {
+		return fmt.Errorf("failed to get always online setting from cloudflare: %s", err)
+	}
+	enabled := settingOn(raw)
+	ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, boolToFloat(enabled))
+	if !enabled || c.wayback == nil {
+		return nil
+	}
+
+	// The Wayback Machine being unavailable says nothing about the zone, so
+	// it does not fail the collector.
+	crawled, err := c.archived(ctx, zone.Name)
+	if err != nil {
+		log.Debugf("Failed to get last archive crawl of zone %s: %s", zone.Name, err)
+		return nil
+	}
+	if !crawled.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.lastCrawl, prometheus.GaugeValue, float64(crawled.Unix()))
+	}
+	return nil
+}
+
+// archived returns when the Wayback Machine last archived host
+// successfully, or the zero time if it never did.
+func (c *alwaysOnlineCollector) archived(ctx context.Context, host string) (time.Time, error) {
+	req, err := http.NewRequest(http.MethodGet, waybackURL+"?url="+url.QueryEscape(host), nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	res, err := c.wayback.Do(req.WithContext(ctx))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	var result struct {
+		ArchivedSnapshots struct {
+			Closest struct {
+				Available bool   `json:"available"`
+				Status    string `json:"status"`
+				Timestamp string `json:"timestamp"`
+			} `json:"closest"`
+		} `json:"archived_snapshots"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return time.Time{}, err
+	}
+	closest := result.ArchivedSnapshots.Closest
+	if !closest.Available || closest.Status != "200" {
+		return time.Time{}, nil
+	}
+	return time.Parse(waybackTimestamp, closest.Timestamp)
+}
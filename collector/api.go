@@ -0,0 +1,25 @@
+package collector
+
+import (
+	"encoding/json"
+
+	"github.com/robbiet480/cloudflare-go"
+)
+
+// API is the subset of the Cloudflare API the exporter uses. *cloudflare.API
+// implements it; alternative backends, fixtures and caching decorators can be
+// swapped in by implementing it as well.
+type API interface {
+	ListZones(z ...string) ([]cloudflare.Zone, error)
+	ZoneDetails(zoneID string) (cloudflare.Zone, error)
+	ZoneAnalyticsDashboard(zoneID string, options cloudflare.ZoneAnalyticsOptions) (cloudflare.ZoneAnalyticsData, error)
+	ZoneAnalyticsByColocation(zoneID string, options cloudflare.ZoneAnalyticsOptions) ([]cloudflare.ZoneAnalyticsData, error)
+	ZoneDNSAnalyticsByTime(zoneID string, options cloudflare.ZoneDNSAnalyticsOptions) (cloudflare.ZoneDNSAnalyticsByTimeData, error)
+	OriginCertificates(options cloudflare.OriginCACertificateListOptions) ([]cloudflare.OriginCACertificate, error)
+	AccountMembers(accountID string, pageOpts cloudflare.PaginationOptions) ([]cloudflare.AccountMember, cloudflare.ResultInfo, error)
+	// Raw calls endpoints cloudflare-go has no wrapper for and returns the
+	// result field of the response.
+	Raw(method, endpoint string, data interface{}) (json.RawMessage, error)
+}
+
+var _ API = (*cloudflare.API)(nil)
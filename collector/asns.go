@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("asns", newASNsCollector)
+	RequireFeature("asns", FeatureProxied)
+}
+
+// asnsQuery selects the top client ASNs; %d is the number of ASNs.
+const asnsQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: httpRequestsAdaptiveGroups(limit: %d, orderBy: [count_DESC], filter: {datetime_geq: $since, datetime_lt: $until}) {
+        count
+        dimensions {
+          clientAsn
+          clientASNDescription
+        }
+      }
+    }
+  }
+}`
+
+// asnsCollector collects requests broken out by the autonomous system (AS)
+// of the client from the sampled GraphQL datasets, which points out traffic
+// from hosting providers such as scrapers. Only the top ASNs are exported to
+// bound cardinality.
+type asnsCollector struct {
+	gql   *GraphQLClient
+	top   int
+	descs []*prometheus.Desc
+
+	requests *prometheus.Desc
+}
+
+func newASNsCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &asnsCollector{gql: opts.GraphQL, top: opts.GraphQLASNs}
+	c.descs = descTable{
+		{&c.requests, metricDef{"asn", "sampled_requests", "Approximate number of requests served in the last 5 minutes by client autonomous system, from sampled data", []string{"asn", "asn_description"}}},
+	}.build(set)
+	return c
+}
+
+func (c *asnsCollector) Name() string { return "asns" }
+
+func (c *asnsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *asnsCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if c.top <= 0 || c.gql == nil {
+		return nil
+	}
+	groups, err := c.gql.zoneGroups(ctx, zone.ID, fmt.Sprintf(asnsQuery, c.top))
+	if err != nil {
+		return fmt.Errorf("failed to get sampled ASN analytics from cloudflare: %s", err)
+	}
+	for _, g := range groups {
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.GaugeValue, g.Count, g.dimension("clientAsn"), g.dimension("clientASNDescription"))
+	}
+	return nil
+}
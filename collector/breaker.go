@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit breaker, exported as the value of
+// the breaker state metric.
+type BreakerState int
+
+// Circuit breaker states.
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig configures the circuit breakers guarding each collector of a
+// zone. A zero FailureThreshold disables them.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that open the
+	// circuit.
+	FailureThreshold int
+	// Backoff is how long the circuit stays open after opening.
+	Backoff time.Duration
+	// MaxBackoff caps the backoff, which doubles every time a trial
+	// collection in the half-open state fails.
+	MaxBackoff time.Duration
+}
+
+// Breaker stops calling an API endpoint that keeps failing, e.g. because
+// analytics are not enabled for a zone's plan, for a backoff period instead
+// of retrying on every scrape.
+type Breaker struct {
+	config BreakerConfig
+
+	mu        sync.Mutex
+	failures  int
+	backoff   time.Duration
+	openUntil time.Time
+}
+
+// NewBreaker returns a closed Breaker.
+func NewBreaker(config BreakerConfig) *Breaker {
+	return &Breaker{config: config, backoff: config.Backoff}
+}
+
+// State returns the state of the breaker at now.
+func (b *Breaker) State(now time.Time) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state(now)
+}
+
+func (b *Breaker) state(now time.Time) BreakerState {
+	if b.config.FailureThreshold <= 0 || b.failures < b.config.FailureThreshold {
+		return BreakerClosed
+	}
+	if now.Before(b.openUntil) {
+		return BreakerOpen
+	}
+	return BreakerHalfOpen
+}
+
+// Allow reports whether a collection may be attempted at now.
+func (b *Breaker) Allow(now time.Time) bool {
+	return b.State(now) != BreakerOpen
+}
+
+// Success records a successful collection and closes the circuit.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.backoff = b.config.Backoff
+}
+
+// Failure records a failed collection at now, opening the circuit once the
+// failure threshold is reached.
+func (b *Breaker) Failure(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state(now) == BreakerHalfOpen {
+		b.backoff *= 2
+		if b.config.MaxBackoff > 0 && b.backoff > b.config.MaxBackoff {
+			b.backoff = b.config.MaxBackoff
+		}
+	}
+	b.failures++
+	if b.config.FailureThreshold > 0 && b.failures >= b.config.FailureThreshold {
+		b.openUntil = now.Add(b.backoff)
+	}
+}
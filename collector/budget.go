@@ -0,0 +1,264 @@
+package collector
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+// budgetSweepInterval is how often admissions are checked for expiry.
+const budgetSweepInterval = time.Minute
+
+// OverflowLabelValue is the value of every variable label of the series
+// that the series of a metric over the budget are summed into.
+const OverflowLabelValue = "_overflow"
+
+// Budget limits the number of series exported per zone and in total, to
+// protect Prometheus from accidental cardinality explosions such as a burst
+// of distinct DNS query names. Series admitted once stay admitted until they
+// have not been seen for the TTL, so the exported set is stable across
+// scrapes while series that went away free their place.
+type Budget struct {
+	zoneLimit    int
+	globalLimit  int
+	ttl          time.Duration
+	zoneIdentity string
+
+	mu    sync.Mutex
+	total int
+	// zones and dropped hold when each admitted and dropped series of a
+	// zone, by ID, was last seen.
+	zones   map[string]map[string]time.Time
+	dropped map[string]map[string]time.Time
+	swept   time.Time
+	logged  map[string]bool
+
+	droppedTotal *prometheus.CounterVec
+}
+
+// NewBudget returns a Budget admitting at most zoneLimit series per zone and
+// globalLimit series overall. A limit of zero means unlimited. Series not
+// seen for ttl are released; a ttl of zero keeps them admitted forever.
+// Dropped series are counted by zone, identified by the labels zoneIdentity
+// selects.
+func NewBudget(zoneLimit, globalLimit int, ttl time.Duration, zoneIdentity string) *Budget {
+	return &Budget{
+		zoneLimit:    zoneLimit,
+		globalLimit:  globalLimit,
+		ttl:          ttl,
+		zoneIdentity: zoneIdentity,
+		zones:        make(map[string]map[string]time.Time),
+		dropped:      make(map[string]map[string]time.Time),
+		logged:       make(map[string]bool),
+		droppedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cloudflare_exporter_dropped_series_total",
+				Help: "Distinct series folded into the _overflow series of their metric because the cardinality budget was exceeded.",
+			},
+			ZoneIdentityLabels(zoneIdentity),
+		),
+	}
+}
+
+func (b *Budget) admit(zone, key string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	series, ok := b.zones[zone]
+	if !ok {
+		series = make(map[string]time.Time)
+		b.zones[zone] = series
+	}
+	if _, ok := series[key]; ok {
+		series[key] = now
+		return true
+	}
+	if b.zoneLimit > 0 && len(series) >= b.zoneLimit {
+		return false
+	}
+	if b.globalLimit > 0 && b.total >= b.globalLimit {
+		return false
+	}
+	series[key] = now
+	b.total++
+	return true
+}
+
+// drop records that the series key of zone was dropped, and reports
+// whether it was not already.
+func (b *Budget) drop(zone, key string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	series, ok := b.dropped[zone]
+	if !ok {
+		series = make(map[string]time.Time)
+		b.dropped[zone] = series
+	}
+	_, seen := series[key]
+	series[key] = now
+	return !seen
+}
+
+// expire releases the admissions, and forgets the drops, not seen for the
+// TTL. It runs at most once per budgetSweepInterval.
+func (b *Budget) expire(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ttl <= 0 || now.Sub(b.swept) < budgetSweepInterval {
+		return
+	}
+	b.swept = now
+	expired := now.Add(-b.ttl)
+	for _, series := range b.zones {
+		for key, seen := range series {
+			if seen.Before(expired) {
+				delete(series, key)
+				b.total--
+			}
+		}
+	}
+	for _, series := range b.dropped {
+		for key, seen := range series {
+			if seen.Before(expired) {
+				delete(series, key)
+			}
+		}
+	}
+}
+
+func (b *Budget) logOnce(zone, fqName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.logged[zone+"/"+fqName] {
+		return
+	}
+	b.logged[zone+"/"+fqName] = true
+	log.Warnf("cardinality budget exceeded for zone %s, folding new %s series into its %s series", zone, fqName, OverflowLabelValue)
+}
+
+// overflowSeries is the sum of the series of a metric over the budget.
+type overflowSeries struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	labels    []string
+	value     float64
+}
+
+// Filter copies metrics for zone from in to out until in is closed. Series
+// over budget are summed into a series of their metric with every variable
+// label set to OverflowLabelValue, so the metric's total is kept. As a sum
+// over a changing set of series, the overflow series of a counter may go
+// down, which rate() takes for a counter reset. Histograms and summaries
+// over budget are dropped.
+func (b *Budget) Filter(zone cloudflare.Zone, in <-chan prometheus.Metric, out chan<- prometheus.Metric) {
+	now := time.Now()
+	b.expire(now)
+
+	overflows := map[*prometheus.Desc]*overflowSeries{}
+	var order []*overflowSeries
+	// Formatting a descriptor is expensive, and a zone's metrics share a
+	// few dozen of them.
+	descStrings := map[*prometheus.Desc]string{}
+	var pairs []string
+
+	for m := range in {
+		pb := &dto.Metric{}
+		if err := m.Write(pb); err != nil {
+			out <- m
+			continue
+		}
+		desc, ok := descStrings[m.Desc()]
+		if !ok {
+			desc = m.Desc().String()
+			descStrings[m.Desc()] = desc
+		}
+		pairs = pairs[:0]
+		for _, l := range pb.Label {
+			pairs = append(pairs, l.GetName()+"="+l.GetValue())
+		}
+		sort.Strings(pairs)
+		key := desc + "{" + strings.Join(pairs, ",") + "}"
+		if b.admit(zone.ID, key, now) {
+			out <- m
+			continue
+		}
+		if b.drop(zone.ID, key, now) {
+			b.droppedTotal.WithLabelValues(ZoneIdentityValues(zone, b.zoneIdentity)...).Inc()
+		}
+
+		o, ok := overflows[m.Desc()]
+		if !ok {
+			o = &overflowSeries{desc: m.Desc()}
+			for range variableLabelsOf(desc) {
+				o.labels = append(o.labels, OverflowLabelValue)
+			}
+			overflows[m.Desc()] = o
+			order = append(order, o)
+			b.logOnce(zone.Name, fqNameOf(m.Desc()))
+		}
+		switch {
+		case pb.Gauge != nil:
+			o.valueType = prometheus.GaugeValue
+			o.value += pb.Gauge.GetValue()
+		case pb.Counter != nil:
+			o.valueType = prometheus.CounterValue
+			o.value += pb.Counter.GetValue()
+		case pb.Untyped != nil:
+			o.valueType = prometheus.UntypedValue
+			o.value += pb.Untyped.GetValue()
+		}
+	}
+
+	for _, o := range order {
+		if o.valueType == 0 {
+			continue
+		}
+		m, err := prometheus.NewConstMetric(o.desc, o.valueType, o.value, o.labels...)
+		if err != nil {
+			log.Errorf("failed to build the %s series of %s: %s", OverflowLabelValue, fqNameOf(o.desc), err)
+			continue
+		}
+		out <- m
+	}
+}
+
+// fqNameOf extracts the metric name from a descriptor.
+func fqNameOf(d *prometheus.Desc) string {
+	s := d.String()
+	if i := strings.Index(s, `fqName: "`); i >= 0 {
+		s = s[i+len(`fqName: "`):]
+		if j := strings.Index(s, `"`); j >= 0 {
+			return s[:j]
+		}
+	}
+	return s
+}
+
+// variableLabelsOf extracts the variable label names from the string of a
+// descriptor, which client_golang does not expose otherwise.
+func variableLabelsOf(desc string) []string {
+	i := strings.LastIndex(desc, "variableLabels: [")
+	if i < 0 {
+		return nil
+	}
+	s := desc[i+len("variableLabels: ["):]
+	if j := strings.Index(s, "]"); j >= 0 {
+		s = s[:j]
+	}
+	return strings.Fields(s)
+}
+
+// Describe implements prometheus.Collector.
+func (b *Budget) Describe(ch chan<- *prometheus.Desc) {
+	b.droppedTotal.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (b *Budget) Collect(ch chan<- prometheus.Metric) {
+	b.droppedTotal.Collect(ch)
+}
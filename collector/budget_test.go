@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func TestBudget(t *testing.T) {
+	b := NewBudget(1, 0, time.Hour, ZoneIdentityBoth)
+	desc := prometheus.NewDesc("test_total", "Test.", []string{"name", "status"}, prometheus.Labels{"zone_name": "example.com"})
+	zone := cloudflare.Zone{ID: "023e105f4ecef8ad9ca31a8372d0c353", Name: "example.com"}
+
+	// filter runs the budget over a counter series per name and returns the
+	// admitted names and the value of the overflow series.
+	filter := func(names ...string) (admitted []string, overflowed float64) {
+		in := make(chan prometheus.Metric, len(names))
+		for _, name := range names {
+			in <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, 1, name, "200")
+		}
+		close(in)
+		out := make(chan prometheus.Metric, len(names)+1)
+		b.Filter(zone, in, out)
+		close(out)
+		for m := range out {
+			if m.Desc() != desc {
+				t.Fatalf("got a series of %s, want only series of the filtered metric", m.Desc())
+			}
+			pb := &dto.Metric{}
+			if err := m.Write(pb); err != nil {
+				t.Fatal(err)
+			}
+			if pb.Counter == nil {
+				t.Fatal("series is not a counter")
+			}
+			labels := map[string]string{}
+			for _, l := range pb.Label {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["name"] == OverflowLabelValue {
+				if labels["status"] != OverflowLabelValue || labels["zone_name"] != "example.com" {
+					t.Errorf("got overflow series labels %v", labels)
+				}
+				overflowed = pb.Counter.GetValue()
+				continue
+			}
+			admitted = append(admitted, labels["name"])
+		}
+		return admitted, overflowed
+	}
+
+	for i := 0; i < 2; i++ {
+		admitted, overflowed := filter("a", "b", "c")
+		if len(admitted) != 1 || admitted[0] != "a" || overflowed != 2 {
+			t.Fatalf("scrape %d: got admitted %v and overflow %v, want [a] and 2", i, admitted, overflowed)
+		}
+	}
+	want := `# HELP cloudflare_exporter_dropped_series_total Distinct series folded into the _overflow series of their metric because the cardinality budget was exceeded.
+# TYPE cloudflare_exporter_dropped_series_total counter
+cloudflare_exporter_dropped_series_total{zone_id="023e105f4ecef8ad9ca31a8372d0c353",zone_name="example.com"} 2
+`
+	if err := testutil.CollectAndCompare(b, strings.NewReader(want)); err != nil {
+		t.Errorf("dropped series after two scrapes, want each counted once: %s", err)
+	}
+
+	// Once a has not been seen for the TTL, b takes its place.
+	for _, series := range b.zones {
+		for key := range series {
+			series[key] = time.Now().Add(-2 * time.Hour)
+		}
+	}
+	b.swept = time.Time{}
+	if admitted, _ := filter("b"); len(admitted) != 1 || admitted[0] != "b" {
+		t.Errorf("got admitted %v after the TTL, want [b]", admitted)
+	}
+}
+
+func TestBudgetZoneIdentity(t *testing.T) {
+	b := NewBudget(0, 1, 0, ZoneIdentityID)
+	desc := prometheus.NewDesc("test", "Test.", []string{"name"}, nil)
+	in := make(chan prometheus.Metric, 2)
+	in <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, "a")
+	in <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 2, "b")
+	close(in)
+	out := make(chan prometheus.Metric, 3)
+	b.Filter(cloudflare.Zone{ID: "023e105f4ecef8ad9ca31a8372d0c353", Name: "example.com"}, in, out)
+	close(out)
+
+	want := `# HELP cloudflare_exporter_dropped_series_total Distinct series folded into the _overflow series of their metric because the cardinality budget was exceeded.
+# TYPE cloudflare_exporter_dropped_series_total counter
+cloudflare_exporter_dropped_series_total{zone_id="023e105f4ecef8ad9ca31a8372d0c353"} 1
+`
+	if err := testutil.CollectAndCompare(b, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
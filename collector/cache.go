@@ -0,0 +1,242 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+// Endpoints cached by CachingAPI, used to configure per-endpoint TTLs.
+const (
+	EndpointZones          = "zones"
+	EndpointZoneDetails    = "zone_details"
+	EndpointDashboard      = "dashboard"
+	EndpointColocations    = "colos"
+	EndpointDNSAnalytics   = "dns_analytics"
+	EndpointAccountMembers = "account_members"
+	EndpointOriginCerts    = "origin_certificates"
+)
+
+// CacheEndpoints lists every endpoint name accepted by NewCachingAPI.
+var CacheEndpoints = []string{EndpointZones, EndpointZoneDetails, EndpointDashboard, EndpointColocations, EndpointDNSAnalytics, EndpointAccountMembers, EndpointOriginCerts}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// CachingAPI wraps an API and reuses its responses for a per-endpoint TTL,
+// keyed by zone and endpoint, so that several Prometheus servers scraping
+// the exporter within the TTL share the same API calls.
+type CachingAPI struct {
+	api        API
+	defaultTTL time.Duration
+	ttls       map[string]time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewCachingAPI returns a CachingAPI in front of api. Endpoints missing from
+// ttls use defaultTTL; a TTL of zero disables caching for an endpoint.
+func NewCachingAPI(api API, defaultTTL time.Duration, ttls map[string]time.Duration) (*CachingAPI, error) {
+	for endpoint := range ttls {
+		if !contains(CacheEndpoints, endpoint) {
+			return nil, fmt.Errorf("unknown cache endpoint %q, must be one of %s", endpoint, strings.Join(CacheEndpoints, ", "))
+		}
+	}
+	return &CachingAPI{
+		api:        api,
+		defaultTTL: defaultTTL,
+		ttls:       ttls,
+		entries:    make(map[string]cacheEntry),
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cloudflare_exporter_cache_requests_total",
+				Help: "Cloudflare API response cache lookups, by endpoint and result (hit or miss).",
+			},
+			[]string{"endpoint", "result"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "cloudflare_exporter_api_call_duration_seconds",
+				Help:    "Duration of Cloudflare API calls by endpoint, excluding cache hits.",
+				Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+			},
+			[]string{"endpoint"},
+		),
+	}, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CachingAPI) ttl(endpoint string) time.Duration {
+	if ttl, ok := c.ttls[endpoint]; ok {
+		return ttl
+	}
+	return c.defaultTTL
+}
+
+// cached returns the unexpired response stored under endpoint and key, or
+// calls fetch and stores its response. Errors are never cached.
+func (c *CachingAPI) cached(endpoint, key string, fetch func() (interface{}, error)) (interface{}, error) {
+	ttl := c.ttl(endpoint)
+	if ttl <= 0 {
+		return c.timed(endpoint, fetch)
+	}
+
+	key = endpoint + "/" + key
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		c.requests.WithLabelValues(endpoint, "hit").Inc()
+		return entry.value, nil
+	}
+
+	c.requests.WithLabelValues(endpoint, "miss").Inc()
+	value, err := c.timed(endpoint, fetch)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{value: value, expires: time.Now().Add(ttl)}
+	c.mu.Unlock()
+	return value, nil
+}
+
+// timed calls fetch, observing its duration for endpoint.
+func (c *CachingAPI) timed(endpoint string, fetch func() (interface{}, error)) (interface{}, error) {
+	start := time.Now()
+	defer func() {
+		c.duration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	}()
+	return fetch()
+}
+
+// ListZones implements API.
+func (c *CachingAPI) ListZones(z ...string) ([]cloudflare.Zone, error) {
+	v, err := c.cached(EndpointZones, strings.Join(z, ","), func() (interface{}, error) {
+		return listZones(c.api, z...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]cloudflare.Zone), nil
+}
+
+// ZoneDetails implements API.
+func (c *CachingAPI) ZoneDetails(zoneID string) (cloudflare.Zone, error) {
+	v, err := c.cached(EndpointZoneDetails, zoneID, func() (interface{}, error) {
+		return c.api.ZoneDetails(zoneID)
+	})
+	if err != nil {
+		return cloudflare.Zone{}, err
+	}
+	return v.(cloudflare.Zone), nil
+}
+
+// ZoneAnalyticsDashboard implements API. The time range is not part of the
+// cache key, as it moves with every scrape.
+func (c *CachingAPI) ZoneAnalyticsDashboard(zoneID string, options cloudflare.ZoneAnalyticsOptions) (cloudflare.ZoneAnalyticsData, error) {
+	v, err := c.cached(EndpointDashboard, zoneID, func() (interface{}, error) {
+		return c.api.ZoneAnalyticsDashboard(zoneID, options)
+	})
+	if err != nil {
+		return cloudflare.ZoneAnalyticsData{}, err
+	}
+	return v.(cloudflare.ZoneAnalyticsData), nil
+}
+
+// ZoneAnalyticsByColocation implements API. The time range is not part of
+// the cache key, as it moves with every scrape.
+func (c *CachingAPI) ZoneAnalyticsByColocation(zoneID string, options cloudflare.ZoneAnalyticsOptions) ([]cloudflare.ZoneAnalyticsData, error) {
+	v, err := c.cached(EndpointColocations, zoneID, func() (interface{}, error) {
+		return c.api.ZoneAnalyticsByColocation(zoneID, options)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]cloudflare.ZoneAnalyticsData), nil
+}
+
+// ZoneDNSAnalyticsByTime implements API.
+func (c *CachingAPI) ZoneDNSAnalyticsByTime(zoneID string, options cloudflare.ZoneDNSAnalyticsOptions) (cloudflare.ZoneDNSAnalyticsByTimeData, error) {
+	key := zoneID + "/" + strings.Join(options.Dimensions, ",") + "/" + strings.Join(options.Metrics, ",") + "/" + strings.Join(options.Filters, ",")
+	v, err := c.cached(EndpointDNSAnalytics, key, func() (interface{}, error) {
+		return c.api.ZoneDNSAnalyticsByTime(zoneID, options)
+	})
+	if err != nil {
+		return cloudflare.ZoneDNSAnalyticsByTimeData{}, err
+	}
+	return v.(cloudflare.ZoneDNSAnalyticsByTimeData), nil
+}
+
+// OriginCertificates implements API.
+func (c *CachingAPI) OriginCertificates(options cloudflare.OriginCACertificateListOptions) ([]cloudflare.OriginCACertificate, error) {
+	v, err := c.cached(EndpointOriginCerts, options.ZoneID, func() (interface{}, error) {
+		return c.api.OriginCertificates(options)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]cloudflare.OriginCACertificate), nil
+}
+
+// accountMembersPage is a page of account members with its result info.
+type accountMembersPage struct {
+	members []cloudflare.AccountMember
+	info    cloudflare.ResultInfo
+}
+
+// AccountMembers implements API.
+func (c *CachingAPI) AccountMembers(accountID string, pageOpts cloudflare.PaginationOptions) ([]cloudflare.AccountMember, cloudflare.ResultInfo, error) {
+	v, err := c.cached(EndpointAccountMembers, fmt.Sprintf("%s/%d/%d", accountID, pageOpts.Page, pageOpts.PerPage), func() (interface{}, error) {
+		members, info, err := c.api.AccountMembers(accountID, pageOpts)
+		return accountMembersPage{members, info}, err
+	})
+	if err != nil {
+		return nil, cloudflare.ResultInfo{}, err
+	}
+	page := v.(accountMembersPage)
+	return page.members, page.info, nil
+}
+
+// Raw implements API. Raw responses are not cached, and their durations
+// are observed under the "raw" endpoint as paths contain zone IDs.
+func (c *CachingAPI) Raw(method, endpoint string, data interface{}) (json.RawMessage, error) {
+	v, err := c.timed("raw", func() (interface{}, error) {
+		return c.api.Raw(method, endpoint, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(json.RawMessage), nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *CachingAPI) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *CachingAPI) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+}
@@ -0,0 +1,389 @@
+// Package collector contains the per-zone Cloudflare metric collectors and
+// the shared plumbing they are built on.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+// Namespace is the metric namespace shared by all collectors.
+const Namespace = "cloudflare"
+
+// Collector collects a single family of metrics for a Cloudflare zone.
+type Collector interface {
+	// Name returns the name the collector is registered under.
+	Name() string
+	// Describe sends the descriptors of every metric the collector emits.
+	Describe(ch chan<- *prometheus.Desc)
+	// Collect fetches data for zone and sends the resulting metrics to ch.
+	Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error
+}
+
+// DNSOptions configures the DNS analytics queries.
+type DNSOptions struct {
+	// Metrics are the DNS analytics API metrics to request. Defaults to the
+	// query counts and response times if empty.
+	Metrics []string
+	// Dimensions are the DNS analytics API dimensions to request. Defaults
+	// to all dimensions available on the zone's plan if empty; dimensions
+	// not available on the plan are skipped.
+	Dimensions []string
+	// Since and Until bound the queried time range, relative to now. Zero
+	// values leave the API defaults in place.
+	Since time.Duration
+	Until time.Duration
+	// TimeDelta is the width of the time buckets, e.g. "minute" or "hour".
+	TimeDelta string
+	// PerColo queries each colo separately, in parallel, for zones whose
+	// DNS analytics are broken out by PoP.
+	PerColo bool
+	// Colos are the colos queried in per-colo mode. If empty, the colos seen
+	// in the last full response are used.
+	Colos []string
+	// Parallelism bounds the number of concurrent per-colo queries.
+	Parallelism int
+	// MaxRows bounds the number of rows exported per collection, 0 means
+	// unlimited. Further rows are dropped.
+	MaxRows int
+	// SeriesTTL drops counter series whose rows have not been returned for
+	// that long, along with their state. Zero keeps them forever.
+	SeriesTTL time.Duration
+	// ByAccount sums DNS queries over each account's zones, by response
+	// code and colo, instead of collecting DNS analytics per zone.
+	ByAccount bool
+}
+
+// DashboardOptions configures the dashboard analytics queries.
+type DashboardOptions struct {
+	// Continuous makes Cloudflare end the queried range at the last complete
+	// time bucket, so the exported bucket is never partial.
+	Continuous bool
+	// Since is how far back the queried range starts. Zero uses the
+	// shortest range allowed by the zone's plan.
+	Since time.Duration
+	// Ratios adds the cache hit ratio, error ratio and HTTPS share of each
+	// zone, so alerts need not divide breakdown metrics.
+	Ratios bool
+	// RequestBreakdowns lists the request breakdowns to export, out of
+	// status, content_type, country and ip_class. Empty exports all.
+	RequestBreakdowns []string
+	// ContentClasses rolls the content type breakdowns up into html, api,
+	// image, video, script and other, under the content_class label.
+	ContentClasses bool
+}
+
+// requestBreakdowns are the breakdowns of dashboard requests, named after
+// the label they add.
+var requestBreakdowns = []string{"status", "content_type", "country", "ip_class"}
+
+// Validate checks that all configured request breakdowns are known.
+func (o DashboardOptions) Validate() error {
+	for _, b := range o.RequestBreakdowns {
+		if !contains(requestBreakdowns, b) {
+			return fmt.Errorf("unknown dashboard request breakdown %q, must be one of %s", b, strings.Join(requestBreakdowns, ", "))
+		}
+	}
+	return nil
+}
+
+// wantsBreakdown reports whether the named request breakdown is exported.
+func (o DashboardOptions) wantsBreakdown(name string) bool {
+	return len(o.RequestBreakdowns) == 0 || contains(o.RequestBreakdowns, name)
+}
+
+// Options configures the collectors built by New.
+type Options struct {
+	// State keeps per-series accumulation state across scrapes and, when
+	// backed by a file, across restarts.
+	State *StateStore
+	// Zones are all monitored zones, for account collectors aggregating
+	// over the zones of an account.
+	Zones []cloudflare.Zone
+	// Budget limits the number of series exported. Nil means unlimited.
+	Budget *Budget
+	// Breaker configures the circuit breaker guarding each collector.
+	Breaker BreakerConfig
+	// Dashboard configures the dashboard analytics collector.
+	Dashboard DashboardOptions
+	// DNS configures the DNS analytics collector.
+	DNS DNSOptions
+	// GraphQL queries the GraphQL Analytics API. Collectors built on it
+	// collect nothing if it is nil.
+	GraphQL *GraphQLClient
+	// GraphQLColos enables the sampled per-colo request breakdown, which
+	// is opt-in as it is approximate and adds a query per zone.
+	GraphQLColos bool
+	// GraphQLReferers is the number of top referer hosts to break sampled
+	// requests out by, 0 disables the breakdown.
+	GraphQLReferers int
+	// GraphQLASNs is the number of top client ASNs to break sampled
+	// requests out by, 0 disables the breakdown.
+	GraphQLASNs int
+	// GraphQLUserAgents is the number of top user agent families to break
+	// sampled requests out by, 0 disables the breakdown.
+	GraphQLUserAgents int
+	// GraphQLZaraz enables the Zaraz trigger and tool breakdowns, which are
+	// opt-in as they add two queries per zone.
+	GraphQLZaraz bool
+	// GraphQLTieredCache enables the requests and hit ratio of upper-tier
+	// PoPs, which are opt-in as they add a query per zone.
+	GraphQLTieredCache bool
+	// GraphQLImageResizing enables the Image Resizing requests and errors,
+	// which are opt-in as they add a query per zone.
+	GraphQLImageResizing bool
+	// GraphQLOriginErrors enables the origin errors by reason, which are
+	// opt-in as they add a query per zone.
+	GraphQLOriginErrors bool
+	// Origins enables the origins proxied DNS records point at, which
+	// takes listing every proxied record of the zone.
+	Origins bool
+	// Wayback queries the Wayback Machine for when each zone with Always
+	// Online was last archived. Nil leaves the last crawl out.
+	Wayback *http.Client
+	// RegionalServices enables the hostnames each zone restricts to a
+	// region with Regional Services, which fail for zones without them.
+	RegionalServices bool
+	// RegionColos maps Regional Services region keys, e.g. eu, to the
+	// colos of the region. Colo breakdowns of zones whose hostnames are
+	// all restricted to mapped regions only export those colos.
+	RegionColos map[string][]string
+	// ZoneIdentity selects the labels identifying zones, one of
+	// ZoneIdentities.
+	ZoneIdentity string
+	// ZoneMetadata adds labels from a file to the metrics of each zone.
+	// Nil adds none.
+	ZoneMetadata *ZoneMetadata
+	// CountryGroups rolls the country breakdowns of dashboard analytics up
+	// into groups. Nil exports them by country_code.
+	CountryGroups *CountryGroups
+	// MemberInfo exports an info metric per account member, including
+	// their email address.
+	MemberInfo bool
+	// Images enables the Cloudflare Images usage of each account, which
+	// fails for accounts without Images.
+	Images bool
+	// LoadBalancerHealth enables the load balancer monitor results of each
+	// account, which take a request per pool.
+	LoadBalancerHealth bool
+	// WorkersCron enables the cron triggers of each account's Workers
+	// scripts and their last runs, which take a request per script.
+	WorkersCron bool
+	// Queues enables the backlog and consumer lag of each account's
+	// Cloudflare Queues, which fail for accounts without Queues.
+	Queues bool
+	// AI enables the Workers AI and AI Gateway usage of each account,
+	// which adds two queries per account.
+	AI bool
+	// OriginCA enables the Origin CA certificate collector, which needs an
+	// Origin CA key.
+	OriginCA bool
+	// Selection chooses the collectors Select returns for each zone.
+	Selection Selection
+	// PopNames adds the pop_name and pop_region labels to dashboard and DNS
+	// analytics broken out by colo, besides colo_id and pop_id.
+	PopNames bool
+	// AggregationLabel keeps dashboard and DNS analytics broken out by PoP
+	// in the cloudflare namespace, with an aggregation label telling them
+	// from zone-level analytics, instead of moving them to cloudflare_pop.
+	AggregationLabel bool
+	// Intervals runs the named zone and account collectors at most once
+	// per interval, serving their last metrics in between.
+	Intervals map[string]time.Duration
+	// MinIntervals runs the named zone and account collectors only once
+	// their last run is that old, serving their last metrics in between.
+	// Intervals take precedence.
+	MinIntervals map[string]time.Duration
+	// Timeouts limits how long the named zone and account collectors may
+	// run per scrape, besides the scrape deadline.
+	Timeouts map[string]time.Duration
+	// ZoneRefresh is how often zone exporters get their zone's details
+	// again, rebuilding their collectors if its plan changed. Zero keeps
+	// the zone as listed at startup.
+	ZoneRefresh time.Duration
+}
+
+// Schedule returns the schedule of the named collector, from Intervals or
+// else MinIntervals, or nil to run it on every scrape.
+func (o Options) Schedule(name string) *Schedule {
+	if interval, ok := o.Intervals[name]; ok && interval > 0 {
+		return NewSchedule(interval)
+	}
+	return NewMinIntervalSchedule(o.MinIntervals[name])
+}
+
+// Selection chooses the collectors to run for a zone.
+type Selection struct {
+	// Enable lists collectors that always run, even if the zone lacks their
+	// required feature.
+	Enable []string
+	// Disable lists collectors that never run.
+	Disable []string
+	// Auto skips collectors whose required feature the zone lacks, instead
+	// of letting them fail on every scrape.
+	Auto bool
+}
+
+// Factory builds a Collector for a zone. Descriptors usually depend on the
+// zone's plan, so collectors are built once per zone.
+type Factory func(api API, zone cloudflare.Zone, opts Options) Collector
+
+var (
+	factories        = make(map[string]Factory)
+	requiredFeatures = make(map[string]string)
+)
+
+// Register makes a collector available under name. It is meant to be called
+// from init functions and panics if name is already taken.
+func Register(name string, factory Factory) {
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("collector %q registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// RequireFeature marks the named collector as only applicable to zones with
+// feature, see Select. It is meant to be called from init functions.
+func RequireFeature(name, feature string) {
+	requiredFeatures[name] = feature
+}
+
+// Select returns the names of the collectors to run for zone, according to
+// opts.Selection. Features are only probed if a collector requires one.
+func Select(api API, zone cloudflare.Zone, opts Options) ([]string, error) {
+	for _, name := range WithLabels(opts.Selection.Enable, opts.Selection.Disable...) {
+		if _, ok := factories[name]; !ok {
+			return nil, fmt.Errorf("unknown collector %q", name)
+		}
+	}
+
+	var features Features
+	names := []string{}
+	for _, name := range Names() {
+		if contains(opts.Selection.Disable, name) {
+			continue
+		}
+		feature, ok := requiredFeatures[name]
+		if ok && opts.Selection.Auto && !contains(opts.Selection.Enable, name) {
+			if features == nil {
+				features = ProbeFeatures(api, zone)
+			}
+			if !features[feature] {
+				log.Infof("Zone %s lacks the %s feature, skipping %s collector", zone.Name, feature, name)
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// Names returns the names of all registered collectors, sorted.
+func Names() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds the named collectors for zone. All registered collectors are
+// built when names is nil; an empty, non-nil names builds none.
+func New(api API, zone cloudflare.Zone, opts Options, names ...string) ([]Collector, error) {
+	if names == nil {
+		names = Names()
+	}
+	if opts.State == nil {
+		opts.State, _ = OpenStateStore("")
+	}
+	collectors := make([]Collector, 0, len(names))
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q", name)
+		}
+		collectors = append(collectors, factory(api, zone, opts))
+	}
+	return collectors, nil
+}
+
+// Zone identities, the labels ZoneLabels identifies zones by.
+const (
+	// ZoneIdentityBoth identifies zones by zone_id and zone_name.
+	ZoneIdentityBoth = "both"
+	// ZoneIdentityName identifies zones by zone_name only, so series
+	// survive a zone being deleted and added again under a new ID.
+	ZoneIdentityName = "name"
+	// ZoneIdentityID identifies zones by zone_id only, so series survive
+	// a zone being renamed.
+	ZoneIdentityID = "id"
+)
+
+// ZoneIdentities are the valid values of Options.ZoneIdentity.
+var ZoneIdentities = []string{ZoneIdentityBoth, ZoneIdentityName, ZoneIdentityID}
+
+// ZoneIdentityLabels returns the names of the labels identity identifies
+// zones by, for metrics labelling zones by variable labels.
+func ZoneIdentityLabels(identity string) []string {
+	switch identity {
+	case ZoneIdentityName:
+		return []string{"zone_name"}
+	case ZoneIdentityID:
+		return []string{"zone_id"}
+	}
+	return []string{"zone_id", "zone_name"}
+}
+
+// ZoneIdentityValues returns the values of ZoneIdentityLabels(identity) for
+// zone, in order.
+func ZoneIdentityValues(zone cloudflare.Zone, identity string) []string {
+	switch identity {
+	case ZoneIdentityName:
+		return []string{zone.Name}
+	case ZoneIdentityID:
+		return []string{zone.ID}
+	}
+	return []string{zone.ID, zone.Name}
+}
+
+// ZoneLabels returns the constant labels identifying zone on every metric,
+// with the zone_id and zone_name labels chosen by opts.ZoneIdentity, an
+// empty one being ZoneIdentityBoth, and the labels of opts.ZoneMetadata.
+func ZoneLabels(zone cloudflare.Zone, opts Options) prometheus.Labels {
+	labels := prometheus.Labels{
+		"account_id":   zone.Account.ID,
+		"account_name": zone.Account.Name,
+		"owner_id":     zone.Owner.ID,
+	}
+	if opts.ZoneIdentity != ZoneIdentityName {
+		labels["zone_id"] = zone.ID
+	}
+	if opts.ZoneIdentity != ZoneIdentityID {
+		labels["zone_name"] = zone.Name
+	}
+
+	if zone.Owner.Name != "" {
+		labels["owner_name"] = zone.Owner.Name
+	}
+
+	if zone.Owner.Email != "" {
+		labels["owner_email"] = zone.Owner.Email
+	}
+
+	if opts.ZoneMetadata != nil {
+		for name, value := range opts.ZoneMetadata.labels(zone) {
+			labels[name] = value
+		}
+	}
+
+	return labels
+}
@@ -0,0 +1,275 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/expfmt"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/internal/fakeapi"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// testEnv is a fake Cloudflare API and the options of collectors using it.
+type testEnv struct {
+	url   string
+	api   API
+	opts  Options
+	close func()
+}
+
+// Close stops the fake API.
+func (e *testEnv) Close() {
+	e.close()
+}
+
+// newTestEnv starts a fake Cloudflare API, which callers stop with Close.
+// The options enable every opt-in collector and breakdown the fake API
+// serves.
+func newTestEnv(t testing.TB) *testEnv {
+	server := fakeapi.NewServer()
+
+	// The default rate limit of the client would make up most of the run
+	// time of the tests.
+	api, err := cloudflare.New("fake", "fake@example.com", cloudflare.UsingRateLimit(1000))
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	api.BaseURL = server.URL + fakeapi.APIPrefix
+
+	state, err := OpenStateStore("")
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return &testEnv{
+		url:   server.URL,
+		api:   api,
+		close: server.Close,
+		opts: Options{
+			State:                state,
+			Zones:                fakeapi.Zones,
+			GraphQL:              NewGraphQLClient(http.DefaultClient, server.URL+fakeapi.APIPrefix+"/graphql", "fake", "fake@example.com", nil),
+			GraphQLColos:         true,
+			GraphQLReferers:      10,
+			GraphQLASNs:          10,
+			GraphQLUserAgents:    10,
+			GraphQLZaraz:         true,
+			GraphQLTieredCache:   true,
+			GraphQLImageResizing: true,
+			GraphQLOriginErrors:  true,
+			Origins:              true,
+			RegionalServices:     true,
+			MemberInfo:           true,
+			Images:               true,
+			LoadBalancerHealth:   true,
+			WorkersCron:          true,
+			Queues:               true,
+			AI:                   true,
+			OriginCA:             true,
+		},
+	}
+}
+
+// collectFunc adapts the Collect method of a zone or account collector to a
+// prometheus.Collector, keeping the error it returned.
+type collectFunc struct {
+	describe func(ch chan<- *prometheus.Desc)
+	collect  func(ch chan<- prometheus.Metric) error
+	err      error
+}
+
+func (c *collectFunc) Describe(ch chan<- *prometheus.Desc) { c.describe(ch) }
+
+func (c *collectFunc) Collect(ch chan<- prometheus.Metric) { c.err = c.collect(ch) }
+
+func zoneCollectFunc(c Collector, zone cloudflare.Zone) *collectFunc {
+	return &collectFunc{
+		describe: c.Describe,
+		collect: func(ch chan<- prometheus.Metric) error {
+			return c.Collect(context.Background(), zone, ch)
+		},
+	}
+}
+
+func accountCollectFunc(c AccountCollector, account Account) *collectFunc {
+	return &collectFunc{
+		describe: c.Describe,
+		collect: func(ch chan<- prometheus.Metric) error {
+			return c.Collect(context.Background(), account, ch)
+		},
+	}
+}
+
+// errorPrefix starts the line of a golden file holding the error the
+// collection failed with.
+const errorPrefix = "# error: "
+
+// compareGolden collects c and compares its exposition and error with the
+// golden file testdata/name.prom, or writes it with -update.
+func compareGolden(t *testing.T, env *testEnv, c *collectFunc, name string) {
+	path := filepath.Join("testdata", name+".prom")
+
+	if *update {
+		reg := prometheus.NewPedanticRegistry()
+		if err := reg.Register(c); err != nil {
+			t.Fatal(err)
+		}
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if c.err != nil {
+			fmt.Fprintf(&buf, "%s%s\n", errorPrefix, strings.Replace(c.err.Error(), env.url, "http://fakeapi", -1))
+		}
+		for _, family := range families {
+			if _, err := expfmt.MetricFamilyToText(&buf, family); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	golden, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s, run the tests with -update to create it", err)
+	}
+	if err := testutil.CollectAndCompare(c, bytes.NewReader(golden)); err != nil {
+		t.Error(err)
+	}
+	wantErr := ""
+	if strings.HasPrefix(string(golden), errorPrefix) {
+		wantErr = strings.TrimPrefix(strings.SplitN(string(golden), "\n", 2)[0], errorPrefix)
+	}
+	gotErr := ""
+	if c.err != nil {
+		gotErr = strings.Replace(c.err.Error(), env.url, "http://fakeapi", -1)
+	}
+	if gotErr != wantErr {
+		t.Errorf("got error %q, want %q", gotErr, wantErr)
+	}
+}
+
+func TestCollectorsGolden(t *testing.T) {
+	for _, zone := range fakeapi.Zones {
+		for _, name := range Names() {
+			zone, name := zone, name
+			t.Run(zone.Plan.LegacyID+"/"+name, func(t *testing.T) {
+				env := newTestEnv(t)
+				defer env.Close()
+				collectors, err := New(env.api, zone, env.opts, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				compareGolden(t, env, zoneCollectFunc(collectors[0], zone), filepath.Join(zone.Plan.LegacyID, name))
+			})
+		}
+	}
+}
+
+func TestAccountCollectorsGolden(t *testing.T) {
+	account := Accounts(fakeapi.Zones)[0]
+	for _, name := range AccountNames() {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnv(t)
+			defer env.Close()
+			collectors, err := NewAccount(env.api, account, env.opts, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compareGolden(t, env, accountCollectFunc(collectors[0], account), filepath.Join("account", name))
+		})
+	}
+}
+
+// largeAPI serves large analytics payloads for every colo of the PoP
+// catalog, like an Enterprise zone with traffic everywhere.
+type largeAPI struct {
+	API
+	colos    []cloudflare.ZoneAnalyticsData
+	dnsRows  []cloudflare.ZoneDNSAnalyticsByTimeRow
+	interval [][]time.Time
+}
+
+// dnsRowsPerColo is the number of DNS analytics rows per colo of largeAPI.
+const dnsRowsPerColo = 20
+
+func newLargeAPI(api API) *largeAPI {
+	l := &largeAPI{API: api}
+	now := time.Now().Truncate(time.Minute)
+	l.interval = [][]time.Time{{now.Add(-3 * time.Minute), now.Add(-2 * time.Minute)}, {now.Add(-2 * time.Minute), now.Add(-time.Minute)}}
+	for _, pop := range Pops() {
+		l.colos = append(l.colos, fakeapi.AnalyticsData(pop.Code))
+		for i := 0; i < dnsRowsPerColo; i++ {
+			l.dnsRows = append(l.dnsRows, cloudflare.ZoneDNSAnalyticsByTimeRow{
+				Dimensions: []string{fmt.Sprintf("host%d.example.com", i), "NOERROR", "false", "false", "4", "Cached", "A", pop.Code},
+				Metrics:    [][]float64{{40, 42}, {4, 5}, {0, 1}, {3.2, 3.5}, {2, 2}, {8, 9}, {21, 24}},
+			})
+		}
+	}
+	return l
+}
+
+func (l *largeAPI) ZoneAnalyticsByColocation(zoneID string, options cloudflare.ZoneAnalyticsOptions) ([]cloudflare.ZoneAnalyticsData, error) {
+	return l.colos, nil
+}
+
+func (l *largeAPI) ZoneDNSAnalyticsByTime(zoneID string, options cloudflare.ZoneDNSAnalyticsOptions) (cloudflare.ZoneDNSAnalyticsByTimeData, error) {
+	return cloudflare.ZoneDNSAnalyticsByTimeData{Rows: l.dnsRows, RowCount: len(l.dnsRows), TimeIntervals: l.interval}, nil
+}
+
+// BenchmarkCollect collects the analytics broken out by colo of an
+// Enterprise zone with traffic in every colo of the PoP catalog.
+func BenchmarkCollect(b *testing.B) {
+	zone := fakeZone(b, "enterprise")
+	for _, name := range []string{"dashboard_analytics", "dns_analytics"} {
+		b.Run(name, func(b *testing.B) {
+			env := newTestEnv(b)
+			defer env.Close()
+			collectors, err := New(newLargeAPI(env.api), zone, env.opts, name)
+			if err != nil {
+				b.Fatal(err)
+			}
+			c := collectors[0]
+			ch := make(chan prometheus.Metric, 1024)
+			done := make(chan struct{})
+			go func() {
+				for range ch {
+				}
+				close(done)
+			}()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := c.Collect(context.Background(), zone, ch); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			close(ch)
+			<-done
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package collector
+
+import "strings"
+
+// contentClasses maps the content types of dashboard analytics to the
+// classes they are rolled up into with DashboardOptions.ContentClasses.
+// Cloudflare reports content types by their subtype, e.g. png or json.
+var contentClasses = map[string]string{
+	"html":       "html",
+	"xhtml":      "html",
+	"json":       "api",
+	"xml":        "api",
+	"javascript": "script",
+	"js":         "script",
+	"wasm":       "script",
+	"png":        "image",
+	"jpeg":       "image",
+	"jpg":        "image",
+	"gif":        "image",
+	"webp":       "image",
+	"avif":       "image",
+	"svg":        "image",
+	"ico":        "image",
+	"bmp":        "image",
+	"tiff":       "image",
+	"mp4":        "video",
+	"webm":       "video",
+	"ogg":        "video",
+	"mpeg":       "video",
+	"quicktime":  "video",
+	"m3u8":       "video",
+	"mpd":        "video",
+	"ts":         "video",
+}
+
+// otherContentClass is the class of content types without one, such as
+// css, fonts and octet-stream.
+const otherContentClass = "other"
+
+// contentClass returns the class of contentType, or otherContentClass.
+// Full media types such as image/png are classed by their subtype, and
+// structured syntaxes such as ld+json by their suffix.
+func contentClass(contentType string) string {
+	contentType = strings.ToLower(contentType)
+	if i := strings.LastIndex(contentType, "/"); i >= 0 {
+		contentType = contentType[i+1:]
+	}
+	if contentType == "svg+xml" {
+		return "image"
+	}
+	if i := strings.LastIndex(contentType, "+"); i >= 0 {
+		contentType = contentType[i+1:]
+	}
+	if class, ok := contentClasses[strings.TrimPrefix(contentType, "x-")]; ok {
+		return class
+	}
+	return otherContentClass
+}
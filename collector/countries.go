@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// continentCountries lists the ISO 3166-1 alpha-2 country codes of each
+// continent, by continent code as used by GeoNames.
+var continentCountries = map[string]string{
+	"AF": "DZ AO BJ BW BF BI CV CM CF TD KM CG CD CI DJ EG GQ ER SZ ET GA GM GH GN GW KE LS LR LY MG MW ML MR MU YT MA MZ NA NE NG RE RW SH ST SN SC SL SO ZA SS SD TZ TG TN UG EH ZM ZW",
+	"AN": "AQ BV GS HM TF",
+	"AS": "AF AM AZ BH BD BT BN KH CN CY GE HK IN ID IR IQ IL JP JO KZ KW KG LA LB MO MY MV MN MM NP KP OM PK PS PH QA SA SG KR LK SY TW TJ TH TL TR TM AE UZ VN YE IO CC CX",
+	"EU": "AX AL AD AT BY BE BA BG HR CZ DK EE FO FI FR DE GI GR GG VA HU IS IE IM IT JE XK LV LI LT LU MT MD MC ME NL MK NO PL PT RO RU SM RS SK SI ES SJ SE CH UA GB",
+	"NA": "AI AG AW BS BB BZ BM BQ VG CA KY CR CU CW DM DO SV GL GD GP GT HT HN JM MQ MX MS NI PA PR BL KN LC MF PM VC SX TT TC US VI UM",
+	"OC": "AS AU CK FJ PF GU KI MH FM NR NC NZ NU NF MP PW PG PN WS SB TK TO TV VU WF",
+	"SA": "AR BO BR CL CO EC FK GF GY PY PE SR UY VE",
+}
+
+// otherCountryGroup is the group of country codes without one, such as XX
+// for unknown countries and T1 for Tor.
+const otherCountryGroup = "other"
+
+// CountryGroups rolls country breakdowns up into coarser groups, which
+// replaces some 250 series per zone and metric with a handful.
+type CountryGroups struct {
+	// Label is the name of the label holding the group, in place of
+	// country_code.
+	Label  string
+	groups map[string]string
+}
+
+// ContinentGroups returns the groups of countries by continent code, e.g.
+// EU or NA, under the continent label.
+func ContinentGroups() *CountryGroups {
+	g := &CountryGroups{Label: "continent", groups: map[string]string{}}
+	for continent, countries := range continentCountries {
+		for _, country := range strings.Fields(countries) {
+			g.groups[country] = continent
+		}
+	}
+	return g
+}
+
+// LoadCountryGroups reads groups of countries from a JSON file mapping
+// country codes to group names, e.g. {"US": "domestic"}, under the
+// country_group label.
+func LoadCountryGroups(path string) (*CountryGroups, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read country groups: %s", err)
+	}
+	var groups map[string]string
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, fmt.Errorf("invalid country groups %s: %s", path, err)
+	}
+	g := &CountryGroups{Label: "country_group", groups: make(map[string]string, len(groups))}
+	for country, group := range groups {
+		g.groups[strings.ToUpper(country)] = group
+	}
+	return g, nil
+}
+
+// group returns the group of country, or otherCountryGroup.
+func (g *CountryGroups) group(country string) string {
+	if group, ok := g.groups[strings.ToUpper(country)]; ok {
+		return group
+	}
+	return otherCountryGroup
+}
@@ -0,0 +1,300 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("dashboard_analytics", newDashboardCollector)
+	RequireFeature("dashboard_analytics", FeatureProxied)
+}
+
+// botIPClasses are the IP classes counted as bot requests: search engine
+// crawlers and badHost, Cloudflare's class for IP addresses with a bad
+// reputation.
+var botIPClasses = []string{"searchEngine", "badHost"}
+
+// dashboardCollector collects zone analytics from the dashboard endpoints.
+//
+// Free, Pro and Business plans:
+// Dashboard Analytics is for Global Cloudflare network
+// Dashboard Analytics Labels are empty
+// Dashboard Analytics Namespace is "cloudflare"
+//
+// Enterprise plans:
+// Dashboard Analytics broken out by point of presence (PoP, sometimes also called "colo")
+// Dashboard Analytics Labels are colo_id, pop_id, pop_name, pop_region
+// Dashboard Analytics Namespace is "cloudflare_pop"
+type dashboardCollector struct {
+	cf       API
+	opts     DashboardOptions
+	popNames bool
+	// aggregation adds the aggregation label, see Options.AggregationLabel.
+	aggregation bool
+	// coloFilter restricts the colos exported for Enterprise zones.
+	coloFilter *coloFilter
+	countries  *CountryGroups
+	descs      []*prometheus.Desc
+
+	windowStart *prometheus.Desc
+	windowEnd   *prometheus.Desc
+
+	allRequests      *prometheus.Desc
+	cachedRequests   *prometheus.Desc
+	uncachedRequests *prometheus.Desc
+
+	encryptedRequests   *prometheus.Desc
+	unencryptedRequests *prometheus.Desc
+
+	byStatusRequests      *prometheus.Desc
+	byContentTypeRequests *prometheus.Desc
+	byCountryRequests     *prometheus.Desc
+	byIPClassRequests     *prometheus.Desc
+	botRequestRatio       *prometheus.Desc
+
+	totalBandwidth    *prometheus.Desc
+	cachedBandwidth   *prometheus.Desc
+	uncachedBandwidth *prometheus.Desc
+
+	encryptedBandwidth   *prometheus.Desc
+	unencryptedBandwidth *prometheus.Desc
+
+	byContentTypeBandwidth *prometheus.Desc
+	byCountryBandwidth     *prometheus.Desc
+
+	allThreats       *prometheus.Desc
+	byTypeThreats    *prometheus.Desc
+	byCountryThreats *prometheus.Desc
+
+	allPageviews            *prometheus.Desc
+	bySearchEnginePageviews *prometheus.Desc
+
+	uniqueIPAddresses *prometheus.Desc
+
+	cacheHitRatio *prometheus.Desc
+	errorRatio    *prometheus.Desc
+	httpsRatio    *prometheus.Desc
+}
+
+func newDashboardCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := byPopSet(descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}, zone.Plan.LegacyID == "enterprise", opts)
+
+	c := &dashboardCollector{cf: api, opts: opts.Dashboard, popNames: opts.PopNames, aggregation: opts.AggregationLabel, countries: opts.CountryGroups}
+	if zone.Plan.LegacyID == "enterprise" {
+		c.coloFilter = newColoFilter(api, zone, opts)
+	}
+	countryLabel := "country_code"
+	if c.countries != nil {
+		countryLabel = c.countries.Label
+	}
+	contentTypeLabel := "content_type"
+	if opts.Dashboard.ContentClasses {
+		contentTypeLabel = "content_class"
+	}
+	// The window is the same for every PoP.
+	c.descs = descTable{
+		{&c.windowStart, metricDef{"dashboard", "window_start_timestamp_seconds", "Start of the time bucket the dashboard analytics were exported from", nil}},
+		{&c.windowEnd, metricDef{"dashboard", "window_end_timestamp_seconds", "End of the time bucket the dashboard analytics were exported from", nil}},
+	}.build(descSet{namespace: Namespace, constLabels: ZoneLabels(zone, opts)})
+	c.descs = append(c.descs, descTable{
+		{&c.allRequests, metricDef{"requests", "total", "Total number of requests served", nil}},
+		{&c.cachedRequests, metricDef{"requests", "cached", "Total number of cached requests served", nil}},
+		{&c.uncachedRequests, metricDef{"requests", "uncached", "Total number of requests served from the origin", nil}},
+		{&c.encryptedRequests, metricDef{"requests", "encrypted", "The number of requests served over HTTPS", nil}},
+		{&c.unencryptedRequests, metricDef{"requests", "unencrypted", "The number of requests served over HTTP", nil}},
+		{&c.byStatusRequests, metricDef{"requests", "by_status", "The total number of requests broken out by status code", []string{"status_code"}}},
+		{&c.byContentTypeRequests, metricDef{"requests", "by_content_type", "The total number of requests broken out by content type", []string{contentTypeLabel}}},
+		{&c.byCountryRequests, metricDef{"requests", "by_country", "The total number of requests broken out by country", []string{countryLabel}}},
+		{&c.byIPClassRequests, metricDef{"requests", "by_ip_class", "The total number of requests broken out by IP class", []string{"ip_class"}}},
+		{&c.botRequestRatio, metricDef{"bot", "request_ratio", "Share of requests from search engine crawlers and IP addresses with a bad reputation, by IP class", nil}},
+
+		{&c.totalBandwidth, metricDef{"bandwidth", "total_bytes", "The total number of bytes served within the time frame", nil}},
+		{&c.cachedBandwidth, metricDef{"bandwidth", "cached_bytes", "The total number of bytes that were cached (and served) by Cloudflare", nil}},
+		{&c.uncachedBandwidth, metricDef{"bandwidth", "uncached_bytes", "The total number of bytes that were fetched and served from the origin server", nil}},
+		{&c.encryptedBandwidth, metricDef{"bandwidth", "encrypted_bytes", "The total number of bytes served over HTTPS", nil}},
+		{&c.unencryptedBandwidth, metricDef{"bandwidth", "unencrypted_bytes", "The total number of bytes served over HTTP", nil}},
+		{&c.byContentTypeBandwidth, metricDef{"bandwidth", "by_content_type_bytes", "The total number of bytes served broken out by content type", []string{contentTypeLabel}}},
+		{&c.byCountryBandwidth, metricDef{"bandwidth", "by_country_bytes", "The total number of bytes served broken out by country", []string{countryLabel}}},
+
+		{&c.allThreats, metricDef{"threats", "total", "The total number of identifiable threats received", nil}},
+		{&c.byTypeThreats, metricDef{"threats", "by_type", "The total number of identifiable threats received broken out by type", []string{"type"}}},
+		{&c.byCountryThreats, metricDef{"threats", "by_country", "The total number of identifiable threats received broken out by country", []string{countryLabel}}},
+
+		{&c.allPageviews, metricDef{"pageviews", "total", "The total number of pageviews served", nil}},
+		{&c.bySearchEnginePageviews, metricDef{"pageviews", "by_search_engine", "The total number of pageviews served broken out by search engine", []string{"search_engine"}}},
+
+		{&c.uniqueIPAddresses, metricDef{"unique_ip_addresses", "total", "Total number of unique IP addresses", nil}},
+	}.build(set)...)
+	if c.opts.Ratios {
+		c.descs = append(c.descs, descTable{
+			{&c.cacheHitRatio, metricDef{"requests", "cache_hit_ratio", "Share of requests served from cache", nil}},
+			{&c.errorRatio, metricDef{"requests", "error_ratio", "Share of requests answered with a 5xx status code", nil}},
+			{&c.httpsRatio, metricDef{"requests", "https_ratio", "Share of requests served over HTTPS", nil}},
+		}.build(set)...)
+	}
+	return c
+}
+
+func (c *dashboardCollector) Name() string { return "dashboard_analytics" }
+
+func (c *dashboardCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *dashboardCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	now := time.Now()
+	sinceTime := now.Add(-10080 * time.Minute).UTC() // 7 days
+	if c.opts.Since > 0 {
+		sinceTime = now.Add(-c.opts.Since).UTC()
+	} else if zone.Plan.LegacyID == "enterprise" {
+		sinceTime = now.Add(-30 * time.Minute).UTC() // Anything higher than business gets 1 minute resolution, minimum -30 minutes
+	} else if zone.Plan.LegacyID == "business" {
+		sinceTime = now.Add(-6 * time.Hour).UTC() // Business plans get 15 minute resolution, minimum -6 hours
+	} else if zone.Plan.LegacyID == "pro" {
+		sinceTime = now.Add(-24 * time.Hour).UTC() // Pro plans get 15 minute resolution, minimum -24 hours
+	}
+	continuous := c.opts.Continuous
+	opts := cloudflare.ZoneAnalyticsOptions{
+		Since:      &sinceTime,
+		Continuous: &continuous,
+	}
+	var data []cloudflare.ZoneAnalyticsData
+	var err error
+	if zone.Plan.LegacyID == "enterprise" {
+		data, err = c.cf.ZoneAnalyticsByColocation(zone.ID, opts)
+	} else {
+		singleData, singleDataErr := c.cf.ZoneAnalyticsDashboard(zone.ID, opts)
+		err = singleDataErr
+		data = append(data, singleData)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get dashboard analytics from cloudflare: %s", err)
+	}
+
+	windowSent := false
+	colos := c.coloFilter.current()
+	for _, entry := range data {
+		if len(entry.Timeseries) == 0 || !colos.allows(entry.ColocationID) {
+			continue
+		}
+		labels := byPopLabelValues(entry.ColocationID, zone.Plan.LegacyID == "enterprise", c.aggregation, c.popNames)
+		extra := newLabelBuffer(labels)
+
+		// Only the latest time bucket is exported.
+		latestEntry := entry.Timeseries[len(entry.Timeseries)-1]
+		if !windowSent {
+			ch <- prometheus.MustNewConstMetric(c.windowStart, prometheus.GaugeValue, float64(latestEntry.Since.Unix()))
+			ch <- prometheus.MustNewConstMetric(c.windowEnd, prometheus.GaugeValue, float64(latestEntry.Until.Unix()))
+			windowSent = true
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.allRequests, prometheus.GaugeValue, float64(latestEntry.Requests.All), labels...)
+		ch <- prometheus.MustNewConstMetric(c.cachedRequests, prometheus.GaugeValue, float64(latestEntry.Requests.Cached), labels...)
+		ch <- prometheus.MustNewConstMetric(c.uncachedRequests, prometheus.GaugeValue, float64(latestEntry.Requests.Uncached), labels...)
+		ch <- prometheus.MustNewConstMetric(c.encryptedRequests, prometheus.GaugeValue, float64(latestEntry.Requests.SSL.Encrypted), labels...)
+		ch <- prometheus.MustNewConstMetric(c.unencryptedRequests, prometheus.GaugeValue, float64(latestEntry.Requests.SSL.Unencrypted), labels...)
+		if c.opts.wantsBreakdown("status") {
+			for code, count := range latestEntry.Requests.HTTPStatus {
+				ch <- prometheus.MustNewConstMetric(c.byStatusRequests, prometheus.GaugeValue, float64(count), extra.with(code)...)
+			}
+		}
+		if c.opts.wantsBreakdown("content_type") {
+			c.collectContentTypes(c.byContentTypeRequests, latestEntry.Requests.ContentType, extra, ch)
+		}
+		if c.opts.wantsBreakdown("country") {
+			c.collectCountries(c.byCountryRequests, latestEntry.Requests.Country, extra, ch)
+		}
+		if c.opts.wantsBreakdown("ip_class") {
+			bots := 0
+			for class, count := range latestEntry.Requests.IPClass {
+				ch <- prometheus.MustNewConstMetric(c.byIPClassRequests, prometheus.GaugeValue, float64(count), extra.with(class)...)
+				if contains(botIPClasses, class) {
+					bots += count
+				}
+			}
+			if latestEntry.Requests.All > 0 {
+				ch <- prometheus.MustNewConstMetric(c.botRequestRatio, prometheus.GaugeValue, float64(bots)/float64(latestEntry.Requests.All), labels...)
+			}
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.totalBandwidth, prometheus.GaugeValue, float64(latestEntry.Bandwidth.All), labels...)
+		ch <- prometheus.MustNewConstMetric(c.cachedBandwidth, prometheus.GaugeValue, float64(latestEntry.Bandwidth.Cached), labels...)
+		ch <- prometheus.MustNewConstMetric(c.uncachedBandwidth, prometheus.GaugeValue, float64(latestEntry.Bandwidth.Uncached), labels...)
+		ch <- prometheus.MustNewConstMetric(c.encryptedBandwidth, prometheus.GaugeValue, float64(latestEntry.Bandwidth.SSL.Encrypted), labels...)
+		ch <- prometheus.MustNewConstMetric(c.unencryptedBandwidth, prometheus.GaugeValue, float64(latestEntry.Bandwidth.SSL.Unencrypted), labels...)
+		c.collectContentTypes(c.byContentTypeBandwidth, latestEntry.Bandwidth.ContentType, extra, ch)
+		c.collectCountries(c.byCountryBandwidth, latestEntry.Bandwidth.Country, extra, ch)
+
+		ch <- prometheus.MustNewConstMetric(c.allThreats, prometheus.GaugeValue, float64(latestEntry.Threats.All), labels...)
+		for threatType, count := range latestEntry.Threats.Type {
+			ch <- prometheus.MustNewConstMetric(c.byTypeThreats, prometheus.GaugeValue, float64(count), extra.with(threatType)...)
+		}
+		c.collectCountries(c.byCountryThreats, latestEntry.Threats.Country, extra, ch)
+
+		ch <- prometheus.MustNewConstMetric(c.allPageviews, prometheus.GaugeValue, float64(latestEntry.Pageviews.All), labels...)
+		for searchEngine, count := range latestEntry.Pageviews.SearchEngines {
+			ch <- prometheus.MustNewConstMetric(c.bySearchEnginePageviews, prometheus.GaugeValue, float64(count), extra.with(searchEngine)...)
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.uniqueIPAddresses, prometheus.GaugeValue, float64(latestEntry.Uniques.All), labels...)
+
+		// Ratios are undefined without requests, so they are left out
+		// rather than exported as zero.
+		if c.opts.Ratios && latestEntry.Requests.All > 0 {
+			all := float64(latestEntry.Requests.All)
+			serverErrors := 0
+			for code, count := range latestEntry.Requests.HTTPStatus {
+				if strings.HasPrefix(code, "5") {
+					serverErrors += count
+				}
+			}
+			ch <- prometheus.MustNewConstMetric(c.cacheHitRatio, prometheus.GaugeValue, float64(latestEntry.Requests.Cached)/all, labels...)
+			ch <- prometheus.MustNewConstMetric(c.errorRatio, prometheus.GaugeValue, float64(serverErrors)/all, labels...)
+			ch <- prometheus.MustNewConstMetric(c.httpsRatio, prometheus.GaugeValue, float64(latestEntry.Requests.SSL.Encrypted)/all, labels...)
+		}
+	}
+	return nil
+}
+
+// collectCountries sends a gauge of desc per country, or per country group
+// summed over its countries if countries are rolled up.
+func (c *dashboardCollector) collectCountries(desc *prometheus.Desc, byCountry map[string]int, extra labelBuffer, ch chan<- prometheus.Metric) {
+	if c.countries == nil {
+		for country, count := range byCountry {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(count), extra.with(country)...)
+		}
+		return
+	}
+	sum := newLabelSum()
+	for country, count := range byCountry {
+		sum.add(float64(count), extra.with(c.countries.group(country))...)
+	}
+	sum.collect(desc, ch)
+}
+
+// collectContentTypes sends a gauge of desc per content type, or per
+// content class summed over its content types if they are rolled up.
+func (c *dashboardCollector) collectContentTypes(desc *prometheus.Desc, byContentType map[string]int, extra labelBuffer, ch chan<- prometheus.Metric) {
+	if !c.opts.ContentClasses {
+		for contentType, count := range byContentType {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(count), extra.with(contentType)...)
+		}
+		return
+	}
+	sum := newLabelSum()
+	for contentType, count := range byContentType {
+		sum.add(float64(count), extra.with(contentClass(contentType))...)
+	}
+	sum.collect(desc, ch)
+}
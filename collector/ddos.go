@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("ddos", newDDoSCollector)
+	RequireFeature("ddos", FeatureProxied)
+}
+
+const ddosQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: firewallEventsAdaptiveGroups(limit: 10000, filter: {source: "l7ddos", datetime_geq: $since, datetime_lt: $until}) {
+        count
+        dimensions {
+          attackId
+          action
+          ruleId
+        }
+      }
+    }
+  }
+}`
+
+// ddosCollector collects requests mitigated by the HTTP DDoS protection
+// managed ruleset, using the GraphQL Analytics API. Rule IDs are resolved to
+// rule descriptions using the Rulesets API.
+type ddosCollector struct {
+	gql   *GraphQLClient
+	rules *ruleDescriptions
+	descs []*prometheus.Desc
+
+	mitigatedRequests *prometheus.Desc
+}
+
+func newDDoSCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &ddosCollector{gql: opts.GraphQL, rules: newRuleDescriptions(api, zone.ID, rulesetDescriptions)}
+	c.descs = descTable{
+		{&c.mitigatedRequests, metricDef{"ddos", "http_mitigated_requests", "Number of requests mitigated by HTTP DDoS protection in the last 5 minutes", []string{"attack_id", "action", "rule_id", "rule_description"}}},
+	}.build(set)
+	return c
+}
+
+func (c *ddosCollector) Name() string { return "ddos" }
+
+func (c *ddosCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *ddosCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if c.gql == nil {
+		return nil
+	}
+	c.rules.prefetch()
+	groups, err := c.gql.zoneGroups(ctx, zone.ID, ddosQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get ddos mitigations from cloudflare: %s", err)
+	}
+	for _, g := range groups {
+		ruleID := g.dimension("ruleId")
+		ch <- prometheus.MustNewConstMetric(c.mitigatedRequests, prometheus.GaugeValue, g.Count, g.dimension("attackId"), g.dimension("action"), ruleID, c.rules.describe(ctx, ruleID))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricDef describes a metric independently of the zone it is exported for.
+type metricDef struct {
+	subsystem string
+	name      string
+	help      string
+	// labels are appended to the base labels of the descSet.
+	labels []string
+}
+
+// descSet holds what every metric of a collector has in common for a zone:
+// its namespace, leading variable labels, help suffix and constant labels.
+type descSet struct {
+	namespace   string
+	helpSuffix  string
+	labels      []string
+	constLabels prometheus.Labels
+}
+
+func (s descSet) desc(d metricDef) *prometheus.Desc {
+	help := d.help
+	if s.helpSuffix != "" {
+		help = fmt.Sprintf("%s %s", help, s.helpSuffix)
+	}
+	fqName := prometheus.BuildFQName(s.namespace, d.subsystem, d.name)
+	labels := WithLabels(s.labels, d.labels...)
+	desc := prometheus.NewDesc(fqName, help, labels, s.constLabels)
+	recordMetadata(desc, fqName, help, labels, s.constLabels)
+	return desc
+}
+
+// descTable binds metric definitions to the fields they should populate, so
+// a collector can declare all of its descriptors in one table.
+type descTable []struct {
+	dst **prometheus.Desc
+	def metricDef
+}
+
+// build creates every descriptor in t and returns them in table order.
+func (t descTable) build(s descSet) []*prometheus.Desc {
+	descs := make([]*prometheus.Desc, 0, len(t))
+	for _, entry := range t {
+		*entry.dst = s.desc(entry.def)
+		descs = append(descs, *entry.dst)
+	}
+	return descs
+}
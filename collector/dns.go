@@ -0,0 +1,586 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("dns_analytics", newDNSCollector)
+}
+
+// dnsCollector collects DNS analytics for a zone. The requested metrics and
+// dimensions can be configured; by default they are derived from the plan.
+// Labels always contain query_name, response_code, origin, tcp, ip_version,
+// response_cached, query_type, empty for dimensions that are not requested:
+//
+// Free plans:
+// DNS Analytics is for Global Cloudflare network
+// DNS Analytics Dimensions contain queryName, responseCode, origin, tcp, ipVersion
+// DNS Analytics Namespace is "cloudflare"
+//
+// Pro plans:
+// DNS Analytics broken out by point of presence (PoP, sometimes also called "colo")
+// DNS Analytics Labels additionally contain colo_id, pop_id, pop_name, pop_region
+// DNS Analytics Dimensions contain queryName, responseCode, origin, tcp, ipVersion, coloName (really ID, name/region provided by statuspage)
+// DNS Analytics Namespace is "cloudflare_pop"
+//
+// Business and Enterprise plans:
+// DNS Analytics broken out by point of presence (PoP, sometimes also called "colo")
+// DNS Analytics Labels additionally contain colo_id, pop_id, pop_name, pop_region
+// DNS Analytics Dimensions contain queryName, responseCode, origin, tcp, ipVersion, responseCached, queryType, coloName (really ID, name/region provided by statuspage)
+// DNS Analytics Namespace is "cloudflare_pop"
+type dnsCollector struct {
+	cf      API
+	opts    DNSOptions
+	request dnsRequest
+	labels  int
+	metrics []string
+	byColo  bool
+	// popNames adds pop_name and pop_region to the colo labels.
+	popNames bool
+	// aggregation adds the aggregation label, see Options.AggregationLabel.
+	aggregation bool
+	// coloFilter restricts the colos exported if byColo.
+	coloFilter *coloFilter
+	scales     []float64
+	// counters marks the metrics exported as counters, names holds their
+	// fully-qualified names for state keys.
+	counters []bool
+	names    []string
+	descs    []*prometheus.Desc
+	state    *StateStore
+
+	mu        sync.Mutex
+	seenColos []string
+	// series are the counter series seen so far, by state key. They are
+	// exported on every scrape, even when a query returns no data for them,
+	// until they have not been seen for opts.SeriesTTL.
+	series map[string]dnsSeries
+	// pruned is when state of series not seen since the exporter started
+	// was last looked for, see finish.
+	pruned time.Time
+	// lastBucket is the end of the newest time bucket counted.
+	lastBucket time.Time
+	// probe requests dimensions the zone's plan is not known to allow
+	// besides those of request. It is used from the first successful query
+	// on, and dropped if Cloudflare rejects it.
+	probe  *dnsRequest
+	probed bool
+	// truncated is set once a collection hit opts.MaxRows, so the warning
+	// is only logged once.
+	truncated bool
+}
+
+// dnsSeries is a counter series exported by the DNS analytics collector.
+type dnsSeries struct {
+	metric int
+	labels []string
+	// seen is when a response last had a row for the series.
+	seen time.Time
+}
+
+// Counts are only added to counters once their time bucket ended
+// dnsSettleDelay ago, as the newest bucket is still filling up. Queries
+// start at the last counted bucket, unless that is more than dnsMaxCatchUp
+// ago.
+const (
+	dnsSettleDelay = time.Minute
+	dnsMaxCatchUp  = 6 * time.Hour
+)
+
+// dnsRequest is the set of dimensions requested from the DNS analytics API.
+type dnsRequest struct {
+	dimensions []string
+	// labelIndex holds, for each requested dimension other than coloName,
+	// the index of its label.
+	labelIndex []int
+}
+
+// dnsMetric describes how a DNS analytics API metric is exported.
+type dnsMetric struct {
+	name string
+	help string
+	// scale converts the API value to the exported unit. Zero means 1.
+	scale float64
+	// counter marks counts, which are summed over time buckets into
+	// counters rather than exported as the latest bucket.
+	counter bool
+}
+
+// dnsMetrics maps the DNS analytics API metrics that can be requested to
+// the metrics they are exported as.
+var dnsMetrics = map[string]dnsMetric{
+	"queryCount":    {"queries_total", "Total number of DNS queries", 0, true},
+	"uncachedCount": {"uncached_queries_total", "Total number of uncached DNS queries", 0, true},
+	"staleCount":    {"stale_queries_total", "Total number of stale DNS queries", 0, true},
+
+	// Response times are reported in milliseconds.
+	"responseTimeAvg":    {"response_time_avg_seconds", "Average DNS response time in seconds", 0.001, false},
+	"responseTimeMedian": {"response_time_median_seconds", "Median DNS response time in seconds", 0.001, false},
+	"responseTime90th":   {"response_time_90th_percentile_seconds", "90th percentile DNS response time in seconds", 0.001, false},
+	"responseTime99th":   {"response_time_99th_percentile_seconds", "99th percentile DNS response time in seconds", 0.001, false},
+}
+
+// dnsDimension is a DNS analytics API dimension that can be requested.
+type dnsDimension struct {
+	name string
+	// label is the label the dimension is exported as. coloName has none
+	// as it is exported as the colo labels instead.
+	label string
+	// plans are the plans the dimension is available on.
+	plans []string
+}
+
+var (
+	allPlans  = []string{"free", "pro", "business", "enterprise"}
+	coloPlans = []string{"pro", "business", "enterprise"}
+	richPlans = []string{"business", "enterprise"}
+)
+
+// dnsDimensions is the schema of the DNS analytics dimensions, in the order
+// they are requested and labels are exported. Every label is exported for
+// every zone, empty if the dimension is not requested, so that zones on
+// different plans export metrics with the same label names. coloName must
+// come last as the colo is read from the end of each row.
+var dnsDimensions = []dnsDimension{
+	{"queryName", "query_name", allPlans},
+	{"responseCode", "response_code", allPlans},
+	{"origin", "origin", allPlans},
+	{"tcp", "tcp", allPlans},
+	{"ipVersion", "ip_version", allPlans},
+	{"responseCached", "response_cached", richPlans},
+	{"queryType", "query_type", richPlans},
+	{"coloName", "", coloPlans},
+}
+
+var defaultDNSMetrics = []string{"queryCount", "uncachedCount", "staleCount", "responseTimeAvg", "responseTimeMedian", "responseTime90th", "responseTime99th"}
+
+func findDNSDimension(name string) (dnsDimension, bool) {
+	for _, d := range dnsDimensions {
+		if d.name == name {
+			return d, true
+		}
+	}
+	return dnsDimension{}, false
+}
+
+// availableOn reports whether the dimension is available on plan. Unknown
+// plans are treated as free ones.
+func (d dnsDimension) availableOn(plan string) bool {
+	if !contains(allPlans, plan) {
+		plan = "free"
+	}
+	return contains(d.plans, plan)
+}
+
+// Validate checks that all configured DNS metrics and dimensions are known.
+func (o DNSOptions) Validate() error {
+	for _, m := range o.Metrics {
+		if _, ok := dnsMetrics[m]; !ok {
+			return fmt.Errorf("unknown DNS analytics metric %q", m)
+		}
+	}
+	for _, d := range o.Dimensions {
+		if _, ok := findDNSDimension(d); !ok {
+			return fmt.Errorf("unknown DNS analytics dimension %q", d)
+		}
+	}
+	return nil
+}
+
+func newDNSCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	// Dimensions are requested in schema order, which puts coloName last as
+	// it is expanded into the colo labels. Wanted dimensions the plan is not known
+	// to allow, other than coloName which changes the exported metrics, are
+	// probed for.
+	byColo := false
+	labels := []string{}
+	request := dnsRequest{}
+	probe := dnsRequest{}
+	for _, d := range dnsDimensions {
+		wanted := len(opts.DNS.Dimensions) == 0 || contains(opts.DNS.Dimensions, d.name)
+		if d.name == "coloName" {
+			byColo = wanted && d.availableOn(zone.Plan.LegacyID)
+			continue
+		}
+		if wanted && d.availableOn(zone.Plan.LegacyID) {
+			request.add(d.name, len(labels))
+		}
+		if wanted {
+			probe.add(d.name, len(labels))
+		}
+		labels = append(labels, d.label)
+	}
+
+	set := byPopSet(descSet{
+		namespace:   Namespace,
+		labels:      labels,
+		constLabels: ZoneLabels(zone, opts),
+	}, byColo, opts)
+	if byColo {
+		request.dimensions = append(request.dimensions, "coloName")
+		probe.dimensions = append(probe.dimensions, "coloName")
+	}
+
+	metrics := opts.DNS.Metrics
+	if len(metrics) == 0 {
+		metrics = defaultDNSMetrics
+	}
+	c := &dnsCollector{
+		cf:          api,
+		opts:        opts.DNS,
+		request:     request,
+		labels:      len(labels),
+		metrics:     metrics,
+		byColo:      byColo,
+		popNames:    opts.PopNames,
+		aggregation: opts.AggregationLabel,
+		state:       opts.State,
+		series:      map[string]dnsSeries{},
+	}
+	if byColo {
+		c.coloFilter = newColoFilter(api, zone, opts)
+	}
+	for _, m := range metrics {
+		scale := dnsMetrics[m].scale
+		if scale == 0 {
+			scale = 1
+		}
+		c.scales = append(c.scales, scale)
+		c.counters = append(c.counters, dnsMetrics[m].counter)
+		c.names = append(c.names, prometheus.BuildFQName(set.namespace, "dns_record", dnsMetrics[m].name))
+		c.descs = append(c.descs, set.desc(metricDef{"dns_record", dnsMetrics[m].name, dnsMetrics[m].help, nil}))
+	}
+
+	log.Debugf("DNS metrics namespace: '%s'", set.namespace)
+	log.Debugf("DNS metrics labels: '%s'", strings.Join(set.labels, ", "))
+	log.Debugf("DNS dimensions: '%s'", strings.Join(request.dimensions, ", "))
+	if len(probe.dimensions) > len(request.dimensions) {
+		c.probe = &probe
+		log.Debugf("DNS dimensions to probe for: '%s'", strings.Join(probe.dimensions, ", "))
+	}
+	return c
+}
+
+func (r *dnsRequest) add(dimension string, labelIndex int) {
+	r.dimensions = append(r.dimensions, dimension)
+	r.labelIndex = append(r.labelIndex, labelIndex)
+}
+
+func (c *dnsCollector) Name() string { return "dns_analytics" }
+
+func (c *dnsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *dnsCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	c.mu.Lock()
+	probe, probed := c.probe, c.probed
+	c.mu.Unlock()
+
+	if probe == nil {
+		return c.collect(zone, c.request, ch)
+	}
+	if probed {
+		return c.collect(zone, *probe, ch)
+	}
+
+	// Nothing is sent until the probe query succeeds, so a rejected probe
+	// does not leave series with the wrong labels behind. Probing happens
+	// once, so its responses are held rather than streamed.
+	var data []cloudflare.ZoneDNSAnalyticsByTimeData
+	err := c.query(zone, *probe, func(d cloudflare.ZoneDNSAnalyticsByTimeData) {
+		data = append(data, d)
+	})
+	if err != nil {
+		if class := ClassifyError(err); class != ErrorClassNotEntitled && class != ErrorClassOther {
+			return fmt.Errorf("failed to get dns analytics from cloudflare: %s", err)
+		}
+		extra := []string{}
+		for _, d := range probe.dimensions {
+			if !contains(c.request.dimensions, d) {
+				extra = append(extra, d)
+			}
+		}
+		log.Infof("Zone %s does not allow DNS analytics dimensions %s, using those of its %s plan: %s", zone.Name, strings.Join(extra, ", "), zone.Plan.LegacyID, err)
+		c.mu.Lock()
+		c.probe = nil
+		c.mu.Unlock()
+		return c.collect(zone, c.request, ch)
+	}
+	c.mu.Lock()
+	c.probed = true
+	c.mu.Unlock()
+	e := c.newExport(zone, *probe, ch)
+	for _, d := range data {
+		e.rows(d)
+	}
+	e.finish()
+	return nil
+}
+
+func (c *dnsCollector) collect(zone cloudflare.Zone, request dnsRequest, ch chan<- prometheus.Metric) error {
+	// Each response is exported as soon as it arrives, and data of
+	// successful per-colo queries even if others failed.
+	e := c.newExport(zone, request, ch)
+	err := c.query(zone, request, e.rows)
+	e.finish()
+	if err != nil {
+		return fmt.Errorf("failed to get dns analytics from cloudflare: %s", err)
+	}
+	return nil
+}
+
+// query requests the dimensions of request for zone, passing each response
+// to handle as soon as it arrives, one at a time. Without a configured
+// range, it starts at the last counted time bucket so no bucket is missed
+// between scrapes.
+func (c *dnsCollector) query(zone cloudflare.Zone, request dnsRequest, handle func(cloudflare.ZoneDNSAnalyticsByTimeData)) error {
+	now := time.Now().UTC()
+	options := cloudflare.ZoneDNSAnalyticsOptions{
+		Metrics:    c.metrics,
+		Dimensions: request.dimensions,
+	}
+	c.mu.Lock()
+	lastBucket := c.lastBucket
+	c.mu.Unlock()
+	if c.opts.Since > 0 {
+		since := now.Add(-c.opts.Since)
+		options.Since = &since
+	} else if !lastBucket.IsZero() && now.Sub(lastBucket) < dnsMaxCatchUp {
+		since := lastBucket.UTC()
+		options.Since = &since
+	}
+	if c.opts.Until > 0 {
+		until := now.Add(-c.opts.Until)
+		options.Until = &until
+	}
+	if c.opts.TimeDelta != "" {
+		timeDelta := c.opts.TimeDelta
+		options.TimeDelta = &timeDelta
+	}
+
+	if colos := c.colos(); c.byColo && c.opts.PerColo && len(colos) > 0 {
+		return c.queryPerColo(zone.ID, options, colos, handle)
+	}
+	data, err := c.cf.ZoneDNSAnalyticsByTime(zone.ID, options)
+	if err != nil {
+		return err
+	}
+	if c.byColo {
+		c.learnColos(data.Rows)
+	}
+	handle(data)
+	return nil
+}
+
+// dnsExport sends the metrics of the responses to one collection. Counts
+// are added to their counters, other metrics are exported as their latest
+// time bucket. At most opts.MaxRows rows are exported, so a burst of
+// distinct query names cannot grow the exported series without bound.
+type dnsExport struct {
+	c        *dnsCollector
+	zone     cloudflare.Zone
+	request  dnsRequest
+	ch       chan<- prometheus.Metric
+	colos    coloSet
+	settled  time.Time
+	exported int
+	dropped  int
+}
+
+func (c *dnsCollector) newExport(zone cloudflare.Zone, request dnsRequest, ch chan<- prometheus.Metric) *dnsExport {
+	return &dnsExport{c: c, zone: zone, request: request, ch: ch, colos: c.coloFilter.current(), settled: time.Now().Add(-dnsSettleDelay)}
+}
+
+// rows sends the metrics of the rows of d, which was returned for the
+// export's request.
+func (e *dnsExport) rows(d cloudflare.ZoneDNSAnalyticsByTimeData) {
+	c := e.c
+	for _, row := range d.Rows {
+		if len(row.Dimensions) != len(e.request.dimensions) {
+			log.Debugf("Skipping DNS analytics row of zone %s with %d dimensions, expected %d", e.zone.Name, len(row.Dimensions), len(e.request.dimensions))
+			continue
+		}
+		if c.byColo && !e.colos.allows(row.Dimensions[len(row.Dimensions)-1]) {
+			continue
+		}
+		if c.opts.MaxRows > 0 && e.exported >= c.opts.MaxRows {
+			e.dropped++
+			continue
+		}
+		e.exported++
+		// Counter series keep their labels, so every row gets its own
+		// slice, sized for the colo labels up front.
+		labels := make([]string, c.labels, c.labels+len(PopLabels)+2)
+		for i, index := range e.request.labelIndex {
+			labels[index] = row.Dimensions[i]
+		}
+		coloID := ""
+		if c.byColo {
+			coloID = row.Dimensions[len(row.Dimensions)-1]
+		}
+		labels = append(labels, byPopLabelValues(coloID, c.byColo, c.aggregation, c.popNames)...)
+
+		for i, desc := range c.descs {
+			if i >= len(row.Metrics) || len(row.Metrics[i]) == 0 {
+				continue
+			}
+			if c.counters[i] {
+				c.count(e.zone, i, labels, row.Metrics[i], d.TimeIntervals, e.settled)
+				continue
+			}
+			e.ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, row.Metrics[i][len(row.Metrics[i])-1]*c.scales[i], labels...)
+		}
+	}
+}
+
+// finish sends every counter series seen so far, including those the
+// responses had no data for. Series not seen for opts.SeriesTTL are dropped
+// with their state, so query names seen once, e.g. during an attack, do
+// not stay in memory and the state file forever.
+func (e *dnsExport) finish() {
+	c := e.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e.dropped > 0 && !c.truncated {
+		c.truncated = true
+		log.Warnf("Zone %s returned more than %d DNS analytics rows, dropped %d; raise --dns.max-rows or request fewer dimensions", e.zone.Name, c.opts.MaxRows, e.dropped)
+	} else if e.dropped > 0 {
+		log.Debugf("Zone %s returned more than %d DNS analytics rows, dropped %d", e.zone.Name, c.opts.MaxRows, e.dropped)
+	}
+	now := time.Now()
+	expired := now.Add(-c.opts.SeriesTTL)
+	for key, series := range c.series {
+		if c.opts.SeriesTTL > 0 && series.seen.Before(expired) {
+			delete(c.series, key)
+			c.state.Delete(key)
+			continue
+		}
+		state, _ := c.state.Get(key)
+		e.ch <- prometheus.MustNewConstMetric(c.descs[series.metric], prometheus.CounterValue, state.Value, series.labels...)
+	}
+
+	// State loaded from the state file for series no response has had
+	// since is dropped once its last bucket is older than the TTL.
+	if c.opts.SeriesTTL > 0 && now.Sub(c.pruned) >= c.opts.SeriesTTL {
+		c.pruned = now
+		for i, name := range c.names {
+			if !c.counters[i] {
+				continue
+			}
+			pruned := c.state.DeleteFunc(name+"{"+e.zone.ID, func(key string, state SeriesState) bool {
+				_, ok := c.series[key]
+				return !ok && state.LastBucket.Before(expired)
+			})
+			if pruned > 0 {
+				log.Debugf("Dropped the state of %d %s series of zone %s not seen for %s", pruned, name, e.zone.Name, c.opts.SeriesTTL)
+			}
+		}
+	}
+}
+
+// count adds the values of the settled time buckets not counted yet to the
+// counter of metric with labels.
+func (c *dnsCollector) count(zone cloudflare.Zone, metric int, labels []string, values []float64, intervals [][]time.Time, settled time.Time) {
+	key := zoneSeriesKey(c.names[metric], zone.ID, labels)
+	state, _ := c.state.Get(key)
+	for i, value := range values {
+		if i >= len(intervals) || len(intervals[i]) < 2 {
+			continue
+		}
+		end := intervals[i][1]
+		if !end.After(state.LastBucket) || end.After(settled) {
+			continue
+		}
+		state.Value += value * c.scales[metric]
+		state.LastBucket = end
+	}
+	c.state.Set(key, state)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.series[key] = dnsSeries{metric: metric, labels: labels, seen: time.Now()}
+	if state.LastBucket.After(c.lastBucket) {
+		c.lastBucket = state.LastBucket
+	}
+}
+
+// colos returns the colos to query separately in per-colo mode: the
+// configured ones, or else those seen in the last full response.
+func (c *dnsCollector) colos() []string {
+	if len(c.opts.Colos) > 0 {
+		return c.opts.Colos
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seenColos
+}
+
+func (c *dnsCollector) learnColos(rows []cloudflare.ZoneDNSAnalyticsByTimeRow) {
+	seen := map[string]bool{}
+	colos := []string{}
+	for _, row := range rows {
+		colo := row.Dimensions[len(row.Dimensions)-1]
+		if !seen[colo] {
+			seen[colo] = true
+			colos = append(colos, colo)
+		}
+	}
+	sort.Strings(colos)
+	c.mu.Lock()
+	c.seenColos = colos
+	c.mu.Unlock()
+}
+
+// queryPerColo runs one query per colo, at most opts.Parallelism at a time,
+// which keeps responses for busy zones small and fast. Successful responses
+// are passed to handle as they arrive and the first error is returned.
+func (c *dnsCollector) queryPerColo(zoneID string, options cloudflare.ZoneDNSAnalyticsOptions, colos []string, handle func(cloudflare.ZoneDNSAnalyticsByTimeData)) error {
+	parallelism := c.opts.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	for _, colo := range colos {
+		wg.Add(1)
+		go func(colo string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			coloOptions := options
+			coloOptions.Filters = []string{"coloName==" + colo}
+			coloData, err := c.cf.ZoneDNSAnalyticsByTime(zoneID, coloOptions)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("colo %s: %s", colo, err)
+				}
+				return
+			}
+			handle(coloData)
+		}(strings.ToUpper(colo))
+	}
+	wg.Wait()
+	return firstErr
+}
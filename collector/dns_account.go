@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterAccount("dns_account_analytics", newDNSAccountCollector)
+}
+
+const dnsAccountQuery = `query ($zoneTags: [string!], $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag_in: $zoneTags}) {
+      groups: dnsAnalyticsAdaptiveGroups(limit: 10000, filter: {datetime_geq: $since, datetime_lt: $until}) {
+        count
+        dimensions {
+          responseCode
+          coloName
+        }
+      }
+    }
+  }
+}`
+
+// dnsAccountBatch is the number of zones selected per query, the most the
+// GraphQL API accepts in a zoneTag_in filter.
+const dnsAccountBatch = 10
+
+// dnsAccountCollector collects DNS queries summed over all monitored zones
+// of an account, by response code and colo. For accounts with hundreds of
+// DNS-only zones, it takes a query per ten zones and exports a series per
+// response code and colo, instead of a query and a full set of DNS
+// analytics series per zone. It only runs with DNSOptions.ByAccount.
+type dnsAccountCollector struct {
+	gql      *GraphQLClient
+	enabled  bool
+	popNames bool
+	zoneIDs  []string
+	descs    []*prometheus.Desc
+
+	queries *prometheus.Desc
+}
+
+func newDNSAccountCollector(api API, account Account, opts Options) AccountCollector {
+	set := descSet{
+		namespace:   Namespace,
+		labels:      coloLabels(opts.PopNames),
+		constLabels: AccountLabels(account),
+	}
+	c := &dnsAccountCollector{gql: opts.GraphQL, enabled: opts.DNS.ByAccount, popNames: opts.PopNames}
+	for _, zone := range opts.Zones {
+		if zone.Account.ID == account.ID {
+			c.zoneIDs = append(c.zoneIDs, zone.ID)
+		}
+	}
+	c.descs = descTable{
+		{&c.queries, metricDef{"account_dns", "queries", "Number of DNS queries to the account's zones in the last 5 minutes, from sampled data", []string{"response_code"}}},
+	}.build(set)
+	return c
+}
+
+func (c *dnsAccountCollector) Name() string { return "dns_account_analytics" }
+
+func (c *dnsAccountCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *dnsAccountCollector) Collect(ctx context.Context, account Account, ch chan<- prometheus.Metric) error {
+	if !c.enabled || c.gql == nil {
+		return nil
+	}
+	queries := newLabelSum()
+	for start := 0; start < len(c.zoneIDs); start += dnsAccountBatch {
+		end := start + dnsAccountBatch
+		if end > len(c.zoneIDs) {
+			end = len(c.zoneIDs)
+		}
+		groups, err := c.gql.zonesGroups(ctx, c.zoneIDs[start:end], dnsAccountQuery)
+		if err != nil {
+			return fmt.Errorf("failed to get account DNS analytics from cloudflare: %s", err)
+		}
+		for _, g := range groups {
+			queries.add(g.Count, WithLabels(coloLabelValues(g.dimension("coloName"), c.popNames), g.dimension("responseCode"))...)
+		}
+	}
+	queries.collect(c.queries, ch)
+	return nil
+}
@@ -0,0 +1,178 @@
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/internal/fakeapi"
+)
+
+// fakeZone returns the zone of the fake API on plan.
+func fakeZone(t testing.TB, plan string) cloudflare.Zone {
+	for _, zone := range fakeapi.Zones {
+		if zone.Plan.LegacyID == plan {
+			return zone
+		}
+	}
+	t.Fatalf("no fake zone on plan %s", plan)
+	return cloudflare.Zone{}
+}
+
+func TestDNSDimensions(t *testing.T) {
+	dnsLabels := []string{"query_name", "response_code", "origin", "tcp", "ip_version", "response_cached", "query_type"}
+
+	for _, test := range []struct {
+		name       string
+		plan       string
+		dimensions []string
+		// wantRequest are the dimensions requested from the plan, and
+		// wantProbe those probed for, if any.
+		wantRequest []string
+		wantProbe   []string
+		// wantNamespace is the namespace of the collected metrics, and
+		// wantValues the value of each label of dnsLabels after the first
+		// collection.
+		wantNamespace string
+		wantValues    map[string]string
+	}{
+		{
+			name:          "free",
+			plan:          "free",
+			wantRequest:   []string{"queryName", "responseCode", "origin", "tcp", "ipVersion"},
+			wantProbe:     []string{"queryName", "responseCode", "origin", "tcp", "ipVersion", "responseCached", "queryType"},
+			wantNamespace: "cloudflare",
+			// The fake API rejects the probed dimensions for free zones.
+			wantValues: map[string]string{"query_name": "www.example.com", "response_code": "NOERROR", "origin": "false", "tcp": "false", "ip_version": "4", "response_cached": "", "query_type": ""},
+		},
+		{
+			name:          "pro",
+			plan:          "pro",
+			wantRequest:   []string{"queryName", "responseCode", "origin", "tcp", "ipVersion", "coloName"},
+			wantProbe:     []string{"queryName", "responseCode", "origin", "tcp", "ipVersion", "responseCached", "queryType", "coloName"},
+			wantNamespace: "cloudflare_pop",
+			// The fake API allows the probed dimensions for pro zones.
+			wantValues: map[string]string{"query_name": "www.example.com", "response_code": "NOERROR", "origin": "false", "tcp": "false", "ip_version": "4", "response_cached": "Cached", "query_type": "A"},
+		},
+		{
+			name:          "business",
+			plan:          "business",
+			wantRequest:   []string{"queryName", "responseCode", "origin", "tcp", "ipVersion", "responseCached", "queryType", "coloName"},
+			wantNamespace: "cloudflare_pop",
+			wantValues:    map[string]string{"query_name": "www.example.com", "response_code": "NOERROR", "origin": "false", "tcp": "false", "ip_version": "4", "response_cached": "Cached", "query_type": "A"},
+		},
+		{
+			name:          "enterprise",
+			plan:          "enterprise",
+			wantRequest:   []string{"queryName", "responseCode", "origin", "tcp", "ipVersion", "responseCached", "queryType", "coloName"},
+			wantNamespace: "cloudflare_pop",
+			wantValues:    map[string]string{"query_name": "www.example.com", "response_code": "NOERROR", "origin": "false", "tcp": "false", "ip_version": "4", "response_cached": "Cached", "query_type": "A"},
+		},
+		{
+			name:          "enterprise without colos",
+			plan:          "enterprise",
+			dimensions:    []string{"queryType", "responseCode"},
+			wantRequest:   []string{"responseCode", "queryType"},
+			wantNamespace: "cloudflare",
+			wantValues:    map[string]string{"query_name": "", "response_code": "NOERROR", "origin": "", "tcp": "", "ip_version": "", "response_cached": "", "query_type": "A"},
+		},
+		{
+			name:          "business with a free dimension",
+			plan:          "business",
+			dimensions:    []string{"queryName", "coloName"},
+			wantRequest:   []string{"queryName", "coloName"},
+			wantNamespace: "cloudflare_pop",
+			wantValues:    map[string]string{"query_name": "www.example.com", "response_code": "", "origin": "", "tcp": "", "ip_version": "", "response_cached": "", "query_type": ""},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			defer env.Close()
+			env.opts.DNS.Dimensions = test.dimensions
+			zone := fakeZone(t, test.plan)
+			c := newDNSCollector(env.api, zone, env.opts).(*dnsCollector)
+
+			if !reflect.DeepEqual(c.request.dimensions, test.wantRequest) {
+				t.Errorf("got requested dimensions %v, want %v", c.request.dimensions, test.wantRequest)
+			}
+			var gotProbe []string
+			if c.probe != nil {
+				gotProbe = c.probe.dimensions
+			}
+			if !reflect.DeepEqual(gotProbe, test.wantProbe) {
+				t.Errorf("got probed dimensions %v, want %v", gotProbe, test.wantProbe)
+			}
+
+			reg := prometheus.NewPedanticRegistry()
+			f := zoneCollectFunc(c, zone)
+			reg.MustRegister(f)
+			families, err := reg.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.err != nil {
+				t.Fatal(f.err)
+			}
+			if len(families) == 0 {
+				t.Fatal("no metrics collected")
+			}
+			for _, family := range families {
+				if !strings.HasPrefix(family.GetName(), test.wantNamespace+"_dns_record_") {
+					t.Errorf("got metric %s, want namespace %s", family.GetName(), test.wantNamespace)
+				}
+				for _, m := range family.GetMetric() {
+					got := map[string]string{}
+					for _, pair := range m.GetLabel() {
+						if contains(dnsLabels, pair.GetName()) {
+							got[pair.GetName()] = pair.GetValue()
+						}
+					}
+					if !reflect.DeepEqual(got, test.wantValues) {
+						t.Errorf("got labels %v on %s, want %v", got, family.GetName(), test.wantValues)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDNSSeriesTTL(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+	env.opts.DNS.SeriesTTL = time.Hour
+	zone := fakeZone(t, "enterprise")
+	c := newDNSCollector(env.api, zone, env.opts).(*dnsCollector)
+
+	// State of a series from the state file, last counted a day ago.
+	stale := zoneSeriesKey(c.names[0], zone.ID, []string{"gone.example.com"})
+	env.opts.State.Set(stale, SeriesState{Value: 1, LastBucket: time.Now().Add(-24 * time.Hour)})
+
+	reg := prometheus.NewPedanticRegistry()
+	f := zoneCollectFunc(c, zone)
+	reg.MustRegister(f)
+	if _, err := reg.Gather(); err != nil {
+		t.Fatal(err)
+	}
+	if f.err != nil {
+		t.Fatal(f.err)
+	}
+	if len(c.series) == 0 {
+		t.Fatal("no series collected")
+	}
+	if _, ok := env.opts.State.Get(stale); ok {
+		t.Error("state of a series not seen within the TTL was kept")
+	}
+
+	// Age every series past the TTL.
+	for key, series := range c.series {
+		series.seen = time.Now().Add(-2 * time.Hour)
+		c.series[key] = series
+	}
+	(&dnsExport{c: c, zone: zone}).finish()
+	if len(c.series) != 0 {
+		t.Errorf("got %d series after the TTL, want none", len(c.series))
+	}
+}
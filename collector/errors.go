@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Classes of API errors, see ClassifyError.
+const (
+	ErrorClassAuth        = "auth"
+	ErrorClassRateLimit   = "rate_limit"
+	ErrorClassNotEntitled = "not_entitled"
+	ErrorClassTimeout     = "timeout"
+	ErrorClassServer      = "server_error"
+	ErrorClassOther       = "other"
+)
+
+// httpStatusRe matches the status code cloudflare-go and GraphQLClient put
+// into errors.
+var httpStatusRe = regexp.MustCompile(`HTTP status (\d{3})`)
+
+// notEntitledMessages are fragments of API error messages returned for
+// products or analytics that are not available on a zone's plan.
+var notEntitledMessages = []string{
+	"not available",
+	"not entitled",
+	"does not allow",
+	"does not have access",
+	"upgrade your plan",
+	"not authorized to access this",
+}
+
+// ClassifyError sorts a Cloudflare API error into one of the ErrorClass
+// values. Errors only carry text, so this goes by their message.
+func ClassifyError(err error) string {
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range notEntitledMessages {
+		if strings.Contains(msg, fragment) {
+			return ErrorClassNotEntitled
+		}
+	}
+	if strings.Contains(msg, "timeout") || strings.Contains(msg, "deadline exceeded") {
+		return ErrorClassTimeout
+	}
+	if m := httpStatusRe.FindStringSubmatch(msg); m != nil {
+		status, _ := strconv.Atoi(m[1])
+		switch {
+		case status == 401:
+			return ErrorClassAuth
+		case status == 403:
+			// Products missing from the plan mostly answer with 403 too.
+			return ErrorClassNotEntitled
+		case status == 429:
+			return ErrorClassRateLimit
+		case status >= 500:
+			return ErrorClassServer
+		}
+	}
+	if strings.Contains(msg, "rate limit") {
+		return ErrorClassRateLimit
+	}
+	return ErrorClassOther
+}
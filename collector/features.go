@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("plan_features", newPlanFeaturesCollector)
+}
+
+// Zone features, in the order they are exported.
+const (
+	FeatureArgo          = "argo"
+	FeatureLoadBalancing = "load_balancing"
+	FeatureSpectrum      = "spectrum"
+	FeatureAdvancedDDoS  = "advanced_ddos"
+	FeatureWorkers       = "workers"
+	FeatureProxied       = "proxied"
+)
+
+// FeatureNames lists every feature probed by ProbeFeatures.
+var FeatureNames = []string{FeatureArgo, FeatureLoadBalancing, FeatureSpectrum, FeatureAdvancedDDoS, FeatureWorkers, FeatureProxied}
+
+// featureRefresh is how often plan_features probes a zone's features again.
+const featureRefresh = time.Hour
+
+// Features are the products a zone is entitled to and uses.
+type Features map[string]bool
+
+// featureProbes report whether a zone has a feature from the result of an
+// endpoint. Endpoints of products the zone is not entitled to fail, which
+// counts as not having the feature.
+var featureProbes = map[string]struct {
+	endpoint string
+	has      func(result json.RawMessage) bool
+}{
+	FeatureArgo:          {"/argo/smart_routing", settingOn},
+	FeatureLoadBalancing: {"/load_balancers", nonEmptyList},
+	FeatureSpectrum:      {"/spectrum/apps", nonEmptyList},
+	FeatureAdvancedDDoS:  {"/settings/advanced_ddos", settingOn},
+	FeatureWorkers:       {"/workers/routes", nonEmptyList},
+	// DNS-only zones have no proxied records, so no HTTP traffic to
+	// analyze.
+	FeatureProxied: {"/dns_records?proxied=true&per_page=1", nonEmptyList},
+}
+
+func settingOn(result json.RawMessage) bool {
+	var setting struct {
+		Value string `json:"value"`
+	}
+	return json.Unmarshal(result, &setting) == nil && setting.Value == "on"
+}
+
+func nonEmptyList(result json.RawMessage) bool {
+	var list []json.RawMessage
+	return json.Unmarshal(result, &list) == nil && len(list) > 0
+}
+
+// ProbeFeatures finds out which features zone has, by calling an endpoint of
+// each product.
+func ProbeFeatures(api API, zone cloudflare.Zone) Features {
+	features := Features{}
+	for _, name := range FeatureNames {
+		probe := featureProbes[name]
+		result, err := api.Raw(http.MethodGet, "/zones/"+zone.ID+probe.endpoint, nil)
+		features[name] = err == nil && probe.has(result)
+	}
+	return features
+}
+
+// planFeaturesCollector exports the zone's plan and features as an info
+// metric, so dashboards can show panels for the products a zone has.
+type planFeaturesCollector struct {
+	cf    API
+	descs []*prometheus.Desc
+
+	planFeatures *prometheus.Desc
+
+	mu       sync.Mutex
+	features Features
+	probed   time.Time
+}
+
+func newPlanFeaturesCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &planFeaturesCollector{cf: api}
+	c.descs = descTable{
+		{&c.planFeatures, metricDef{"zone", "plan_features", "The zone's plan and whether it has each feature, with a constant '1' value", WithLabels([]string{"plan"}, FeatureNames...)}},
+	}.build(set)
+	return c
+}
+
+func (c *planFeaturesCollector) Name() string { return "plan_features" }
+
+func (c *planFeaturesCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *planFeaturesCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	c.mu.Lock()
+	if c.features == nil || time.Since(c.probed) > featureRefresh {
+		c.features = ProbeFeatures(c.cf, zone)
+		c.probed = time.Now()
+	}
+	features := c.features
+	c.mu.Unlock()
+
+	labels := []string{zone.Plan.LegacyID}
+	for _, name := range FeatureNames {
+		labels = append(labels, boolLabel(features[name]))
+	}
+	ch <- prometheus.MustNewConstMetric(c.planFeatures, prometheus.GaugeValue, 1, labels...)
+	return nil
+}
+
+func boolLabel(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
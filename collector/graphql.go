@@ -0,0 +1,167 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Adaptive (sampled) GraphQL datasets lag behind real time, so queries cover
+// graphQLWindow ending graphQLDelay ago.
+const (
+	graphQLWindow = 5 * time.Minute
+	graphQLDelay  = time.Minute
+)
+
+// GraphQLClient queries the Cloudflare GraphQL Analytics API, which
+// cloudflare-go has no support for.
+type GraphQLClient struct {
+	client  *http.Client
+	url     string
+	headers http.Header
+}
+
+// NewGraphQLClient returns a client posting queries to url, authenticated
+// with the given API key and email. headers are added to every request.
+func NewGraphQLClient(client *http.Client, url, key, email string, headers http.Header) *GraphQLClient {
+	h := http.Header{}
+	for k, v := range headers {
+		h[k] = v
+	}
+	h.Set("X-Auth-Key", key)
+	h.Set("X-Auth-Email", email)
+	h.Set("Content-Type", "application/json")
+	return &GraphQLClient{client: client, url: url, headers: h}
+}
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Query runs query with variables and decodes the data field of the
+// response into result.
+func (c *GraphQLClient) Query(ctx context.Context, query string, variables map[string]interface{}, result interface{}) error {
+	body, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range c.headers {
+		req.Header[k] = v
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read response body: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP status %d: content %q", resp.StatusCode, respBody)
+	}
+
+	var r graphQLResponse
+	if err := json.Unmarshal(respBody, &r); err != nil {
+		return fmt.Errorf("could not parse response: %s", err)
+	}
+	if len(r.Errors) > 0 {
+		messages := make([]string, 0, len(r.Errors))
+		for _, e := range r.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("query failed: %s", strings.Join(messages, "; "))
+	}
+	return json.Unmarshal(r.Data, result)
+}
+
+// graphQLGroup is one row of an aggregated GraphQL dataset.
+type graphQLGroup struct {
+	Count      float64                `json:"count"`
+	Sum        map[string]float64     `json:"sum"`
+	Avg        map[string]float64     `json:"avg"`
+	Max        map[string]float64     `json:"max"`
+	Dimensions map[string]interface{} `json:"dimensions"`
+}
+
+// dimension returns the named dimension formatted as a label value. List
+// values are joined by commas.
+func (g graphQLGroup) dimension(name string) string {
+	v, ok := g.Dimensions[name]
+	if !ok || v == nil {
+		return ""
+	}
+	if list, ok := v.([]interface{}); ok {
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			values = append(values, fmt.Sprint(item))
+		}
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+// zoneGroups runs a query selecting a single zone's groups, aliased as
+// "groups", over the current query window.
+func (c *GraphQLClient) zoneGroups(ctx context.Context, zoneID, query string) ([]graphQLGroup, error) {
+	return c.groups(ctx, "zoneTag", zoneID, query)
+}
+
+// accountGroups runs a query selecting a single account's groups, aliased
+// as "groups", over the current query window.
+func (c *GraphQLClient) accountGroups(ctx context.Context, accountID, query string) ([]graphQLGroup, error) {
+	return c.groups(ctx, "accountTag", accountID, query)
+}
+
+// zonesGroups runs a query selecting the groups, aliased as "groups", of
+// the zones whose tags are passed as $zoneTags, over the current query
+// window. The groups of all zones are returned together.
+func (c *GraphQLClient) zonesGroups(ctx context.Context, zoneIDs []string, query string) ([]graphQLGroup, error) {
+	return c.groups(ctx, "zoneTags", zoneIDs, query)
+}
+
+func (c *GraphQLClient) groups(ctx context.Context, tagName string, tag interface{}, query string) ([]graphQLGroup, error) {
+	until := time.Now().UTC().Add(-graphQLDelay).Truncate(time.Minute)
+	variables := map[string]interface{}{
+		tagName: tag,
+		"since": until.Add(-graphQLWindow).Format(time.RFC3339),
+		"until": until.Format(time.RFC3339),
+	}
+
+	// viewer holds a single "zones" or "accounts" list, depending on the
+	// query, with one entry per zone or account selected.
+	var data struct {
+		Viewer map[string][]struct {
+			Groups []graphQLGroup `json:"groups"`
+		} `json:"viewer"`
+	}
+	if err := c.Query(ctx, query, variables, &data); err != nil {
+		return nil, err
+	}
+	var groups []graphQLGroup
+	for _, scopes := range data.Viewer {
+		for _, scope := range scopes {
+			groups = append(groups, scope.Groups...)
+		}
+	}
+	return groups, nil
+}
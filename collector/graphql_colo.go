@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("graphql_colo", newGraphQLColoCollector)
+	RequireFeature("graphql_colo", FeatureProxied)
+}
+
+const graphQLColoQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: httpRequestsAdaptiveGroups(limit: 10000, filter: {datetime_geq: $since, datetime_lt: $until}) {
+        count
+        sum {
+          edgeResponseBytes
+        }
+        dimensions {
+          coloCode
+        }
+      }
+    }
+  }
+}`
+
+// graphQLColoCollector collects requests broken out by PoP from the sampled
+// GraphQL datasets. Unlike ZoneAnalyticsByColocation these are not limited
+// to Enterprise plans, but the numbers are approximate.
+type graphQLColoCollector struct {
+	gql        *GraphQLClient
+	enabled    bool
+	coloFilter *coloFilter
+	descs      []*prometheus.Desc
+
+	requests  *prometheus.Desc
+	bandwidth *prometheus.Desc
+}
+
+func newGraphQLColoCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   fmt.Sprintf("%s_pop", Namespace),
+		labels:      PopLabels,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &graphQLColoCollector{gql: opts.GraphQL, enabled: opts.GraphQLColos}
+	if c.enabled {
+		c.coloFilter = newColoFilter(api, zone, opts)
+	}
+	c.descs = descTable{
+		{&c.requests, metricDef{"sampled", "requests", "Approximate number of requests served in the last 5 minutes, from sampled data", nil}},
+		{&c.bandwidth, metricDef{"sampled", "bandwidth_bytes", "Approximate number of bytes served in the last 5 minutes, from sampled data", nil}},
+	}.build(set)
+	return c
+}
+
+func (c *graphQLColoCollector) Name() string { return "graphql_colo" }
+
+func (c *graphQLColoCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *graphQLColoCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if !c.enabled || c.gql == nil {
+		return nil
+	}
+	groups, err := c.gql.zoneGroups(ctx, zone.ID, graphQLColoQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get sampled colo analytics from cloudflare: %s", err)
+	}
+
+	// Several colo codes can resolve to the same PoP, so sum them up.
+	requests := newLabelSum()
+	bandwidth := newLabelSum()
+	colos := c.coloFilter.current()
+	for _, g := range groups {
+		if !colos.allows(g.dimension("coloCode")) {
+			continue
+		}
+		labels := popLabelValues(g.dimension("coloCode"))
+		requests.add(g.Count, labels...)
+		bandwidth.add(g.Sum["edgeResponseBytes"], labels...)
+	}
+	requests.collect(c.requests, ch)
+	bandwidth.collect(c.bandwidth, ch)
+	return nil
+}
@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("image_resizing", newImageResizingCollector)
+	RequireFeature("image_resizing", FeatureProxied)
+}
+
+// imageResizingQuery selects the requests for images transformed through
+// URLs under /cdn-cgi/image/. Transformations requested by Workers are
+// subrequests of the Worker and not included.
+const imageResizingQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: httpRequestsAdaptiveGroups(limit: 10000, filter: {datetime_geq: $since, datetime_lt: $until, clientRequestPath_like: "/cdn-cgi/image/%"}) {
+        count
+        dimensions {
+          cacheStatus
+          edgeResponseStatus
+        }
+      }
+    }
+  }
+}`
+
+// imageResizingCollector collects Image Resizing requests by cache status,
+// and those that failed by status code, from the sampled GraphQL datasets.
+type imageResizingCollector struct {
+	gql     *GraphQLClient
+	enabled bool
+	descs   []*prometheus.Desc
+
+	requests *prometheus.Desc
+	errors   *prometheus.Desc
+}
+
+func newImageResizingCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &imageResizingCollector{gql: opts.GraphQL, enabled: opts.GraphQLImageResizing}
+	c.descs = descTable{
+		{&c.requests, metricDef{"image_resizing", "sampled_requests", "Approximate number of Image Resizing requests in the last 5 minutes, by cache status, from sampled data", []string{"cache_status"}}},
+		{&c.errors, metricDef{"image_resizing", "sampled_errors", "Approximate number of Image Resizing requests answered with a 4xx or 5xx status code in the last 5 minutes, from sampled data", []string{"status_code"}}},
+	}.build(set)
+	return c
+}
+
+func (c *imageResizingCollector) Name() string { return "image_resizing" }
+
+func (c *imageResizingCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *imageResizingCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if !c.enabled || c.gql == nil {
+		return nil
+	}
+	groups, err := c.gql.zoneGroups(ctx, zone.ID, imageResizingQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get image resizing analytics from cloudflare: %s", err)
+	}
+
+	// Groups are split by both cache status and status code, so sum them
+	// up for each metric.
+	requests := newLabelSum()
+	failed := newLabelSum()
+	for _, g := range groups {
+		requests.add(g.Count, g.dimension("cacheStatus"))
+		if status := g.dimension("edgeResponseStatus"); status >= "400" {
+			failed.add(g.Count, status)
+		}
+	}
+	requests.collect(c.requests, ch)
+	failed.collect(c.errors, ch)
+	return nil
+}
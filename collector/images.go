@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterAccount("images", newImagesCollector)
+}
+
+const imagesDeliveredQuery = `query ($accountTag: string, $since: Time, $until: Time) {
+  viewer {
+    accounts(filter: {accountTag: $accountTag}) {
+      groups: imagesRequestsAdaptiveGroups(limit: 1, filter: {datetime_geq: $since, datetime_lt: $until}) {
+        count
+      }
+    }
+  }
+}`
+
+// imagesStats is the result of the Cloudflare Images usage endpoint.
+type imagesStats struct {
+	Count struct {
+		Current float64 `json:"current"`
+		Allowed float64 `json:"allowed"`
+	} `json:"count"`
+}
+
+// imagesCollector collects how many images an account stores in Cloudflare
+// Images, out of how many its plan allows, and how many it delivered. It
+// only runs with Options.Images, as accounts without Images fail.
+type imagesCollector struct {
+	cf      API
+	gql     *GraphQLClient
+	enabled bool
+	descs   []*prometheus.Desc
+
+	stored    *prometheus.Desc
+	allowed   *prometheus.Desc
+	delivered *prometheus.Desc
+}
+
+func newImagesCollector(api API, account Account, opts Options) AccountCollector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: AccountLabels(account),
+	}
+	c := &imagesCollector{cf: api, gql: opts.GraphQL, enabled: opts.Images}
+	c.descs = descTable{
+		{&c.stored, metricDef{"images", "stored", "Number of images stored in Cloudflare Images", nil}},
+		{&c.allowed, metricDef{"images", "allowed", "Number of images the account's plan allows storing in Cloudflare Images", nil}},
+		{&c.delivered, metricDef{"images", "sampled_delivered", "Approximate number of images delivered by Cloudflare Images in the last 5 minutes, from sampled data", nil}},
+	}.build(set)
+	return c
+}
+
+func (c *imagesCollector) Name() string { return "images" }
+
+func (c *imagesCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *imagesCollector) Collect(ctx context.Context, account Account, ch chan<- prometheus.Metric) error {
+	if !c.enabled {
+		return nil
+	}
+	raw, err := c.cf.Raw(http.MethodGet, fmt.Sprintf("/accounts/%s/images/v1/stats", account.ID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get images stats from cloudflare: %s", err)
+	}
+	var stats imagesStats
+	if err := json.Unmarshal(raw, &stats); err != nil {
+		return fmt.Errorf("failed to parse images stats: %s", err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.stored, prometheus.GaugeValue, stats.Count.Current)
+	ch <- prometheus.MustNewConstMetric(c.allowed, prometheus.GaugeValue, stats.Count.Allowed)
+
+	if c.gql == nil {
+		return nil
+	}
+	groups, err := c.gql.accountGroups(ctx, account.ID, imagesDeliveredQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get images analytics from cloudflare: %s", err)
+	}
+	delivered := 0.0
+	for _, g := range groups {
+		delivered += g.Count
+	}
+	ch <- prometheus.MustNewConstMetric(c.delivered, prometheus.GaugeValue, delivered)
+	return nil
+}
@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WithLabels returns labels followed by extra in a freshly allocated slice.
+// Appending directly to a shared label slice can alias its backing array and
+// corrupt label values across series, so all label construction goes through
+// here.
+func WithLabels(labels []string, extra ...string) []string {
+	l := make([]string, 0, len(labels)+len(extra))
+	l = append(l, labels...)
+	return append(l, extra...)
+}
+
+// labelBuffer is labels followed by one variable label value, reused for
+// every series of a metric broken out by that label. MustNewConstMetric
+// copies label values, so the buffer can be overwritten as soon as the
+// metric is created, but it must never be retained.
+type labelBuffer []string
+
+func newLabelBuffer(labels []string) labelBuffer {
+	return WithLabels(labels, "")
+}
+
+// with sets the variable label value and returns the label values.
+func (b labelBuffer) with(value string) []string {
+	b[len(b)-1] = value
+	return b
+}
+
+// labelSum sums values by label values, for API responses that are broken
+// out by more dimensions than are exported.
+type labelSum struct {
+	sums  map[string]float64
+	order [][]string
+}
+
+func newLabelSum() *labelSum {
+	return &labelSum{sums: map[string]float64{}}
+}
+
+func (s *labelSum) add(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	if _, ok := s.sums[key]; !ok {
+		s.order = append(s.order, WithLabels(labelValues))
+	}
+	s.sums[key] += value
+}
+
+// collect sends a gauge per distinct label values, in order of first
+// appearance.
+func (s *labelSum) collect(desc *prometheus.Desc, ch chan<- prometheus.Metric) {
+	for _, labelValues := range s.order {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, s.sums[strings.Join(labelValues, "\xff")], labelValues...)
+	}
+}
@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterAccount("load_balancer_health", newLBHealthCollector)
+}
+
+// lbPool is a load balancer origin pool.
+type lbPool struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Monitor string `json:"monitor"`
+}
+
+// lbOriginHealth is the result of the last health check of an origin from
+// a region.
+type lbOriginHealth struct {
+	Healthy      bool   `json:"healthy"`
+	RTT          string `json:"rtt"`
+	ResponseCode int    `json:"response_code"`
+}
+
+// lbPoolHealth is the result of the pool health endpoint. Each region lists
+// its origins as single-entry objects keyed by address.
+type lbPoolHealth struct {
+	PopHealth map[string]struct {
+		Origins []map[string]lbOriginHealth `json:"origins"`
+	} `json:"pop_health"`
+}
+
+// lbHealthCollector collects the results of the load balancer monitors, as
+// health checks from each region against each origin, so origin
+// reachability can be seen from Cloudflare's side. It only runs with
+// Options.LoadBalancerHealth, as it takes a request per pool.
+type lbHealthCollector struct {
+	cf      API
+	enabled bool
+	descs   []*prometheus.Desc
+
+	healthy       *prometheus.Desc
+	rtt           *prometheus.Desc
+	responseCodes *prometheus.Desc
+}
+
+func newLBHealthCollector(api API, account Account, opts Options) AccountCollector {
+	set := descSet{
+		namespace:   Namespace,
+		labels:      []string{"pool_id", "pool_name", "monitor_id", "region"},
+		constLabels: AccountLabels(account),
+	}
+	c := &lbHealthCollector{cf: api, enabled: opts.LoadBalancerHealth}
+	c.descs = descTable{
+		{&c.healthy, metricDef{"load_balancer", "origin_healthy", "Whether the last health check of an origin from a region succeeded", []string{"origin"}}},
+		{&c.rtt, metricDef{"load_balancer", "origin_rtt_seconds", "Round trip time of the last health check of an origin from a region", []string{"origin"}}},
+		{&c.responseCodes, metricDef{"load_balancer", "monitor_responses", "Number of origins whose last health check from a region got each HTTP status code, 0 if there was no response", []string{"response_code"}}},
+	}.build(set)
+	return c
+}
+
+func (c *lbHealthCollector) Name() string { return "load_balancer_health" }
+
+func (c *lbHealthCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *lbHealthCollector) Collect(ctx context.Context, account Account, ch chan<- prometheus.Metric) error {
+	if !c.enabled {
+		return nil
+	}
+	raw, err := c.cf.Raw(http.MethodGet, fmt.Sprintf("/accounts/%s/load_balancers/pools", account.ID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get load balancer pools from cloudflare: %s", err)
+	}
+	var pools []lbPool
+	if err := json.Unmarshal(raw, &pools); err != nil {
+		return fmt.Errorf("failed to parse load balancer pools: %s", err)
+	}
+
+	for _, pool := range pools {
+		raw, err := c.cf.Raw(http.MethodGet, fmt.Sprintf("/accounts/%s/load_balancers/pools/%s/health", account.ID, pool.ID), nil)
+		if err != nil {
+			return fmt.Errorf("failed to get health of load balancer pool %s from cloudflare: %s", pool.Name, err)
+		}
+		var health lbPoolHealth
+		if err := json.Unmarshal(raw, &health); err != nil {
+			return fmt.Errorf("failed to parse health of load balancer pool %s: %s", pool.Name, err)
+		}
+		for region, pop := range health.PopHealth {
+			labels := []string{pool.ID, pool.Name, pool.Monitor, region}
+			codes := newLabelSum()
+			for _, origins := range pop.Origins {
+				for origin, result := range origins {
+					healthy := 0.0
+					if result.Healthy {
+						healthy = 1
+					}
+					ch <- prometheus.MustNewConstMetric(c.healthy, prometheus.GaugeValue, healthy, WithLabels(labels, origin)...)
+					if rtt, err := time.ParseDuration(result.RTT); err == nil {
+						ch <- prometheus.MustNewConstMetric(c.rtt, prometheus.GaugeValue, rtt.Seconds(), WithLabels(labels, origin)...)
+					}
+					codes.add(1, WithLabels(labels, strconv.Itoa(result.ResponseCode))...)
+				}
+			}
+			codes.collect(c.responseCodes, ch)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("legacy_firewall", newLegacyFirewallCollector)
+	RequireFeature("legacy_firewall", FeatureProxied)
+}
+
+const legacyFirewallQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: firewallEventsAdaptiveGroups(limit: 10000, filter: {source_in: ["zonelockdown", "uablock"], datetime_geq: $since, datetime_lt: $until}) {
+        count
+        dimensions {
+          source
+          action
+          ruleId
+        }
+      }
+    }
+  }
+}`
+
+// legacyRulesPerPage is the page size for listing Zone Lockdown and
+// User-Agent Blocking rules.
+const legacyRulesPerPage = 100
+
+// legacyFirewallCollector collects requests matched by the Zone Lockdown
+// and User-Agent Blocking rules of the zone, which predate the Rulesets
+// API but are still relied on. Rule IDs are resolved to rule descriptions
+// using the APIs of these features.
+type legacyFirewallCollector struct {
+	gql   *GraphQLClient
+	rules *ruleDescriptions
+	descs []*prometheus.Desc
+
+	lockdownRequests *prometheus.Desc
+	uaBlockRequests  *prometheus.Desc
+}
+
+func newLegacyFirewallCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &legacyFirewallCollector{
+		gql:   opts.GraphQL,
+		rules: newRuleDescriptions(api, zone.ID, legacyRuleDescriptions),
+	}
+	labels := []string{"action", "rule_id", "rule_description"}
+	c.descs = descTable{
+		{&c.lockdownRequests, metricDef{"zone_lockdown", "requests", "Number of requests matched by Zone Lockdown rules in the last 5 minutes", labels}},
+		{&c.uaBlockRequests, metricDef{"user_agent_block", "requests", "Number of requests matched by User-Agent Blocking rules in the last 5 minutes", labels}},
+	}.build(set)
+	return c
+}
+
+func (c *legacyFirewallCollector) Name() string { return "legacy_firewall" }
+
+func (c *legacyFirewallCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *legacyFirewallCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if c.gql == nil {
+		return nil
+	}
+	c.rules.prefetch()
+	groups, err := c.gql.zoneGroups(ctx, zone.ID, legacyFirewallQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get zone lockdown and user agent blocking events from cloudflare: %s", err)
+	}
+	for _, g := range groups {
+		desc := c.lockdownRequests
+		if g.dimension("source") == "uablock" {
+			desc = c.uaBlockRequests
+		}
+		ruleID := g.dimension("ruleId")
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, g.Count, g.dimension("action"), ruleID, c.rules.describe(ctx, ruleID))
+	}
+	return nil
+}
+
+// legacyRuleDescriptions returns the descriptions of the Zone Lockdown and
+// User-Agent Blocking rules of the zone.
+func legacyRuleDescriptions(api API, zoneID string) (map[string]string, error) {
+	descriptions := map[string]string{}
+	for _, endpoint := range []string{"lockdowns", "ua_rules"} {
+		for page := 1; ; page++ {
+			raw, err := api.Raw(http.MethodGet, fmt.Sprintf("/zones/%s/firewall/%s?page=%d&per_page=%d", zoneID, endpoint, page, legacyRulesPerPage), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s: %s", endpoint, err)
+			}
+			var rules []struct {
+				ID          string `json:"id"`
+				Description string `json:"description"`
+			}
+			if err := json.Unmarshal(raw, &rules); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %s", endpoint, err)
+			}
+			for _, rule := range rules {
+				descriptions[rule.ID] = rule.Description
+			}
+			if len(rules) < legacyRulesPerPage {
+				break
+			}
+		}
+	}
+	return descriptions, nil
+}
@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("managed_rulesets", newManagedRulesetsCollector)
+}
+
+// managedRulesetRefresh is how often managed_rulesets fetches the zone's
+// rulesets again.
+const managedRulesetRefresh = 10 * time.Minute
+
+// alwaysDeployedPhases are the phases whose managed rulesets run without
+// being deployed by the zone.
+var alwaysDeployedPhases = []string{"ddos_l4", "ddos_l7"}
+
+// managedRulesetsCollector exports the versions of the Cloudflare managed
+// rulesets available to a zone, so behavior changes can be correlated with
+// ruleset updates.
+type managedRulesetsCollector struct {
+	cf    API
+	descs []*prometheus.Desc
+
+	info        *prometheus.Desc
+	lastUpdated *prometheus.Desc
+
+	mu       sync.Mutex
+	rulesets []ruleset
+	deployed map[string]bool
+	fetched  time.Time
+}
+
+func newManagedRulesetsCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &managedRulesetsCollector{cf: api}
+	c.descs = descTable{
+		{&c.info, metricDef{"managed_ruleset", "info", "Version of a managed ruleset and whether the zone deploys it, with a constant '1' value", []string{"ruleset_id", "ruleset_name", "phase", "version", "deployed"}}},
+		{&c.lastUpdated, metricDef{"managed_ruleset", "last_updated_timestamp_seconds", "When Cloudflare last updated a managed ruleset", []string{"ruleset_id", "ruleset_name"}}},
+	}.build(set)
+	return c
+}
+
+func (c *managedRulesetsCollector) Name() string { return "managed_rulesets" }
+
+func (c *managedRulesetsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *managedRulesetsCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	c.mu.Lock()
+	if c.rulesets == nil || time.Since(c.fetched) > managedRulesetRefresh {
+		rulesets, deployed, err := c.fetch(zone.ID)
+		if err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		c.rulesets, c.deployed, c.fetched = rulesets, deployed, time.Now()
+	}
+	rulesets, deployed := c.rulesets, c.deployed
+	c.mu.Unlock()
+
+	for _, rs := range rulesets {
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, rs.ID, rs.Name, rs.Phase, rs.Version, boolLabel(deployed[rs.ID]))
+		if !rs.LastUpdated.IsZero() {
+			ch <- prometheus.MustNewConstMetric(c.lastUpdated, prometheus.GaugeValue, float64(rs.LastUpdated.Unix()), rs.ID, rs.Name)
+		}
+	}
+	return nil
+}
+
+// fetch returns the managed rulesets of the zone, and which of them are
+// deployed by an enabled "execute" rule of the zone's own rulesets.
+func (c *managedRulesetsCollector) fetch(zoneID string) ([]ruleset, map[string]bool, error) {
+	listed, err := listRulesets(c.cf, zoneID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get rulesets from cloudflare: %s", err)
+	}
+	managed := []ruleset{}
+	deployed := map[string]bool{}
+	for _, rs := range listed {
+		switch rs.Kind {
+		case "managed":
+			managed = append(managed, rs)
+			if contains(alwaysDeployedPhases, rs.Phase) {
+				deployed[rs.ID] = true
+			}
+		case "zone":
+			entrypoint, err := getRuleset(c.cf, zoneID, rs.ID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get ruleset %s from cloudflare: %s", rs.ID, err)
+			}
+			for _, rule := range entrypoint.Rules {
+				if rule.Action == "execute" && rule.Enabled {
+					deployed[rule.ActionParameters.ID] = true
+				}
+			}
+		}
+	}
+	return managed, deployed, nil
+}
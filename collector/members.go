@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	RegisterAccount("members", newMembersCollector)
+}
+
+// membersCollector collects the account's members by role, so that e.g. an
+// unexpected new administrator can be alerted on.
+type membersCollector struct {
+	cf         API
+	memberInfo bool
+	descs      []*prometheus.Desc
+
+	members            *prometheus.Desc
+	pendingInvitations *prometheus.Desc
+	memberInfoDesc     *prometheus.Desc
+}
+
+func newMembersCollector(api API, account Account, opts Options) AccountCollector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: AccountLabels(account),
+	}
+	c := &membersCollector{cf: api, memberInfo: opts.MemberInfo}
+	c.descs = descTable{
+		{&c.members, metricDef{"account", "members", "Number of account members by role, members with several roles are counted for each", []string{"role"}}},
+		{&c.pendingInvitations, metricDef{"account", "pending_invitations", "Number of invitations to the account that have not been accepted yet", nil}},
+		{&c.memberInfoDesc, metricDef{"account", "member_info", "Account members, with a constant '1' value", []string{"member_id", "email", "status", "roles"}}},
+	}.build(set)
+	return c
+}
+
+func (c *membersCollector) Name() string { return "members" }
+
+func (c *membersCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *membersCollector) Collect(ctx context.Context, account Account, ch chan<- prometheus.Metric) error {
+	members := []cloudflare.AccountMember{}
+	for page := 1; ; page++ {
+		result, info, err := c.cf.AccountMembers(account.ID, cloudflare.PaginationOptions{Page: page, PerPage: 50})
+		if err != nil {
+			return fmt.Errorf("failed to get account members from cloudflare: %s", err)
+		}
+		members = append(members, result...)
+		if page >= info.TotalPages {
+			break
+		}
+	}
+
+	byRole := map[string]int{}
+	pending := 0
+	for _, m := range members {
+		if m.Status == "pending" {
+			pending++
+		} else {
+			for _, role := range m.Roles {
+				byRole[role.Name]++
+			}
+		}
+		if c.memberInfo {
+			roles := make([]string, 0, len(m.Roles))
+			for _, role := range m.Roles {
+				roles = append(roles, role.Name)
+			}
+			sort.Strings(roles)
+			ch <- prometheus.MustNewConstMetric(c.memberInfoDesc, prometheus.GaugeValue, 1, m.ID, m.User.Email, m.Status, strings.Join(roles, ","))
+		}
+	}
+
+	for role, count := range byRole {
+		ch <- prometheus.MustNewConstMetric(c.members, prometheus.GaugeValue, float64(count), role)
+	}
+	ch <- prometheus.MustNewConstMetric(c.pendingInvitations, prometheus.GaugeValue, float64(pending))
+	return nil
+}
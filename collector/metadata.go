@@ -0,0 +1,152 @@
+package collector
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+// MetricMetadata describes a metric as defined by its collector.
+type MetricMetadata struct {
+	Name string `json:"name"`
+	Help string `json:"help"`
+	// Labels are the constant labels, sorted, followed by the variable
+	// labels.
+	Labels []string `json:"labels"`
+
+	// variable are the variable labels.
+	variable []string
+}
+
+// DefinedMetric is a metric a registered collector is defined to export.
+type DefinedMetric struct {
+	MetricMetadata
+	Collector string `json:"collector"`
+	// Scope is zone or account.
+	Scope string `json:"scope"`
+	// Plans are the plans of the zones the metric is exported for.
+	Plans []string `json:"plans,omitempty"`
+}
+
+var (
+	// definitionsMu serializes Definitions, which records metadata into
+	// recording while it builds collectors.
+	definitionsMu sync.Mutex
+
+	metadataMu sync.Mutex
+	recording  map[*prometheus.Desc]MetricMetadata
+)
+
+// recordMetadata remembers what d was built from while Definitions runs.
+// Descriptors built at other times are not kept, so rebuilding collectors
+// does not accumulate them.
+func recordMetadata(d *prometheus.Desc, fqName, help string, labels []string, constLabels prometheus.Labels) {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	if recording == nil {
+		return
+	}
+	names := make([]string, 0, len(constLabels))
+	for name := range constLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	recording[d] = MetricMetadata{Name: fqName, Help: help, Labels: append(names, labels...), variable: labels}
+}
+
+// Definitions returns the metrics of every registered zone and account
+// collector, sorted by name. Zone collectors are built for a zone on each
+// plan, which gives the plans of their metrics, and account collectors for
+// an account; building a collector calls no API, and nothing is collected.
+func Definitions(opts Options) []DefinedMetric {
+	definitionsMu.Lock()
+	defer definitionsMu.Unlock()
+	metadataMu.Lock()
+	recording = map[*prometheus.Desc]MetricMetadata{}
+	metadataMu.Unlock()
+	defer func() {
+		metadataMu.Lock()
+		recording = nil
+		metadataMu.Unlock()
+	}()
+
+	byName := map[string]*DefinedMetric{}
+	add := func(d *prometheus.Desc, name, scope, plan string) {
+		metadataMu.Lock()
+		m, ok := recording[d]
+		metadataMu.Unlock()
+		if !ok {
+			return
+		}
+		entry, ok := byName[m.Name]
+		if !ok {
+			entry = &DefinedMetric{MetricMetadata: m, Collector: name, Scope: scope}
+			byName[m.Name] = entry
+		}
+		if plan != "" && !contains(entry.Plans, plan) {
+			entry.Plans = append(entry.Plans, plan)
+		}
+	}
+
+	opts.State, _ = OpenStateStore("")
+	for _, plan := range allPlans {
+		zone := cloudflare.Zone{ID: "metadata", Name: "metadata"}
+		zone.Owner.Name = "metadata"
+		zone.Owner.Email = "metadata"
+		zone.Plan.LegacyID = plan
+		for _, name := range Names() {
+			c := factories[name](nil, zone, opts)
+			for _, d := range describe(c.Describe) {
+				add(d, name, "zone", plan)
+			}
+		}
+	}
+	for _, name := range AccountNames() {
+		c := accountFactories[name](nil, Account{ID: "metadata", Name: "metadata"}, opts)
+		for _, d := range describe(c.Describe) {
+			add(d, name, "account", "")
+		}
+	}
+
+	metrics := make([]DefinedMetric, 0, len(byName))
+	for _, m := range byName {
+		metrics = append(metrics, *m)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+	return metrics
+}
+
+// variableLabels returns the variable labels of the metrics of every
+// registered collector, built both without and with the options adding or
+// renaming labels.
+func variableLabels() map[string]bool {
+	labels := map[string]bool{}
+	for _, opts := range []Options{
+		{},
+		{PopNames: true, AggregationLabel: true, CountryGroups: ContinentGroups(), Dashboard: DashboardOptions{ContentClasses: true}},
+		{CountryGroups: &CountryGroups{Label: "country_group"}},
+	} {
+		for _, m := range Definitions(opts) {
+			for _, label := range m.variable {
+				labels[label] = true
+			}
+		}
+	}
+	return labels
+}
+
+// describe returns the descriptors sent by describeFunc.
+func describe(describeFunc func(ch chan<- *prometheus.Desc)) []*prometheus.Desc {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		describeFunc(ch)
+		close(ch)
+	}()
+	descs := []*prometheus.Desc{}
+	for d := range ch {
+		descs = append(descs, d)
+	}
+	return descs
+}
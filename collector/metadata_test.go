@@ -0,0 +1,25 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefinitions(t *testing.T) {
+	metrics := Definitions(Options{})
+	plans := map[string][]string{}
+	for _, m := range metrics {
+		plans[m.Name] = m.Plans
+	}
+	for name, want := range map[string][]string{
+		"cloudflare_requests_total":     {"free", "pro", "business"},
+		"cloudflare_pop_requests_total": {"enterprise"},
+	} {
+		if !reflect.DeepEqual(plans[name], want) {
+			t.Errorf("got plans %v for %s, want %v", plans[name], name, want)
+		}
+	}
+	if recording != nil {
+		t.Error("descriptors are still recorded after Definitions returned")
+	}
+}
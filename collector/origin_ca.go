@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("origin_ca", newOriginCACollector)
+}
+
+// originCACollector collects the Origin CA certificates issued for a zone.
+// Unlike edge certificates they are not renewed automatically, and origins
+// of zones using Full (strict) SSL go offline when they expire. Listing them
+// requires an Origin CA key.
+type originCACollector struct {
+	cf      API
+	enabled bool
+	descs   []*prometheus.Desc
+
+	expiry *prometheus.Desc
+}
+
+func newOriginCACollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &originCACollector{cf: api, enabled: opts.OriginCA}
+	c.descs = descTable{
+		{&c.expiry, metricDef{"origin_ca", "certificate_expiry_timestamp_seconds", "When an Origin CA certificate expires", []string{"certificate_id", "hostnames"}}},
+	}.build(set)
+	return c
+}
+
+func (c *originCACollector) Name() string { return "origin_ca" }
+
+func (c *originCACollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *originCACollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if !c.enabled {
+		return nil
+	}
+	certificates, err := c.cf.OriginCertificates(cloudflare.OriginCACertificateListOptions{ZoneID: zone.ID})
+	if err != nil {
+		return fmt.Errorf("failed to get origin ca certificates from cloudflare: %s", err)
+	}
+	for _, cert := range certificates {
+		hostnames := WithLabels(cert.Hostnames)
+		sort.Strings(hostnames)
+		ch <- prometheus.MustNewConstMetric(c.expiry, prometheus.GaugeValue, float64(cert.ExpiresOn.Unix()), cert.ID, strings.Join(hostnames, ","))
+	}
+	return nil
+}
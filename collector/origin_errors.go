@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("origin_errors", newOriginErrorsCollector)
+	RequireFeature("origin_errors", FeatureProxied)
+}
+
+// originErrorsQuery selects the requests Cloudflare answered with one of its
+// own 52x or 530 status codes, which tell why the origin failed.
+const originErrorsQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: httpRequestsAdaptiveGroups(limit: 100, filter: {datetime_geq: $since, datetime_lt: $until, edgeResponseStatus_geq: 520, edgeResponseStatus_leq: 530}) {
+        count
+        dimensions {
+          edgeResponseStatus
+        }
+      }
+    }
+  }
+}`
+
+// originErrorReasons are the reasons for the status codes Cloudflare
+// answers with when it cannot get a response from the origin.
+var originErrorReasons = map[string]string{
+	"520": "unknown_error",
+	"521": "connection_refused",
+	"522": "connect_timeout",
+	"523": "origin_unreachable",
+	"524": "response_timeout",
+	"525": "tls_handshake_failed",
+	"526": "invalid_certificate",
+	"527": "railgun_error",
+	"530": "origin_dns_error",
+}
+
+// originErrorsCollector collects requests that failed because Cloudflare
+// could not get a response from the origin, by reason, from the sampled
+// GraphQL datasets. That tells origin outages apart from Cloudflare ones.
+type originErrorsCollector struct {
+	gql     *GraphQLClient
+	enabled bool
+	descs   []*prometheus.Desc
+
+	errors *prometheus.Desc
+}
+
+func newOriginErrorsCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &originErrorsCollector{gql: opts.GraphQL, enabled: opts.GraphQLOriginErrors}
+	c.descs = descTable{
+		{&c.errors, metricDef{"origin", "sampled_errors", "Approximate number of requests in the last 5 minutes that failed because Cloudflare could not get a response from the origin, by reason, from sampled data", []string{"status_code", "reason"}}},
+	}.build(set)
+	return c
+}
+
+func (c *originErrorsCollector) Name() string { return "origin_errors" }
+
+func (c *originErrorsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *originErrorsCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if !c.enabled || c.gql == nil {
+		return nil
+	}
+	groups, err := c.gql.zoneGroups(ctx, zone.ID, originErrorsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get origin error analytics from cloudflare: %s", err)
+	}
+	for _, g := range groups {
+		status := g.dimension("edgeResponseStatus")
+		reason, ok := originErrorReasons[status]
+		if !ok {
+			// 528 and 529 are not origin errors.
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.errors, prometheus.GaugeValue, g.Count, status, reason)
+	}
+	return nil
+}
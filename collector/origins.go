@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("origins", newOriginsCollector)
+	RequireFeature("origins", FeatureProxied)
+}
+
+const originRecordsPerPage = 100
+
+// originsCollector collects the origins proxied DNS records resolve to: the
+// CNAME target, or the address of A and AAAA records. An info metric per
+// record tells when one silently starts pointing at another origin, and a
+// count per origin shows how many hostnames each serves. It is opt-in with
+// Options.Origins as it lists every proxied record of the zone.
+type originsCollector struct {
+	cf      API
+	enabled bool
+	descs   []*prometheus.Desc
+
+	info    *prometheus.Desc
+	records *prometheus.Desc
+}
+
+func newOriginsCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &originsCollector{cf: api, enabled: opts.Origins}
+	c.descs = descTable{
+		{&c.info, metricDef{"origin", "info", "Origin a proxied DNS record points at, with a constant '1' value", []string{"record_name", "record_type", "origin"}}},
+		{&c.records, metricDef{"origin", "records", "Number of proxied DNS records pointing at an origin", []string{"record_type", "origin"}}},
+	}.build(set)
+	return c
+}
+
+func (c *originsCollector) Name() string { return "origins" }
+
+func (c *originsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *originsCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if !c.enabled {
+		return nil
+	}
+	result, err := proxiedRecords(c.cf, zone.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get proxied dns records from cloudflare: %s", err)
+	}
+	records := newLabelSum()
+	for _, r := range result {
+		origin := strings.ToLower(strings.TrimSuffix(r.Content, "."))
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, r.Name, r.Type, origin)
+		records.add(1, r.Type, origin)
+	}
+	records.collect(c.records, ch)
+	return nil
+}
+
+// proxiedRecords returns the proxied A, AAAA and CNAME records of the zone,
+// the only types that can be proxied to an origin.
+func proxiedRecords(api API, zoneID string) ([]cloudflare.DNSRecord, error) {
+	records := []cloudflare.DNSRecord{}
+	for page := 1; ; page++ {
+		raw, err := api.Raw(http.MethodGet, fmt.Sprintf("/zones/%s/dns_records?proxied=true&page=%d&per_page=%d", zoneID, page, originRecordsPerPage), nil)
+		if err != nil {
+			return nil, err
+		}
+		var result []cloudflare.DNSRecord
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse proxied dns records: %s", err)
+		}
+		for _, r := range result {
+			if r.Type == "A" || r.Type == "AAAA" || r.Type == "CNAME" {
+				records = append(records, r)
+			}
+		}
+		if len(result) < originRecordsPerPage {
+			return records, nil
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pop is a Cloudflare Point of Presence (PoP), sometimes also called a colo.
+type Pop struct {
+	Name   string `json:"name"`
+	Code   string `json:"code"`
+	Region string `json:"region"`
+	Source string `json:"source"`
+	// Updated is when the status page last listed the PoP, zero if it has
+	// not since the exporter started.
+	Updated time.Time `json:"updated"`
+}
+
+type byName []Pop
+
+func (a byName) Len() int           { return len(a) }
+func (a byName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+var popsMu sync.RWMutex
+var pops []Pop
+var popsByIDMap = make(map[string]Pop)
+var popsUpdated = make(map[string]time.Time)
+
+// coloLabelValuesCache holds the values for coloLabels of colo IDs, as
+// analytics broken out by PoP resolve the same few hundred IDs for every
+// row. It is cleared when a PoP is added, which may resolve an ID that fell
+// back to a placeholder before.
+var coloLabelValuesCache = make(map[string][]string)
+
+// regionNames maps lower-cased status page region names to the pop_region
+// and region_name label values exported for them.
+var regionNames = make(map[string]string)
+
+// PopLabels are the label names used by every metric broken out by PoP, so
+// that status and analytics series can be joined on them.
+var PopLabels = []string{"pop_id", "pop_name", "pop_region"}
+
+// LabelValues returns the values for PopLabels, in order.
+func (p *Pop) LabelValues() []string {
+	return []string{p.Code, p.Name, RegionName(p.Region)}
+}
+
+// SetRegionNames replaces the label values of regions, keyed by their name
+// on the status page, e.g. to export "Latin America & the Caribbean" as
+// "LATAM". Names are matched case-insensitively.
+func SetRegionNames(names map[string]string) {
+	popsMu.Lock()
+	defer popsMu.Unlock()
+	regionNames = make(map[string]string, len(names))
+	for from, to := range names {
+		regionNames[strings.ToLower(strings.TrimSpace(from))] = to
+	}
+	coloLabelValuesCache = make(map[string][]string)
+}
+
+// RegionName returns the label value of the region called region on the
+// status page: its mapped name if SetRegionNames set one, or region.
+func RegionName(region string) string {
+	popsMu.RLock()
+	defer popsMu.RUnlock()
+	if name, ok := regionNames[strings.ToLower(strings.TrimSpace(region))]; ok {
+		return name
+	}
+	return region
+}
+
+// coloLabels returns the label names of analytics broken out by colo: the
+// colo ID as reported by Cloudflare, for joining with other sources such as
+// logs, followed by PopLabels. Without popNames, only pop_id is kept of
+// PopLabels, as the names add nothing to join on.
+func coloLabels(popNames bool) []string {
+	labels := WithLabels([]string{"colo_id"}, PopLabels...)
+	if !popNames {
+		return labels[:2]
+	}
+	return labels
+}
+
+// popLabelValues returns the values for PopLabels of the PoP popID resolves
+// to. The slice is shared and must not be modified.
+func popLabelValues(popID string) []string {
+	return coloLabelValues(popID, true)[1:]
+}
+
+// coloLabelValues returns the values for coloLabels(popNames) of coloID. The
+// slice is shared and must not be modified.
+func coloLabelValues(coloID string, popNames bool) []string {
+	popsMu.RLock()
+	values, ok := coloLabelValuesCache[coloID]
+	popsMu.RUnlock()
+	if !ok {
+		values = WithLabels([]string{coloID}, GetPop(coloID).LabelValues()...)
+		popsMu.Lock()
+		coloLabelValuesCache[coloID] = values
+		popsMu.Unlock()
+	}
+	if !popNames {
+		return values[:2:2]
+	}
+	return values
+}
+
+// aggregationLabel tells analytics of the whole zone ("zone") from those
+// broken out by PoP ("pop") apart with Options.AggregationLabel.
+const aggregationLabel = "aggregation"
+
+// byPopSet returns set for analytics that zones whose plan allows it break
+// out by PoP, as byPop tells. By default those move to the cloudflare_pop
+// namespace and get the colo labels. With opts.AggregationLabel, all plans
+// keep the namespace and get the aggregation and colo labels instead, so
+// dashboards work across plans.
+func byPopSet(set descSet, byPop bool, opts Options) descSet {
+	switch {
+	case opts.AggregationLabel:
+		set.labels = WithLabels(set.labels, WithLabels([]string{aggregationLabel}, coloLabels(opts.PopNames)...)...)
+	case byPop:
+		set.namespace = fmt.Sprintf("%s_pop", Namespace)
+		set.helpSuffix = "(broken out by point of presence (PoP))"
+		set.labels = WithLabels(set.labels, coloLabels(opts.PopNames)...)
+	}
+	return set
+}
+
+// byPopLabelValues returns the values of the labels byPopSet adds, for
+// analytics of coloID if byPop. With aggregation, they start with the
+// aggregation label, and zone-level analytics get empty colo labels.
+func byPopLabelValues(coloID string, byPop, aggregation, popNames bool) []string {
+	switch {
+	case !aggregation && !byPop:
+		return nil
+	case !aggregation:
+		return coloLabelValues(coloID, popNames)
+	case !byPop:
+		return append([]string{"zone"}, make([]string, len(coloLabels(popNames)))...)
+	}
+	return WithLabels([]string{"pop"}, coloLabelValues(coloID, popNames)...)
+}
+
+// normalizePopID canonicalizes a colo identifier as reported by either the
+// status page or the analytics APIs so that both resolve to the same PoP.
+func normalizePopID(popID string) string {
+	return strings.ToUpper(strings.TrimSpace(popID))
+}
+
+// The catalog of PoPs known at build time is generated from the status page.
+// PoPs opened since are added at runtime by AddPop as the status exporter
+// finds them.
+//go:generate go run ../cmd/genpops -o pops_generated.go
+
+func init() {
+	pops = make([]Pop, len(builtinPops))
+	for i, p := range builtinPops {
+		p.Source = "built-in"
+		pops[i] = p
+		popsByIDMap[p.Code] = p
+	}
+}
+
+// Pops returns a copy of all known PoPs.
+func Pops() []Pop {
+	popsMu.RLock()
+	defer popsMu.RUnlock()
+	all := append([]Pop(nil), pops...)
+	for i := range all {
+		all[i].Updated = popsUpdated[all[i].Code]
+	}
+	return all
+}
+
+// GetPop resolves a colo identifier to a PoP, falling back to a placeholder
+// entry when the PoP is unknown.
+func GetPop(popID string) *Pop {
+	popID = normalizePopID(popID)
+	popsMu.RLock()
+	defer popsMu.RUnlock()
+	if pop, ok := popsByIDMap[popID]; ok {
+		return &pop
+	}
+	popID = strings.Split(popID, "-")[0]
+	if pop, ok := popsByIDMap[popID]; ok {
+		return &pop
+	}
+	if popID == "" {
+		popID = "Unknown"
+	}
+	return &Pop{
+		Name:   "Unknown",
+		Code:   popID,
+		Region: "Unknown",
+		Source: "fallback",
+	}
+}
+
+// AddPop adds a PoP listed on the status page to the registry. PoPs that
+// are already known keep their name and region, only their Updated time is
+// set.
+func AddPop(newP Pop) {
+	newP.Code = normalizePopID(newP.Code)
+	popsMu.Lock()
+	defer popsMu.Unlock()
+	popsUpdated[newP.Code] = time.Now()
+	if _, ok := popsByIDMap[newP.Code]; ok {
+		return
+	}
+	newP.Source = "external"
+	pops = append(pops, newP)
+	sort.Sort(byName(pops))
+	popsByIDMap[newP.Code] = newP
+	coloLabelValuesCache = make(map[string][]string)
+}
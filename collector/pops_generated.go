@@ -0,0 +1,134 @@
+// Code generated by genpops from the Cloudflare status page; DO NOT EDIT.
+
+package collector
+
+// builtinPops are the PoPs known when the exporter was built.
+var builtinPops = []Pop{
+	{Name: "Auckland, New Zealand", Code: "AKL", Region: "Oceania"},
+	{Name: "Amsterdam, Netherlands", Code: "AMS", Region: "Europe"},
+	{Name: "Stockholm, Sweden", Code: "ARN", Region: "Europe"},
+	{Name: "Athens, Greece", Code: "ATH", Region: "Europe"},
+	{Name: "Atlanta, GA, United States", Code: "ATL", Region: "North America"},
+	{Name: "Barcelona, Spain", Code: "BCN", Region: "Europe"},
+	{Name: "Belgrade, Serbia", Code: "BEG", Region: "Europe"},
+	{Name: "Beirut, Lebanon", Code: "BEY", Region: "Middle East"},
+	{Name: "Bangkok, Thailand", Code: "BKK", Region: "Asia"},
+	{Name: "Nashville, TN, United States", Code: "BNA", Region: "North America"},
+	{Name: "Brisbane, QLD, Australia", Code: "BNE", Region: "Oceania"},
+	{Name: "Mumbai, India", Code: "BOM", Region: "Asia"},
+	{Name: "Boston, MA, United States", Code: "BOS", Region: "North America"},
+	{Name: "Brussels, Belgium", Code: "BRU", Region: "Europe"},
+	{Name: "Budapest, HU", Code: "BUD", Region: "Europe"},
+	{Name: "Cairo, Egypt", Code: "CAI", Region: "Africa"},
+	{Name: "Guangzhou, China", Code: "CAN", Region: "Asia"},
+	{Name: "Paris, France", Code: "CDG", Region: "Europe"},
+	{Name: "Zhengzhou, China", Code: "CGO", Region: "Asia"},
+	{Name: "Colombo, Sri Lanka", Code: "CMB", Region: "Asia"},
+	{Name: "Copenhagen, Denmark", Code: "CPH", Region: "Europe"},
+	{Name: "Cape Town, South Africa", Code: "CPT", Region: "Africa"},
+	{Name: "Changsha, China", Code: "CSX", Region: "Asia"},
+	{Name: "Chengdu, China", Code: "CTU", Region: "Asia"},
+	{Name: "Willemstad, Curaçao", Code: "CUR", Region: "Latin America & the Caribbean"},
+	{Name: "New Delhi, India", Code: "DEL", Region: "Asia"},
+	{Name: "Denver, CO, United States", Code: "DEN", Region: "North America"},
+	{Name: "Dallas, TX, United States", Code: "DFW", Region: "North America"},
+	{Name: "Moscow, Russia", Code: "DME", Region: "Europe"},
+	{Name: "Doha, Qatar", Code: "DOH", Region: "Middle East"},
+	{Name: "Detroit, MI, United States", Code: "DTW", Region: "North America"},
+	{Name: "Dublin, Ireland", Code: "DUB", Region: "Europe"},
+	{Name: "Düsseldorf, Germany", Code: "DUS", Region: "Europe"},
+	{Name: "Dubai, United Arab Emirates", Code: "DXB", Region: "Middle East"},
+	{Name: "Yerevan, Armenia", Code: "EVN", Region: "Asia"},
+	{Name: "Newark, NJ, United States", Code: "EWR", Region: "North America"},
+	{Name: "Buenos Aires, Argentina", Code: "EZE", Region: "Latin America & the Caribbean"},
+	{Name: "Rome, Italy", Code: "FCO", Region: "Europe"},
+	{Name: "Fuzhou, China", Code: "FOC", Region: "Asia"},
+	{Name: "Frankfurt, Germany", Code: "FRA", Region: "Europe"},
+	{Name: "Foshan, China", Code: "FUO", Region: "Asia"},
+	{Name: "Rio de Janeiro, Brazil", Code: "GIG", Region: "Latin America & the Caribbean"},
+	{Name: "São Paulo, Brazil", Code: "GRU", Region: "Latin America & the Caribbean"},
+	{Name: "Hamburg, Germany", Code: "HAM", Region: "Europe"},
+	{Name: "Helsinki, Finland", Code: "HEL", Region: "Europe"},
+	{Name: "Hangzhou, China", Code: "HGH", Region: "Asia"},
+	{Name: "Hong Kong, Hong Kong", Code: "HKG", Region: "Asia"},
+	{Name: "Hengyang, China", Code: "HNY", Region: "Asia"},
+	{Name: "Ashburn, VA, United States", Code: "IAD", Region: "North America"},
+	{Name: "Seoul, South Korea", Code: "ICN", Region: "Asia"},
+	{Name: "Indianapolis, IN, United States", Code: "IND", Region: "North America"},
+	{Name: "Djibouti City, Djibouti", Code: "JIB", Region: "Africa"},
+	{Name: "Johannesburg, South Africa", Code: "JNB", Region: "Africa"},
+	{Name: "Kiev, Ukraine", Code: "KBP", Region: "Europe"},
+	{Name: "Osaka, Japan", Code: "KIX", Region: "Asia"},
+	{Name: "Kathmandu, Nepal", Code: "KTM", Region: "Asia"},
+	{Name: "Kuala Lumpur, Malaysia", Code: "KUL", Region: "Asia"},
+	{Name: "Kuwait City, Kuwait", Code: "KWI", Region: "Middle East"},
+	{Name: "Luanda, Angola", Code: "LAD", Region: "Africa"},
+	{Name: "Las Vegas, NV, United States", Code: "LAS", Region: "North America"},
+	{Name: "Los Angeles, CA, United States", Code: "LAX", Region: "North America"},
+	{Name: "London, United Kingdom", Code: "LHR", Region: "Europe"},
+	{Name: "Lima, Peru", Code: "LIM", Region: "Latin America & the Caribbean"},
+	{Name: "Lisbon, Portugal", Code: "LIS", Region: "Europe"},
+	{Name: "Luoyang, China", Code: "LYA", Region: "Asia"},
+	{Name: "Chennai, India", Code: "MAA", Region: "Asia"},
+	{Name: "Madrid, Spain", Code: "MAD", Region: "Europe"},
+	{Name: "Manchester, United Kingdom", Code: "MAN", Region: "Europe"},
+	{Name: "Mombasa, Kenya", Code: "MBA", Region: "Africa"},
+	{Name: "Kansas City, MO, United States", Code: "MCI", Region: "North America"},
+	{Name: "Muscat, Oman", Code: "MCT", Region: "Middle East"},
+	{Name: "Medellín, Colombia", Code: "MDE", Region: "Latin America & the Caribbean"},
+	{Name: "Melbourne, VIC, Australia", Code: "MEL", Region: "Oceania"},
+	{Name: "McAllen, TX, United States", Code: "MFE", Region: "North America"},
+	{Name: "Miami, FL, United States", Code: "MIA", Region: "North America"},
+	{Name: "Manila, Philippines", Code: "MNL", Region: "Asia"},
+	{Name: "Marseille, France", Code: "MRS", Region: "Europe"},
+	{Name: "Port Louis, Mauritius", Code: "MRU", Region: "Africa"},
+	{Name: "Minneapolis, MN, United States", Code: "MSP", Region: "North America"},
+	{Name: "Munich, Germany", Code: "MUC", Region: "Europe"},
+	{Name: "Milan, Italy", Code: "MXP", Region: "Europe"},
+	{Name: "Langfang, China", Code: "NAY", Region: "Asia"},
+	{Name: "Nanning, China", Code: "NNG", Region: "Asia"},
+	{Name: "Tokyo, Japan", Code: "NRT", Region: "Asia"},
+	{Name: "Omaha, NE, United States", Code: "OMA", Region: "North America"},
+	{Name: "Chicago, IL, United States", Code: "ORD", Region: "North America"},
+	{Name: "Oslo, Norway", Code: "OSL", Region: "Europe"},
+	{Name: "Bucharest, Romania", Code: "OTP", Region: "Europe"},
+	{Name: "Portland, OR, United States", Code: "PDX", Region: "North America"},
+	{Name: "Perth, WA, Australia", Code: "PER", Region: "Oceania"},
+	{Name: "Phoenix, AZ, United States", Code: "PHX", Region: "North America"},
+	{Name: "Pittsburgh, PA, United States", Code: "PIT", Region: "North America"},
+	{Name: "Phnom Penh, Cambodia", Code: "PNH", Region: "Asia"},
+	{Name: "Prague, Czech Republic", Code: "PRG", Region: "Europe"},
+	{Name: "Panama City, Panama", Code: "PTY", Region: "Latin America & the Caribbean"},
+	{Name: "San Diego, CA, United States", Code: "SAN", Region: "North America"},
+	{Name: "Valparaíso, Chile", Code: "SCL", Region: "Latin America & the Caribbean"},
+	{Name: "Seattle, WA, United States", Code: "SEA", Region: "North America"},
+	{Name: "San Francisco, CA, United States", Code: "SFO", Region: "North America"},
+	{Name: "Shenyang, China", Code: "SHE", Region: "Asia"},
+	{Name: "Singapore, Singapore", Code: "SIN", Region: "Asia"},
+	{Name: "San Jose, CA, United States", Code: "SJC", Region: "North America"},
+	{Name: "San Jose (Alternate), CA, United States", Code: "SJC-PIG", Region: "North America"},
+	{Name: "Shijiazhuang, China", Code: "SJW", Region: "Asia"},
+	{Name: "Salt Lake City, UT, United States", Code: "SLC", Region: "North America"},
+	{Name: "Sofia, Bulgaria", Code: "SOF", Region: "Europe"},
+	{Name: "St. Louis, MO, United States", Code: "STL", Region: "North America"},
+	{Name: "Sydney, NSW, Australia", Code: "SYD", Region: "Oceania"},
+	{Name: "Suzhou, China", Code: "SZV", Region: "Asia"},
+	{Name: "Dongguan, China", Code: "SZX", Region: "Asia"},
+	{Name: "Qingdao, China", Code: "TAO", Region: "Asia"},
+	{Name: "Jinan, China", Code: "TNA", Region: "Asia"},
+	{Name: "Tampa, FL, United States", Code: "TPA", Region: "North America"},
+	{Name: "Taipei, Taiwan", Code: "TPE", Region: "Asia"},
+	{Name: "Tianjin, China", Code: "TSN", Region: "Asia"},
+	{Name: "Berlin, Germany", Code: "TXL", Region: "Europe"},
+	{Name: "Quito, Ecuador", Code: "UIO", Region: "Latin America & the Caribbean"},
+	{Name: "Vienna, Austria", Code: "VIE", Region: "Europe"},
+	{Name: "Warsaw, Poland", Code: "WAW", Region: "Europe"},
+	{Name: "Wuhan, China", Code: "WUH", Region: "Asia"},
+	{Name: "Wuxi, China", Code: "WUX", Region: "Asia"},
+	{Name: "Xi'an, China", Code: "XIY", Region: "Asia"},
+	{Name: "Montréal, QC, Canada", Code: "YUL", Region: "North America"},
+	{Name: "Vancouver, BC, Canada", Code: "YVR", Region: "North America"},
+	{Name: "Toronto, ON, Canada", Code: "YYZ", Region: "North America"},
+	{Name: "Zagreb, Croatia", Code: "ZAG", Region: "Europe"},
+	{Name: "Zürich, Switzerland", Code: "ZRH", Region: "Europe"},
+}
@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterAccount("queues", newQueuesCollector)
+}
+
+const queuesBacklogQuery = `query ($accountTag: string, $since: Time, $until: Time) {
+  viewer {
+    accounts(filter: {accountTag: $accountTag}) {
+      groups: queueBacklogAdaptiveGroups(limit: 10000, filter: {datetime_geq: $since, datetime_lt: $until}) {
+        avg {
+          messages
+          bytes
+        }
+        dimensions {
+          queueId
+        }
+      }
+    }
+  }
+}`
+
+const queuesLagQuery = `query ($accountTag: string, $since: Time, $until: Time) {
+  viewer {
+    accounts(filter: {accountTag: $accountTag}) {
+      groups: queueMessageOperationsAdaptiveGroups(limit: 10000, filter: {datetime_geq: $since, datetime_lt: $until}) {
+        max {
+          lagTime
+        }
+        dimensions {
+          queueId
+        }
+      }
+    }
+  }
+}`
+
+// queuesCollector collects the backlog of each Cloudflare Queue, and how
+// long the messages its consumers received had waited, so consumers falling
+// behind can be alerted on like any other queue consumer. It only runs with
+// Options.Queues, as accounts without Queues fail.
+type queuesCollector struct {
+	cf      API
+	gql     *GraphQLClient
+	enabled bool
+	descs   []*prometheus.Desc
+
+	backlogMessages *prometheus.Desc
+	backlogBytes    *prometheus.Desc
+	lag             *prometheus.Desc
+}
+
+func newQueuesCollector(api API, account Account, opts Options) AccountCollector {
+	set := descSet{
+		namespace:   Namespace,
+		labels:      []string{"queue_id", "queue_name"},
+		constLabels: AccountLabels(account),
+	}
+	c := &queuesCollector{cf: api, gql: opts.GraphQL, enabled: opts.Queues}
+	c.descs = descTable{
+		{&c.backlogMessages, metricDef{"queue", "backlog_messages", "Average number of messages waiting in the queue over the last 5 minutes", nil}},
+		{&c.backlogBytes, metricDef{"queue", "backlog_bytes", "Average size of the messages waiting in the queue over the last 5 minutes", nil}},
+		{&c.lag, metricDef{"queue", "consumer_lag_seconds", "Longest time a message consumed in the last 5 minutes had waited in the queue", nil}},
+	}.build(set)
+	return c
+}
+
+func (c *queuesCollector) Name() string { return "queues" }
+
+func (c *queuesCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *queuesCollector) Collect(ctx context.Context, account Account, ch chan<- prometheus.Metric) error {
+	if !c.enabled || c.gql == nil {
+		return nil
+	}
+	raw, err := c.cf.Raw(http.MethodGet, fmt.Sprintf("/accounts/%s/queues", account.ID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get queues from cloudflare: %s", err)
+	}
+	var queues []struct {
+		ID   string `json:"queue_id"`
+		Name string `json:"queue_name"`
+	}
+	if err := json.Unmarshal(raw, &queues); err != nil {
+		return fmt.Errorf("failed to parse queues: %s", err)
+	}
+	names := make(map[string]string, len(queues))
+	for _, q := range queues {
+		names[q.ID] = q.Name
+	}
+
+	backlog, err := c.gql.accountGroups(ctx, account.ID, queuesBacklogQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get queue backlog from cloudflare: %s", err)
+	}
+	for _, g := range backlog {
+		id := g.dimension("queueId")
+		ch <- prometheus.MustNewConstMetric(c.backlogMessages, prometheus.GaugeValue, g.Avg["messages"], id, names[id])
+		ch <- prometheus.MustNewConstMetric(c.backlogBytes, prometheus.GaugeValue, g.Avg["bytes"], id, names[id])
+	}
+	lag, err := c.gql.accountGroups(ctx, account.ID, queuesLagQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get queue consumer lag from cloudflare: %s", err)
+	}
+	for _, g := range lag {
+		id := g.dimension("queueId")
+		// lagTime is in milliseconds.
+		ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, g.Max["lagTime"]/1000, id, names[id])
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("referers", newReferersCollector)
+	RequireFeature("referers", FeatureProxied)
+}
+
+// referersQuery selects the top referer hosts; %d is the number of hosts.
+const referersQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: httpRequestsAdaptiveGroups(limit: %d, orderBy: [count_DESC], filter: {datetime_geq: $since, datetime_lt: $until}) {
+        count
+        dimensions {
+          clientRefererHost
+        }
+      }
+    }
+  }
+}`
+
+// referersCollector collects requests broken out by the host of their
+// referer from the sampled GraphQL datasets, so traffic sources can be
+// tracked without shipping logs. Only the top hosts are exported to bound
+// cardinality.
+type referersCollector struct {
+	gql   *GraphQLClient
+	top   int
+	descs []*prometheus.Desc
+
+	requests *prometheus.Desc
+}
+
+func newReferersCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &referersCollector{gql: opts.GraphQL, top: opts.GraphQLReferers}
+	c.descs = descTable{
+		{&c.requests, metricDef{"referer", "sampled_requests", "Approximate number of requests served in the last 5 minutes by referer host, from sampled data. An empty host is requests without referer", []string{"referer_host"}}},
+	}.build(set)
+	return c
+}
+
+func (c *referersCollector) Name() string { return "referers" }
+
+func (c *referersCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *referersCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if c.top <= 0 || c.gql == nil {
+		return nil
+	}
+	groups, err := c.gql.zoneGroups(ctx, zone.ID, fmt.Sprintf(referersQuery, c.top))
+	if err != nil {
+		return fmt.Errorf("failed to get sampled referer analytics from cloudflare: %s", err)
+	}
+	for _, g := range groups {
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.GaugeValue, g.Count, g.dimension("clientRefererHost"))
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("regional_services", newRegionalServicesCollector)
+}
+
+// regionalHostname is a hostname of the zone whose traffic Regional
+// Services keep to the PoPs of a region, e.g. eu or fedramp.
+type regionalHostname struct {
+	Hostname  string `json:"hostname"`
+	RegionKey string `json:"region_key"`
+}
+
+// regionalHostnames returns the hostnames of the zone restricted to a
+// region.
+func regionalHostnames(api API, zoneID string) ([]regionalHostname, error) {
+	raw, err := api.Raw(http.MethodGet, "/zones/"+zoneID+"/addressing/regional_hostnames", nil)
+	if err != nil {
+		return nil, err
+	}
+	var hostnames []regionalHostname
+	if err := json.Unmarshal(raw, &hostnames); err != nil {
+		return nil, fmt.Errorf("failed to parse regional hostnames: %s", err)
+	}
+	return hostnames, nil
+}
+
+// coloSet is the set of colos a zone's colo breakdowns are restricted to.
+// A nil coloSet allows every colo.
+type coloSet map[string]bool
+
+// allows reports whether breakdowns by coloID are exported.
+func (s coloSet) allows(coloID string) bool {
+	return s == nil || s[normalizePopID(coloID)]
+}
+
+// coloFilter restricts the colo breakdowns of a zone whose proxied
+// hostnames are all kept to regions by Regional Services to the colos of
+// those regions, as mapped by Options.RegionColos. The regions are looked
+// up again every Options.ZoneRefresh, or hourly if zones are not
+// refreshed. A nil coloFilter allows every colo.
+type coloFilter struct {
+	cf          API
+	zone        cloudflare.Zone
+	regionColos map[string][]string
+	refresh     time.Duration
+
+	mu      sync.Mutex
+	colos   coloSet
+	fetched time.Time
+}
+
+// newColoFilter returns the colo filter of zone, or nil if no regions are
+// mapped to colos.
+func newColoFilter(api API, zone cloudflare.Zone, opts Options) *coloFilter {
+	if len(opts.RegionColos) == 0 {
+		return nil
+	}
+	refresh := opts.ZoneRefresh
+	if refresh <= 0 {
+		refresh = featureRefresh
+	}
+	return &coloFilter{cf: api, zone: zone, regionColos: opts.RegionColos, refresh: refresh}
+}
+
+// current returns the colos the zone's breakdowns are restricted to,
+// looking them up again if they are older than the refresh interval. If
+// that fails, the last colos are kept.
+func (f *coloFilter) current() coloSet {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.fetched.IsZero() && time.Since(f.fetched) < f.refresh {
+		return f.colos
+	}
+	colos, err := f.lookup()
+	if err != nil {
+		log.Debugf("Failed to get regional hostnames of zone %s, keeping its colo restriction: %s", f.zone.Name, err)
+		return f.colos
+	}
+	f.colos = colos
+	f.fetched = time.Now()
+	return f.colos
+}
+
+// lookup returns the colos of the regions the zone's proxied hostnames are
+// restricted to, or nil if any proxied hostname is not regional or is in a
+// region that is not mapped.
+func (f *coloFilter) lookup() (coloSet, error) {
+	hostnames, err := regionalHostnames(f.cf, f.zone.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(hostnames) == 0 {
+		return nil, nil
+	}
+	regions := make(map[string]string, len(hostnames))
+	for _, h := range hostnames {
+		regions[strings.ToLower(h.Hostname)] = h.RegionKey
+	}
+	records, err := proxiedRecords(f.cf, f.zone.ID)
+	if err != nil {
+		return nil, err
+	}
+	colos := coloSet{}
+	for _, r := range records {
+		name := strings.ToLower(r.Name)
+		region, ok := regions[name]
+		if !ok {
+			log.Debugf("Not restricting colos of zone %s, proxied hostname %s is not regional", f.zone.Name, name)
+			return nil, nil
+		}
+		regionColos, ok := f.regionColos[region]
+		if !ok {
+			log.Debugf("Not restricting colos of zone %s, region %q of %s is not mapped to colos", f.zone.Name, region, name)
+			return nil, nil
+		}
+		for _, colo := range regionColos {
+			colos[normalizePopID(colo)] = true
+		}
+	}
+	if len(colos) == 0 {
+		return nil, nil
+	}
+	return colos, nil
+}
+
+// regionalServicesCollector collects the hostnames of the zone that
+// Regional Services restrict to a region, so that geofencing required for
+// compliance can be verified from monitoring. It is opt-in with
+// Options.RegionalServices, as the API fails for zones without them.
+type regionalServicesCollector struct {
+	cf      API
+	enabled bool
+	descs   []*prometheus.Desc
+
+	hostnameInfo *prometheus.Desc
+}
+
+func newRegionalServicesCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &regionalServicesCollector{cf: api, enabled: opts.RegionalServices}
+	c.descs = descTable{
+		{&c.hostnameInfo, metricDef{"regional", "hostname_info", "Region a hostname's traffic is restricted to by Regional Services, with a constant '1' value", []string{"hostname", "region_key"}}},
+	}.build(set)
+	return c
+}
+
+func (c *regionalServicesCollector) Name() string { return "regional_services" }
+
+func (c *regionalServicesCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *regionalServicesCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if !c.enabled {
+		return nil
+	}
+	hostnames, err := regionalHostnames(c.cf, zone.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get regional hostnames from cloudflare: %s", err)
+	}
+	for _, h := range hostnames {
+		ch <- prometheus.MustNewConstMetric(c.hostnameInfo, prometheus.GaugeValue, 1, strings.ToLower(h.Hostname), h.RegionKey)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+// Rule descriptions change rarely, so they are loaded every
+// ruleDescriptionRefresh, or after ruleDescriptionRetry when an unknown rule
+// is seen.
+const (
+	ruleDescriptionRefresh = time.Hour
+	ruleDescriptionRetry   = 10 * time.Minute
+)
+
+// ruleset is a Rulesets API ruleset. Rules are left out when listing
+// rulesets.
+type ruleset struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Phase       string    `json:"phase"`
+	Version     string    `json:"version"`
+	LastUpdated time.Time `json:"last_updated"`
+	Rules       []struct {
+		ID               string `json:"id"`
+		Description      string `json:"description"`
+		Action           string `json:"action"`
+		Enabled          bool   `json:"enabled"`
+		ActionParameters struct {
+			// ID is the ruleset run by an "execute" rule.
+			ID string `json:"id"`
+		} `json:"action_parameters"`
+	} `json:"rules"`
+}
+
+// listRulesets returns the rulesets available to zone, without their rules.
+func listRulesets(api API, zoneID string) ([]ruleset, error) {
+	raw, err := api.Raw(http.MethodGet, "/zones/"+zoneID+"/rulesets", nil)
+	if err != nil {
+		return nil, err
+	}
+	var rulesets []ruleset
+	if err := json.Unmarshal(raw, &rulesets); err != nil {
+		return nil, fmt.Errorf("failed to parse rulesets: %s", err)
+	}
+	return rulesets, nil
+}
+
+// getRuleset returns a ruleset of zone with its rules.
+func getRuleset(api API, zoneID, id string) (ruleset, error) {
+	var rs ruleset
+	raw, err := api.Raw(http.MethodGet, "/zones/"+zoneID+"/rulesets/"+id, nil)
+	if err != nil {
+		return rs, err
+	}
+	if err := json.Unmarshal(raw, &rs); err != nil {
+		return rs, fmt.Errorf("failed to parse ruleset %s: %s", id, err)
+	}
+	return rs, nil
+}
+
+// ruleDescriptions resolves the rule IDs of firewall events to the
+// descriptions of the rules, so alerts name the rule rather than a UUID.
+// Descriptions are loaded in the background: scrapes only wait for the
+// first load, within their deadline, so series do not start without their
+// description, and never for later ones.
+type ruleDescriptions struct {
+	cf     API
+	zoneID string
+	// fetch returns the descriptions of the zone's rules by ID.
+	fetch func(api API, zoneID string) (map[string]string, error)
+
+	mu           sync.Mutex
+	descriptions map[string]string
+	loaded       time.Time
+	loading      bool
+	// first is closed once the first load finished.
+	first chan struct{}
+}
+
+func newRuleDescriptions(api API, zoneID string, fetch func(api API, zoneID string) (map[string]string, error)) *ruleDescriptions {
+	return &ruleDescriptions{cf: api, zoneID: zoneID, fetch: fetch, first: make(chan struct{})}
+}
+
+// refresh starts loading the descriptions in the background if they were
+// never loaded, are older than ruleDescriptionRefresh, or known is false
+// and they are older than ruleDescriptionRetry. It must be called with
+// r.mu held.
+func (r *ruleDescriptions) refresh(known bool) {
+	age := time.Since(r.loaded)
+	if r.loading || !(r.descriptions == nil || age > ruleDescriptionRefresh || (!known && age > ruleDescriptionRetry)) {
+		return
+	}
+	r.loading = true
+	r.loaded = time.Now()
+	go r.load()
+}
+
+// prefetch starts loading the descriptions if they are due, so the first
+// load runs along with the query of the events to describe.
+func (r *ruleDescriptions) prefetch() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refresh(true)
+}
+
+// describe returns the description of the rule with the given ID, or an
+// empty string if it is unknown. Until the first load finished, it waits
+// for it as long as ctx allows.
+func (r *ruleDescriptions) describe(ctx context.Context, ruleID string) string {
+	r.mu.Lock()
+	description, known := r.descriptions[ruleID]
+	r.refresh(known)
+	loaded := r.descriptions != nil
+	r.mu.Unlock()
+	if loaded {
+		return description
+	}
+	select {
+	case <-r.first:
+	case <-ctx.Done():
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.descriptions[ruleID]
+}
+
+// load fetches the descriptions of the zone's rules, without holding r.mu.
+// On failure the previous descriptions are kept until the next retry.
+func (r *ruleDescriptions) load() {
+	descriptions, err := r.fetch(r.cf, r.zoneID)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loading = false
+	first := r.descriptions == nil
+	if err != nil {
+		log.Debugf("Failed to get rules of zone %s: %s", r.zoneID, err)
+		if first {
+			r.descriptions = map[string]string{}
+		}
+	} else {
+		r.descriptions = descriptions
+	}
+	if first {
+		close(r.first)
+	}
+}
+
+// rulesetDescriptions returns the descriptions of the rules of every
+// ruleset of the zone. Rulesets that fail to load are skipped, so one
+// ruleset the token cannot read does not leave every rule undescribed.
+func rulesetDescriptions(api API, zoneID string) (map[string]string, error) {
+	rulesets, err := listRulesets(api, zoneID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rulesets: %s", err)
+	}
+	descriptions := map[string]string{}
+	for _, listed := range rulesets {
+		rs, err := getRuleset(api, zoneID, listed.ID)
+		if err != nil {
+			log.Debugf("Skipping ruleset %s of zone %s in rule descriptions: %s", listed.ID, zoneID, err)
+			continue
+		}
+		for _, rule := range rs.Rules {
+			descriptions[rule.ID] = rule.Description
+		}
+	}
+	return descriptions, nil
+}
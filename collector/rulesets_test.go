@@ -0,0 +1,40 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRuleDescriptions(t *testing.T) {
+	release := make(chan struct{})
+	fetches := 0
+	r := newRuleDescriptions(nil, "zone", func(api API, zoneID string) (map[string]string, error) {
+		fetches++
+		if fetches > 1 {
+			return nil, errors.New("unavailable")
+		}
+		<-release
+		return map[string]string{"rule": "Block bots"}, nil
+	})
+
+	// The first load is waited for within the deadline only.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if got := r.describe(ctx, "rule"); got != "" {
+		t.Errorf("got description %q past the deadline, want none", got)
+	}
+	close(release)
+	if got := r.describe(context.Background(), "rule"); got != "Block bots" {
+		t.Errorf("got description %q, want the loaded one", got)
+	}
+
+	// Failed reloads keep the descriptions, and scrapes do not wait for them.
+	r.mu.Lock()
+	r.loaded = time.Now().Add(-2 * ruleDescriptionRefresh)
+	r.mu.Unlock()
+	if got := r.describe(context.Background(), "rule"); got != "Block bots" {
+		t.Errorf("got description %q during a reload, want the previous one", got)
+	}
+}
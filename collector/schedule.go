@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Schedule runs an expensive collector at most once per interval instead of
+// on every scrape. Intervals are aligned to the wall clock, so a collector
+// scheduled every hour runs on the first scrape of each hour. In between,
+// the metrics of the last run are served with its timestamp, so they are
+// recognisably stale.
+//
+// A minimum interval schedule instead runs a collector once its last run is
+// interval old, for sources that update less often than they are scraped.
+// Its metrics are served as current in between, as they are at most
+// interval old.
+type Schedule struct {
+	interval time.Duration
+	aligned  bool
+
+	mu      sync.Mutex
+	last    time.Time
+	metrics []prometheus.Metric
+}
+
+// NewSchedule returns a Schedule running a collector every interval, or nil
+// to run it on every scrape if interval is not positive. A nil Schedule is
+// always due.
+func NewSchedule(interval time.Duration) *Schedule {
+	if interval <= 0 {
+		return nil
+	}
+	return &Schedule{interval: interval, aligned: true}
+}
+
+// NewMinIntervalSchedule returns a Schedule running a collector at most
+// every interval, or nil to run it on every scrape if interval is not
+// positive.
+func NewMinIntervalSchedule(interval time.Duration) *Schedule {
+	if interval <= 0 {
+		return nil
+	}
+	return &Schedule{interval: interval}
+}
+
+// Due reports whether the collector should run at now, i.e. it has not run
+// successfully within the current interval, or within interval before now
+// for minimum interval schedules.
+func (s *Schedule) Due(now time.Time) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last.IsZero() {
+		return true
+	}
+	if !s.aligned {
+		return now.Sub(s.last) >= s.interval
+	}
+	return now.Truncate(s.interval).After(s.last.Truncate(s.interval))
+}
+
+// Age returns how long before now the last successful run started, and
+// false if there was none.
+func (s *Schedule) Age(now time.Time) (time.Duration, bool) {
+	if s == nil {
+		return 0, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Sub(s.last), !s.last.IsZero()
+}
+
+// Record keeps the metrics of a successful run started at now, to be served
+// until the next run is due.
+func (s *Schedule) Record(now time.Time, metrics []prometheus.Metric) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = now
+	s.metrics = metrics
+}
+
+// Replay sends the metrics of the last run to ch, timestamped with the time
+// it started unless the schedule is a minimum interval one, and returns
+// their number.
+func (s *Schedule) Replay(ch chan<- prometheus.Metric) int {
+	s.mu.Lock()
+	last, metrics := s.last, s.metrics
+	s.mu.Unlock()
+	for _, m := range metrics {
+		if !s.aligned {
+			ch <- m
+			continue
+		}
+		ch <- timestampedMetric{Metric: m, timestamp: last}
+	}
+	return len(metrics)
+}
+
+// timestampedMetric is a Metric exposed with an explicit timestamp.
+type timestampedMetric struct {
+	prometheus.Metric
+	timestamp time.Time
+}
+
+func (m timestampedMetric) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+	ms := m.timestamp.UnixNano() / int64(time.Millisecond)
+	out.TimestampMs = &ms
+	return nil
+}
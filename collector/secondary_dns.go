@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("secondary_dns", newSecondaryDNSCollector)
+}
+
+// secondaryDNSIncoming is the incoming zone transfer configuration and
+// status of a secondary zone.
+type secondaryDNSIncoming struct {
+	ID                 string    `json:"id"`
+	Name               string    `json:"name"`
+	Peers              []string  `json:"peers"`
+	AutoRefreshSeconds int       `json:"auto_refresh_seconds"`
+	SOASerial          int64     `json:"soa_serial"`
+	CreatedTime        time.Time `json:"created_time"`
+	CheckedTime        time.Time `json:"checked_time"`
+	ModifiedTime       time.Time `json:"modified_time"`
+}
+
+// secondaryDNSCollector collects incoming zone transfer (AXFR/IXFR) status
+// for zones using Cloudflare as secondary DNS. Other zones are skipped.
+type secondaryDNSCollector struct {
+	cf    API
+	state *StateStore
+	descs []*prometheus.Desc
+
+	soaSerial       *prometheus.Desc
+	lastCheck       *prometheus.Desc
+	lastTransfer    *prometheus.Desc
+	refreshInterval *prometheus.Desc
+}
+
+func newSecondaryDNSCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &secondaryDNSCollector{cf: api, state: opts.State}
+	c.descs = descTable{
+		{&c.soaSerial, metricDef{"secondary_dns", "soa_serial", "SOA serial of the zone as last transferred from the primary", nil}},
+		{&c.lastCheck, metricDef{"secondary_dns", "last_check_timestamp_seconds", "When Cloudflare last checked the primary's SOA serial", nil}},
+		{&c.lastTransfer, metricDef{"secondary_dns", "last_transfer_timestamp_seconds", "When the exporter first saw the current SOA serial, i.e. the last successful zone transfer", nil}},
+		{&c.refreshInterval, metricDef{"secondary_dns", "refresh_interval_seconds", "How often Cloudflare checks the primary for a new SOA serial", nil}},
+	}.build(set)
+	return c
+}
+
+func (c *secondaryDNSCollector) Name() string { return "secondary_dns" }
+
+func (c *secondaryDNSCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *secondaryDNSCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if zone.Type != "secondary" {
+		return nil
+	}
+
+	raw, err := c.cf.Raw(http.MethodGet, "/zones/"+zone.ID+"/secondary_dns/incoming", nil)
+	if err != nil {
+		return fmt.Errorf("failed to get secondary dns status from cloudflare: %s", err)
+	}
+	var incoming secondaryDNSIncoming
+	if err := json.Unmarshal(raw, &incoming); err != nil {
+		return fmt.Errorf("failed to parse secondary dns status: %s", err)
+	}
+
+	// The API only reports the current serial, so remember when it changed.
+	key := SeriesKey("secondary_dns_soa_serial", zone.ID)
+	serial := strconv.FormatInt(incoming.SOASerial, 10)
+	state, ok := c.state.Get(key)
+	if !ok || strconv.FormatFloat(state.Value, 'f', -1, 64) != serial {
+		transferred := incoming.CheckedTime
+		if !ok {
+			// Unknown when the serial was transferred before we first saw it.
+			transferred = time.Time{}
+		}
+		state = SeriesState{Value: float64(incoming.SOASerial), LastBucket: transferred}
+		c.state.Set(key, state)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.soaSerial, prometheus.GaugeValue, float64(incoming.SOASerial))
+	ch <- prometheus.MustNewConstMetric(c.refreshInterval, prometheus.GaugeValue, float64(incoming.AutoRefreshSeconds))
+	if !incoming.CheckedTime.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.lastCheck, prometheus.GaugeValue, float64(incoming.CheckedTime.Unix()))
+	}
+	if !state.LastBucket.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.lastTransfer, prometheus.GaugeValue, float64(state.LastBucket.Unix()))
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("ssl", newSSLCollector)
+}
+
+const certificatePacksPerPage = 50
+
+// certificatePackStatuses are the statuses a certificate pack goes through
+// when it is ordered or renewed. A pack stays "pending_validation" until
+// Cloudflare could validate control of its hostnames.
+var certificatePackStatuses = []string{"initializing", "pending_validation", "pending_issuance", "pending_deployment", "active", "expired", "validation_timed_out", "issuance_timed_out", "deployment_timed_out"}
+
+// certificatePack is a set of edge certificates for some of the zone's
+// hostnames, usually one per signature algorithm.
+type certificatePack struct {
+	ID                   string   `json:"id"`
+	Type                 string   `json:"type"`
+	Hosts                []string `json:"hosts"`
+	Status               string   `json:"status"`
+	CertificateAuthority string   `json:"certificate_authority"`
+	Certificates         []struct {
+		ExpiresOn time.Time `json:"expires_on"`
+	} `json:"certificates"`
+}
+
+// sslRecommendation is the encryption mode the SSL/TLS recommender found
+// the zone's origins to support.
+type sslRecommendation struct {
+	Value string `json:"value"`
+}
+
+// sslCollector collects the status of the zone's edge certificate packs,
+// so that renewals stuck on validation can be alerted on before the
+// certificates expire, and the SSL/TLS recommender's recommendation.
+type sslCollector struct {
+	cf    API
+	descs []*prometheus.Desc
+
+	packInfo       *prometheus.Desc
+	packStatus     *prometheus.Desc
+	packExpiry     *prometheus.Desc
+	recommendation *prometheus.Desc
+}
+
+func newSSLCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &sslCollector{cf: api}
+	c.descs = descTable{
+		{&c.packInfo, metricDef{"ssl", "certificate_pack_info", "Type, hostnames and certificate authority of an edge certificate pack, with a constant '1' value", []string{"certificate_pack_id", "type", "hosts", "certificate_authority"}}},
+		{&c.packStatus, metricDef{"ssl", "certificate_pack_status", "Status of an edge certificate pack, 1 for the current one. A pending_validation pack waits for control of its hostnames to be validated", []string{"certificate_pack_id", "status"}}},
+		{&c.packExpiry, metricDef{"ssl", "certificate_pack_expiry_timestamp_seconds", "When the first certificate of an edge certificate pack expires", []string{"certificate_pack_id"}}},
+		{&c.recommendation, metricDef{"ssl", "recommendation", "Encryption mode the SSL/TLS recommender recommends for the zone, with a constant '1' value", []string{"mode"}}},
+	}.build(set)
+	return c
+}
+
+func (c *sslCollector) Name() string { return "ssl" }
+
+func (c *sslCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *sslCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	packs := []certificatePack{}
+	for page := 1; ; page++ {
+		raw, err := c.cf.Raw(http.MethodGet, fmt.Sprintf("/zones/%s/ssl/certificate_packs?status=all&page=%d&per_page=%d", zone.ID, page, certificatePacksPerPage), nil)
+		if err != nil {
+			return fmt.Errorf("failed to get certificate packs from cloudflare: %s", err)
+		}
+		var result []certificatePack
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return fmt.Errorf("failed to parse certificate packs: %s", err)
+		}
+		packs = append(packs, result...)
+		if len(result) < certificatePacksPerPage {
+			break
+		}
+	}
+
+	for _, pack := range packs {
+		ch <- prometheus.MustNewConstMetric(c.packInfo, prometheus.GaugeValue, 1, pack.ID, pack.Type, strings.Join(pack.Hosts, ","), pack.CertificateAuthority)
+		statuses := certificatePackStatuses
+		if !contains(statuses, pack.Status) {
+			statuses = WithLabels(statuses, pack.Status)
+		}
+		for _, status := range statuses {
+			ch <- prometheus.MustNewConstMetric(c.packStatus, prometheus.GaugeValue, boolToFloat(status == pack.Status), pack.ID, status)
+		}
+		var expiry time.Time
+		for _, cert := range pack.Certificates {
+			if !cert.ExpiresOn.IsZero() && (expiry.IsZero() || cert.ExpiresOn.Before(expiry)) {
+				expiry = cert.ExpiresOn
+			}
+		}
+		if !expiry.IsZero() {
+			ch <- prometheus.MustNewConstMetric(c.packExpiry, prometheus.GaugeValue, float64(expiry.Unix()), pack.ID)
+		}
+	}
+
+	// Not every zone gets a recommendation, which is no reason to drop the
+	// certificate packs.
+	raw, err := c.cf.Raw(http.MethodGet, "/zones/"+zone.ID+"/ssl/recommendation", nil)
+	if err != nil {
+		log.Debugf("Failed to get SSL/TLS recommendation of zone %s: %s", zone.Name, err)
+		return nil
+	}
+	var recommendation sslRecommendation
+	if err := json.Unmarshal(raw, &recommendation); err != nil {
+		return fmt.Errorf("failed to parse ssl recommendation: %s", err)
+	}
+	if recommendation.Value != "" {
+		ch <- prometheus.MustNewConstMetric(c.recommendation, prometheus.GaugeValue, 1, recommendation.Value)
+	}
+	return nil
+}
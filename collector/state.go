@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SeriesState is the accumulation state kept for a single series so that
+// counters derived from windowed API data continue across restarts.
+type SeriesState struct {
+	// Value is the accumulated counter value.
+	Value float64 `json:"value"`
+	// LastBucket is the end of the newest time bucket already accumulated.
+	LastBucket time.Time `json:"last_bucket"`
+}
+
+// StateStore holds SeriesState by series key, optionally persisted to a JSON
+// file. The zero path keeps state in memory only.
+type StateStore struct {
+	path string
+
+	mu     sync.Mutex
+	series map[string]SeriesState
+	dirty  bool
+}
+
+// OpenStateStore loads the state file at path, if any. A missing file is not
+// an error; it is created on the first Save.
+func OpenStateStore(path string) (*StateStore, error) {
+	s := &StateStore{path: path, series: make(map[string]SeriesState)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.series); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SeriesKey builds the key a series' state is stored under from its metric
+// name and label values.
+func SeriesKey(name string, labelValues ...string) string {
+	return name + "{" + strings.Join(labelValues, ",") + "}"
+}
+
+// zoneSeriesKey returns SeriesKey(name, zoneID, labelValues...) without
+// allocating the joined label values.
+func zoneSeriesKey(name, zoneID string, labelValues []string) string {
+	n := len(name) + len(zoneID) + 2
+	for _, v := range labelValues {
+		n += len(v) + 1
+	}
+	var b strings.Builder
+	b.Grow(n)
+	b.WriteString(name)
+	b.WriteByte('{')
+	b.WriteString(zoneID)
+	for _, v := range labelValues {
+		b.WriteByte(',')
+		b.WriteString(v)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Get returns the state stored under key.
+func (s *StateStore) Get(key string) (SeriesState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.series[key]
+	return state, ok
+}
+
+// Set stores state under key.
+func (s *StateStore) Set(key string, state SeriesState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.series[key] = state
+	s.dirty = true
+}
+
+// Delete removes the state stored under key.
+func (s *StateStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.series[key]; ok {
+		delete(s.series, key)
+		s.dirty = true
+	}
+}
+
+// DeleteFunc removes the state stored under the keys starting with prefix
+// for which del returns true, and returns the number of keys removed.
+func (s *StateStore) DeleteFunc(prefix string, del func(key string, state SeriesState) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := 0
+	for key, state := range s.series {
+		if strings.HasPrefix(key, prefix) && del(key, state) {
+			delete(s.series, key)
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		s.dirty = true
+	}
+	return deleted
+}
+
+// Save writes the state to disk if it changed since the last Save. The file
+// is replaced atomically so a crash never leaves it half written.
+func (s *StateStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" || !s.dirty {
+		return nil
+	}
+	data, err := json.Marshal(s.series)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	s.dirty = false
+	return nil
+}
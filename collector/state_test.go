@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestStateStoreSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	s, err := OpenStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing is written until state changed.
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("got %v for the state file of an unchanged store, want it not to exist", err)
+	}
+
+	bucket := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.Set(SeriesKey("queries_total", "zone", "www.example.com"), SeriesState{Value: 3, LastBucket: bucket})
+	s.Set(SeriesKey("queries_total", "zone", "gone.example.com"), SeriesState{Value: 1, LastBucket: bucket})
+	s.Set(SeriesKey("queries_total", "other", "www.example.org"), SeriesState{Value: 2, LastBucket: bucket})
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, ok := s.Get(SeriesKey("queries_total", "zone", "www.example.com"))
+	if !ok || state.Value != 3 || !state.LastBucket.Equal(bucket) {
+		t.Errorf("got state %+v after reopening, want value 3 and last bucket %s", state, bucket)
+	}
+	deleted := s.DeleteFunc("queries_total{zone,", func(key string, state SeriesState) bool {
+		return state.Value < 2
+	})
+	if deleted != 1 {
+		t.Errorf("deleted %d series, want 1", deleted)
+	}
+	s.Delete(SeriesKey("queries_total", "other", "www.example.org"))
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.series) != 1 {
+		t.Errorf("got state of %d series after deleting, want 1: %v", len(s.series), s.series)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("got %d files next to the state file, want temporary files removed", len(files)-1)
+	}
+}
+
+func TestStateStoreCorrupt(t *testing.T) {
+	f, err := ioutil.TempFile("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("{")
+	f.Close()
+
+	if _, err := OpenStateStore(f.Name()); err == nil {
+		t.Error("expected an error for a state file that is not JSON")
+	}
+}
+
+// TestStateStoreRestart checks that DNS query counters continue from the
+// state file after a restart instead of starting again from zero.
+func TestStateStoreRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	env := newTestEnv(t)
+	defer env.Close()
+	zone := fakeZone(t, "enterprise")
+	// collect runs a DNS collector of a freshly started exporter and
+	// returns the value of each counter series.
+	collect := func() map[string]float64 {
+		state, err := OpenStateStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.opts.State = state
+		c := newDNSCollector(env.api, zone, env.opts)
+		reg := prometheus.NewPedanticRegistry()
+		f := zoneCollectFunc(c, zone)
+		reg.MustRegister(f)
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.err != nil {
+			t.Fatal(f.err)
+		}
+		if err := state.Save(); err != nil {
+			t.Fatal(err)
+		}
+		counters := map[string]float64{}
+		for _, family := range families {
+			for _, m := range family.GetMetric() {
+				if m.GetCounter() == nil {
+					continue
+				}
+				series := family.GetName()
+				for _, pair := range m.GetLabel() {
+					series += "," + pair.GetName() + "=" + pair.GetValue()
+				}
+				counters[series] = m.GetCounter().GetValue()
+			}
+		}
+		return counters
+	}
+
+	before := collect()
+	if len(before) == 0 {
+		t.Fatal("no counters collected")
+	}
+	// Stand in for queries counted from buckets the API no longer returns.
+	state, err := OpenStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, series := range state.series {
+		series.Value += 10
+		state.Set(key, series)
+	}
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+	// The fake API returns the same buckets again, which were already
+	// counted before the restart.
+	after := collect()
+	for series, value := range before {
+		if after[series] != value+10 {
+			t.Errorf("got %s %v after the restart, want %v", series, after[series], value+10)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("tiered_cache", newTieredCacheCollector)
+	RequireFeature("tiered_cache", FeatureProxied)
+}
+
+// tieredCacheQuery selects the requests a lower-tier PoP forwarded to an
+// upper-tier PoP instead of the origin.
+const tieredCacheQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: httpRequestsAdaptiveGroups(limit: 10000, filter: {datetime_geq: $since, datetime_lt: $until, upperTierColoName_neq: ""}) {
+        count
+        dimensions {
+          upperTierColoName
+          cacheStatus
+        }
+      }
+    }
+  }
+}`
+
+// tieredCacheCollector collects the requests going through upper-tier PoPs
+// with Tiered Cache or Argo Tiered Caching, by upper tier and cache status,
+// from the sampled GraphQL datasets. The hit ratio of each upper tier is how
+// many of the lower tiers' misses it kept from reaching the origin.
+type tieredCacheCollector struct {
+	gql      *GraphQLClient
+	enabled  bool
+	popNames bool
+	descs    []*prometheus.Desc
+
+	requests *prometheus.Desc
+	hitRatio *prometheus.Desc
+}
+
+func newTieredCacheCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		labels:      coloLabels(opts.PopNames),
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &tieredCacheCollector{gql: opts.GraphQL, enabled: opts.GraphQLTieredCache, popNames: opts.PopNames}
+	c.descs = descTable{
+		{&c.requests, metricDef{"tiered_cache", "sampled_requests", "Approximate number of requests forwarded to an upper-tier PoP in the last 5 minutes, by its cache status, from sampled data", []string{"cache_status"}}},
+		{&c.hitRatio, metricDef{"tiered_cache", "hit_ratio", "Share of requests forwarded to an upper-tier PoP in the last 5 minutes that it served from cache, from sampled data", nil}},
+	}.build(set)
+	return c
+}
+
+func (c *tieredCacheCollector) Name() string { return "tiered_cache" }
+
+func (c *tieredCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *tieredCacheCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if !c.enabled || c.gql == nil {
+		return nil
+	}
+	groups, err := c.gql.zoneGroups(ctx, zone.ID, tieredCacheQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get tiered cache analytics from cloudflare: %s", err)
+	}
+
+	requests := newLabelSum()
+	var tiers []string
+	totals := map[string]float64{}
+	hits := map[string]float64{}
+	for _, g := range groups {
+		tier := g.dimension("upperTierColoName")
+		if _, ok := totals[tier]; !ok {
+			tiers = append(tiers, tier)
+		}
+		totals[tier] += g.Count
+		if g.dimension("cacheStatus") == "hit" {
+			hits[tier] += g.Count
+		}
+		requests.add(g.Count, WithLabels(coloLabelValues(tier, c.popNames), g.dimension("cacheStatus"))...)
+	}
+	requests.collect(c.requests, ch)
+	for _, tier := range tiers {
+		ch <- prometheus.MustNewConstMetric(c.hitRatio, prometheus.GaugeValue, hits[tier]/totals[tier], coloLabelValues(tier, c.popNames)...)
+	}
+	return nil
+}
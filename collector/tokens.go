@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterAccount("api_tokens", newTokensCollector)
+}
+
+const tokensPerPage = 50
+
+// apiToken is an API token owned by an account.
+type apiToken struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	ExpiresOn *time.Time `json:"expires_on"`
+}
+
+// tokensCollector collects the account's API tokens, so that automation
+// credentials can be rotated before they expire.
+type tokensCollector struct {
+	cf    API
+	descs []*prometheus.Desc
+
+	tokens *prometheus.Desc
+	expiry *prometheus.Desc
+}
+
+func newTokensCollector(api API, account Account, opts Options) AccountCollector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: AccountLabels(account),
+	}
+	c := &tokensCollector{cf: api}
+	c.descs = descTable{
+		{&c.tokens, metricDef{"api", "tokens", "Number of API tokens owned by the account by status", []string{"status"}}},
+		{&c.expiry, metricDef{"api", "token_expiry_timestamp_seconds", "When an API token expires, for tokens with an expiry", []string{"token_id", "token_name"}}},
+	}.build(set)
+	return c
+}
+
+func (c *tokensCollector) Name() string { return "api_tokens" }
+
+func (c *tokensCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *tokensCollector) Collect(ctx context.Context, account Account, ch chan<- prometheus.Metric) error {
+	tokens := []apiToken{}
+	for page := 1; ; page++ {
+		raw, err := c.cf.Raw(http.MethodGet, fmt.Sprintf("/accounts/%s/tokens?page=%d&per_page=%d", account.ID, page, tokensPerPage), nil)
+		if err != nil {
+			return fmt.Errorf("failed to get api tokens from cloudflare: %s", err)
+		}
+		var result []apiToken
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return fmt.Errorf("failed to parse api tokens: %s", err)
+		}
+		tokens = append(tokens, result...)
+		if len(result) < tokensPerPage {
+			break
+		}
+	}
+
+	byStatus := map[string]int{}
+	for _, t := range tokens {
+		byStatus[t.Status]++
+		if t.ExpiresOn != nil && !t.ExpiresOn.IsZero() {
+			ch <- prometheus.MustNewConstMetric(c.expiry, prometheus.GaugeValue, float64(t.ExpiresOn.Unix()), t.ID, t.Name)
+		}
+	}
+	for status, count := range byStatus {
+		ch <- prometheus.MustNewConstMetric(c.tokens, prometheus.GaugeValue, float64(count), status)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("user_agents", newUserAgentsCollector)
+	RequireFeature("user_agents", FeatureProxied)
+}
+
+// userAgentsQuery selects the top user agent families, i.e. browsers or
+// clients regardless of their version; %d is the number of families.
+const userAgentsQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: httpRequestsAdaptiveGroups(limit: %d, orderBy: [count_DESC], filter: {datetime_geq: $since, datetime_lt: $until}) {
+        count
+        dimensions {
+          userAgentBrowser
+        }
+      }
+    }
+  }
+}`
+
+// userAgentsCollector collects requests broken out by the user agent family
+// of the client from the sampled GraphQL datasets, which points out scripted
+// clients and scrapers. Only the top families are exported to bound
+// cardinality.
+type userAgentsCollector struct {
+	gql   *GraphQLClient
+	top   int
+	descs []*prometheus.Desc
+
+	requests *prometheus.Desc
+}
+
+func newUserAgentsCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &userAgentsCollector{gql: opts.GraphQL, top: opts.GraphQLUserAgents}
+	c.descs = descTable{
+		{&c.requests, metricDef{"user_agent", "sampled_requests", "Approximate number of requests served in the last 5 minutes by user agent family, from sampled data", []string{"user_agent_family"}}},
+	}.build(set)
+	return c
+}
+
+func (c *userAgentsCollector) Name() string { return "user_agents" }
+
+func (c *userAgentsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *userAgentsCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if c.top <= 0 || c.gql == nil {
+		return nil
+	}
+	groups, err := c.gql.zoneGroups(ctx, zone.ID, fmt.Sprintf(userAgentsQuery, c.top))
+	if err != nil {
+		return fmt.Errorf("failed to get sampled user agent analytics from cloudflare: %s", err)
+	}
+	for _, g := range groups {
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.GaugeValue, g.Count, g.dimension("userAgentBrowser"))
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("workers", newWorkersCollector)
+	RequireFeature("workers", FeatureWorkers)
+}
+
+const workersSubrequestsQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: workersZoneSubrequestsAdaptiveGroups(limit: 10000, filter: {datetime_geq: $since, datetime_lt: $until}) {
+        count
+        dimensions {
+          scriptName
+          cacheStatus
+        }
+      }
+    }
+  }
+}`
+
+// workersCollector collects the subrequests Workers running on a zone make,
+// e.g. to origins or other APIs, by script and cache status, and the
+// zone's Workers routes.
+type workersCollector struct {
+	cf    API
+	gql   *GraphQLClient
+	descs []*prometheus.Desc
+
+	subrequests *prometheus.Desc
+	routes      *prometheus.Desc
+}
+
+func newWorkersCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &workersCollector{cf: api, gql: opts.GraphQL}
+	c.descs = descTable{
+		{&c.subrequests, metricDef{"workers", "subrequests", "Number of subrequests made by Workers in the last 5 minutes", []string{"script_name", "cache_status"}}},
+		{&c.routes, metricDef{"workers", "routes", "Number of Workers routes of the zone by script, an empty script for routes disabling Workers", []string{"script_name"}}},
+	}.build(set)
+	return c
+}
+
+func (c *workersCollector) Name() string { return "workers" }
+
+func (c *workersCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *workersCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	raw, err := c.cf.Raw(http.MethodGet, fmt.Sprintf("/zones/%s/workers/routes", zone.ID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get workers routes from cloudflare: %s", err)
+	}
+	var routes []struct {
+		Script string `json:"script"`
+	}
+	if err := json.Unmarshal(raw, &routes); err != nil {
+		return fmt.Errorf("failed to parse workers routes: %s", err)
+	}
+	byScript := map[string]int{}
+	for _, r := range routes {
+		byScript[r.Script]++
+	}
+	for script, count := range byScript {
+		ch <- prometheus.MustNewConstMetric(c.routes, prometheus.GaugeValue, float64(count), script)
+	}
+
+	if c.gql == nil {
+		return nil
+	}
+	groups, err := c.gql.zoneGroups(ctx, zone.ID, workersSubrequestsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get workers subrequests from cloudflare: %s", err)
+	}
+	for _, g := range groups {
+		ch <- prometheus.MustNewConstMetric(c.subrequests, prometheus.GaugeValue, g.Count, g.dimension("scriptName"), g.dimension("cacheStatus"))
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterAccount("workers_cron", newWorkersCronCollector)
+}
+
+// workersCronQuery selects the latest scheduled invocations of the
+// account's Workers, newest first.
+const workersCronQuery = `query ($accountTag: string, $since: Time) {
+  viewer {
+    accounts(filter: {accountTag: $accountTag}) {
+      invocations: workersInvocationsScheduled(limit: 1000, orderBy: [datetime_DESC], filter: {datetime_geq: $since}) {
+        scriptName
+        cron
+        status
+        datetime
+      }
+    }
+  }
+}`
+
+// workersCronWindow is how far back the last execution of a cron trigger is
+// looked for, enough for daily triggers.
+const workersCronWindow = 25 * time.Hour
+
+// workersCronCollector collects the cron triggers of the account's Workers
+// scripts and when each last ran and whether it succeeded, so that broken
+// scheduled Workers can be alerted on. It only runs with Options.WorkersCron,
+// as it takes a request per script.
+type workersCronCollector struct {
+	cf      API
+	gql     *GraphQLClient
+	enabled bool
+	descs   []*prometheus.Desc
+
+	triggers    *prometheus.Desc
+	lastRun     *prometheus.Desc
+	lastSuccess *prometheus.Desc
+}
+
+func newWorkersCronCollector(api API, account Account, opts Options) AccountCollector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: AccountLabels(account),
+	}
+	c := &workersCronCollector{cf: api, gql: opts.GraphQL, enabled: opts.WorkersCron}
+	c.descs = descTable{
+		{&c.triggers, metricDef{"workers", "cron_triggers", "Number of cron triggers of a Workers script", []string{"script_name"}}},
+		{&c.lastRun, metricDef{"workers", "cron_last_run_timestamp_seconds", "When a cron trigger last ran, if in the last 25 hours", []string{"script_name", "cron"}}},
+		{&c.lastSuccess, metricDef{"workers", "cron_last_run_success", "Whether the last run of a cron trigger succeeded, if in the last 25 hours", []string{"script_name", "cron"}}},
+	}.build(set)
+	return c
+}
+
+func (c *workersCronCollector) Name() string { return "workers_cron" }
+
+func (c *workersCronCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *workersCronCollector) Collect(ctx context.Context, account Account, ch chan<- prometheus.Metric) error {
+	if !c.enabled {
+		return nil
+	}
+	raw, err := c.cf.Raw(http.MethodGet, fmt.Sprintf("/accounts/%s/workers/scripts", account.ID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get workers scripts from cloudflare: %s", err)
+	}
+	var scripts []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &scripts); err != nil {
+		return fmt.Errorf("failed to parse workers scripts: %s", err)
+	}
+	for _, script := range scripts {
+		raw, err := c.cf.Raw(http.MethodGet, fmt.Sprintf("/accounts/%s/workers/scripts/%s/schedules", account.ID, url.PathEscape(script.ID)), nil)
+		if err != nil {
+			return fmt.Errorf("failed to get cron triggers of workers script %s from cloudflare: %s", script.ID, err)
+		}
+		var result struct {
+			Schedules []struct {
+				Cron string `json:"cron"`
+			} `json:"schedules"`
+		}
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return fmt.Errorf("failed to parse cron triggers of workers script %s: %s", script.ID, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.triggers, prometheus.GaugeValue, float64(len(result.Schedules)), script.ID)
+	}
+
+	if c.gql == nil {
+		return nil
+	}
+	var data struct {
+		Viewer struct {
+			Accounts []struct {
+				Invocations []struct {
+					ScriptName string    `json:"scriptName"`
+					Cron       string    `json:"cron"`
+					Status     string    `json:"status"`
+					Datetime   time.Time `json:"datetime"`
+				} `json:"invocations"`
+			} `json:"accounts"`
+		} `json:"viewer"`
+	}
+	variables := map[string]interface{}{
+		"accountTag": account.ID,
+		"since":      time.Now().UTC().Add(-workersCronWindow).Format(time.RFC3339),
+	}
+	if err := c.gql.Query(ctx, workersCronQuery, variables, &data); err != nil {
+		return fmt.Errorf("failed to get workers cron invocations from cloudflare: %s", err)
+	}
+	seen := map[[2]string]bool{}
+	for _, a := range data.Viewer.Accounts {
+		// Invocations are newest first, so the first of each trigger is
+		// its last run.
+		for _, inv := range a.Invocations {
+			key := [2]string{inv.ScriptName, inv.Cron}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			success := 0.0
+			if inv.Status == "success" {
+				success = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.lastRun, prometheus.GaugeValue, float64(inv.Datetime.Unix()), inv.ScriptName, inv.Cron)
+			ch <- prometheus.MustNewConstMetric(c.lastSuccess, prometheus.GaugeValue, success, inv.ScriptName, inv.Cron)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("zaraz", newZarazCollector)
+	RequireFeature("zaraz", FeatureProxied)
+}
+
+const zarazTriggersQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: zarazTriggersAdaptiveGroups(limit: 1000, filter: {datetime_geq: $since, datetime_lt: $until}) {
+        count
+        dimensions {
+          triggerName
+        }
+      }
+    }
+  }
+}`
+
+const zarazActionsQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      groups: zarazActionsAdaptiveGroups(limit: 1000, filter: {datetime_geq: $since, datetime_lt: $until}) {
+        count
+        dimensions {
+          toolName
+        }
+      }
+    }
+  }
+}`
+
+// zarazCollector collects how often Zaraz, Cloudflare's third-party script
+// manager, fires each trigger and loads each tool, from the sampled GraphQL
+// datasets. Zones without Zaraz export nothing.
+type zarazCollector struct {
+	gql     *GraphQLClient
+	enabled bool
+	descs   []*prometheus.Desc
+
+	triggers *prometheus.Desc
+	actions  *prometheus.Desc
+}
+
+func newZarazCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &zarazCollector{gql: opts.GraphQL, enabled: opts.GraphQLZaraz}
+	c.descs = descTable{
+		{&c.triggers, metricDef{"zaraz", "triggers", "Number of times each Zaraz trigger fired in the last 5 minutes, from sampled data", []string{"trigger"}}},
+		{&c.actions, metricDef{"zaraz", "tool_actions", "Number of actions, such as loading or sending an event, each Zaraz tool ran in the last 5 minutes, from sampled data", []string{"tool"}}},
+	}.build(set)
+	return c
+}
+
+func (c *zarazCollector) Name() string { return "zaraz" }
+
+func (c *zarazCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *zarazCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	if !c.enabled || c.gql == nil {
+		return nil
+	}
+	triggers, err := c.gql.zoneGroups(ctx, zone.ID, zarazTriggersQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get Zaraz trigger analytics from cloudflare: %s", err)
+	}
+	actions, err := c.gql.zoneGroups(ctx, zone.ID, zarazActionsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get Zaraz tool analytics from cloudflare: %s", err)
+	}
+	for _, g := range triggers {
+		ch <- prometheus.MustNewConstMetric(c.triggers, prometheus.GaugeValue, g.Count, g.dimension("triggerName"))
+	}
+	for _, g := range actions {
+		ch <- prometheus.MustNewConstMetric(c.actions, prometheus.GaugeValue, g.Count, g.dimension("toolName"))
+	}
+	return nil
+}
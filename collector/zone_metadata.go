@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/robbiet480/cloudflare-go"
+)
+
+// labelNamePattern matches valid Prometheus label names.
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedZoneLabels are the labels ZoneLabels sets itself, and the
+// variable labels of the metrics the exporter adds to those of the
+// collectors of each zone. The variable labels of the collectors are found
+// from their definitions, see variableLabels.
+var reservedZoneLabels = []string{"zone_id", "zone_name", "account_id", "account_name", "owner_id", "owner_name", "owner_email", "component", "host", "from_plan", "to_plan", "metric"}
+
+// ZoneMetadata holds labels such as team, service or tier to add to the
+// metrics of each zone, so alerts can be routed without joining with
+// another source.
+type ZoneMetadata struct {
+	// names are all label names, which every zone gets so that metrics
+	// have the same labels whatever the zone.
+	names []string
+	zones map[string]map[string]string
+}
+
+// LoadZoneMetadata reads zone labels from a JSON file mapping zone names or
+// IDs to labels, e.g. {"example.com": {"team": "web", "tier": "1"}}.
+func LoadZoneMetadata(path string) (*ZoneMetadata, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zone labels: %s", err)
+	}
+	m := &ZoneMetadata{}
+	if err := json.Unmarshal(data, &m.zones); err != nil {
+		return nil, fmt.Errorf("invalid zone labels %s: %s", path, err)
+	}
+	collectorLabels := variableLabels()
+	seen := map[string]bool{}
+	for zone, labels := range m.zones {
+		for name := range labels {
+			if !labelNamePattern.MatchString(name) || strings.HasPrefix(name, "__") {
+				return nil, fmt.Errorf("invalid label name %q for zone %s", name, zone)
+			}
+			if contains(reservedZoneLabels, name) {
+				return nil, fmt.Errorf("label %q for zone %s is set by the exporter", name, zone)
+			}
+			if collectorLabels[name] {
+				return nil, fmt.Errorf("label %q for zone %s is a label of collector metrics", name, zone)
+			}
+			if !seen[name] {
+				seen[name] = true
+				m.names = append(m.names, name)
+			}
+		}
+	}
+	return m, nil
+}
+
+// labels returns the labels of zone, looked up by name then ID. Labels the
+// zone lacks are empty, which Prometheus treats as unset.
+func (m *ZoneMetadata) labels(zone cloudflare.Zone) map[string]string {
+	zoneLabels, ok := m.zones[zone.Name]
+	if !ok {
+		zoneLabels = m.zones[zone.ID]
+	}
+	labels := make(map[string]string, len(m.names))
+	for _, name := range m.names {
+		labels[name] = zoneLabels[name]
+	}
+	return labels
+}
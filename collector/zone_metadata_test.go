@@ -0,0 +1,38 @@
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadZoneMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zone_metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		labels  string
+		wantErr string
+	}{
+		{`{"example.com": {"team": "web"}}`, ""},
+		{`{"example.com": {"zone_name": "web"}}`, "set by the exporter"},
+		{`{"example.com": {"component": "web"}}`, "set by the exporter"},
+		{`{"example.com": {"colo_id": "web"}}`, "label of collector metrics"},
+		{`{"example.com": {"status": "web"}}`, "label of collector metrics"},
+		{`{"example.com": {"continent": "web"}}`, "label of collector metrics"},
+	} {
+		path := filepath.Join(dir, "labels.json")
+		if err := ioutil.WriteFile(path, []byte(test.labels), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadZoneMetadata(path)
+		if test.wantErr == "" && err != nil || test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+			t.Errorf("labels %s: got error %v, want %q", test.labels, err, test.wantErr)
+		}
+	}
+}
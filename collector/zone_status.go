@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+func init() {
+	Register("zone_status", newZoneStatusCollector)
+}
+
+// zoneStatuses are the statuses a zone goes through. A zone is "pending"
+// until Cloudflare has verified that its nameservers were changed, and
+// "moved" once they were changed away again.
+var zoneStatuses = []string{"initializing", "pending", "active", "moved", "deactivated", "deleted"}
+
+// zoneHold is the hold on a zone, which prevents it from being added to
+// another account.
+type zoneHold struct {
+	Hold bool `json:"hold"`
+}
+
+// zoneStatusCollector collects the status of a zone, so that migrations in
+// progress and zones stuck waiting for nameserver changes can be alerted on.
+type zoneStatusCollector struct {
+	cf    API
+	descs []*prometheus.Desc
+
+	status *prometheus.Desc
+	paused *prometheus.Desc
+	hold   *prometheus.Desc
+}
+
+func newZoneStatusCollector(api API, zone cloudflare.Zone, opts Options) Collector {
+	set := descSet{
+		namespace:   Namespace,
+		constLabels: ZoneLabels(zone, opts),
+	}
+	c := &zoneStatusCollector{cf: api}
+	c.descs = descTable{
+		{&c.status, metricDef{"zone", "status", "Status of the zone, 1 for the current one. A pending zone is waiting for its nameservers to be verified", []string{"status"}}},
+		{&c.paused, metricDef{"zone", "paused", "Whether the zone is paused, i.e. serves DNS only", nil}},
+		{&c.hold, metricDef{"zone", "hold", "Whether the zone is on hold, which prevents adding it to another account", nil}},
+	}.build(set)
+	return c
+}
+
+func (c *zoneStatusCollector) Name() string { return "zone_status" }
+
+func (c *zoneStatusCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *zoneStatusCollector) Collect(ctx context.Context, zone cloudflare.Zone, ch chan<- prometheus.Metric) error {
+	details, err := c.cf.ZoneDetails(zone.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get zone details from cloudflare: %s", err)
+	}
+
+	statuses := zoneStatuses
+	if !contains(statuses, details.Status) {
+		statuses = WithLabels(statuses, details.Status)
+	}
+	for _, status := range statuses {
+		ch <- prometheus.MustNewConstMetric(c.status, prometheus.GaugeValue, boolToFloat(status == details.Status), status)
+	}
+	ch <- prometheus.MustNewConstMetric(c.paused, prometheus.GaugeValue, boolToFloat(details.Paused))
+
+	raw, err := c.cf.Raw(http.MethodGet, "/zones/"+zone.ID+"/hold", nil)
+	if err != nil {
+		return fmt.Errorf("failed to get zone hold from cloudflare: %s", err)
+	}
+	var hold zoneHold
+	if err := json.Unmarshal(raw, &hold); err != nil {
+		return fmt.Errorf("failed to parse zone hold: %s", err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.hold, prometheus.GaugeValue, boolToFloat(hold.Hold))
+	return nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
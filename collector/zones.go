@@ -0,0 +1,38 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/robbiet480/cloudflare-go"
+)
+
+// zonesPerPage is the page size for listing all zones, the most the API
+// allows.
+const zonesPerPage = 50
+
+// listZones returns the zones named z, or all zones of the credentials if
+// none are named. The pinned cloudflare-go only returns the first page of
+// zones, so all zones are listed page by page, lest accounts with more than
+// a page of zones silently miss some.
+func listZones(api API, z ...string) ([]cloudflare.Zone, error) {
+	if len(z) > 0 {
+		return api.ListZones(z...)
+	}
+	zones := []cloudflare.Zone{}
+	for page := 1; ; page++ {
+		raw, err := api.Raw(http.MethodGet, fmt.Sprintf("/zones?page=%d&per_page=%d", page, zonesPerPage), nil)
+		if err != nil {
+			return nil, err
+		}
+		var result []cloudflare.Zone
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse zones: %s", err)
+		}
+		zones = append(zones, result...)
+		if len(result) < zonesPerPage {
+			return zones, nil
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/internal/fakeapi"
+)
+
+// manyZones returns n zones with distinct IDs and names.
+func manyZones(n int) []cloudflare.Zone {
+	zones := make([]cloudflare.Zone, n)
+	for i := range zones {
+		zones[i] = cloudflare.Zone{ID: fmt.Sprintf("%032x", i), Name: fmt.Sprintf("zone%d.example.com", i)}
+	}
+	return zones
+}
+
+// zoneListAPI returns an API listing the zones of list, with the server to
+// Close.
+func zoneListAPI(t *testing.T, list fakeapi.ZoneList) (API, func()) {
+	server := fakeapi.NewZoneListServer(list)
+	// Server errors are not retried, which would take seconds.
+	api, err := cloudflare.New("fake", "fake@example.com", cloudflare.UsingRateLimit(1000), cloudflare.UsingRetryPolicy(0, 0, 0))
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	api.BaseURL = server.URL + fakeapi.APIPrefix
+	return api, server.Close
+}
+
+func TestListZonesPages(t *testing.T) {
+	// 120 zones take a short last page, 100 an empty one.
+	for _, n := range []int{120, 2 * zonesPerPage, 3} {
+		zones := manyZones(n)
+		api, done := zoneListAPI(t, fakeapi.ZoneList{Zones: zones})
+		listed, err := listZones(api)
+		done()
+		if err != nil {
+			t.Fatalf("%d zones: %s", n, err)
+		}
+		if len(listed) != n {
+			t.Fatalf("got %d zones, want %d", len(listed), n)
+		}
+		for i, zone := range listed {
+			if zone.ID != zones[i].ID {
+				t.Fatalf("%d zones: got zone %s at %d, want %s", n, zone.ID, i, zones[i].ID)
+			}
+		}
+	}
+}
+
+func TestListZonesPageError(t *testing.T) {
+	api, done := zoneListAPI(t, fakeapi.ZoneList{Zones: manyZones(120), FailPage: 2})
+	defer done()
+	if zones, err := listZones(api); err == nil {
+		t.Errorf("got %d zones, want the error of the second page", len(zones))
+	}
+}
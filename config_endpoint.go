@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// secretFlags are redacted from the config endpoint.
+var secretFlags = map[string]bool{
+	"cloudflare.api-key":       true,
+	"cloudflare.origin-ca-key": true,
+	"cloudflare.auth-header":   true,
+	"web.webhook-secret":       true,
+	"web.maintenance-token":    true,
+}
+
+const secretValue = "<secret>"
+
+// urlFlags hold URLs whose password is redacted from the config endpoint.
+var urlFlags = map[string]bool{
+	"cloudflare.api-url":           true,
+	"cloudflare.proxy-url":         true,
+	"status.proxy-url":             true,
+	"web.webhook-alertmanager-url": true,
+}
+
+// redactURL replaces the password in raw, if any.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); !ok {
+		return raw
+	}
+	u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	return u.String()
+}
+
+// configHandler serves the effective value of every flag as JSON, whether it
+// was set on the command line, from the environment or left at its default.
+func configHandler(app *kingpin.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := map[string]string{}
+		for _, flag := range app.Model().Flags {
+			if flag.Name == "help" || flag.Name == "version" || flag.Value == nil {
+				continue
+			}
+			value := flag.Value.String()
+			if secretFlags[flag.Name] && value != "" {
+				value = secretValue
+			}
+			if urlFlags[flag.Name] {
+				value = redactURL(value)
+			}
+			config[flag.Name] = value
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(config)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+const firewallEventsQuery = `query ($zoneTag: string, $since: Time, $limit: uint64) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      events: firewallEventsAdaptive(limit: $limit, orderBy: [datetime_DESC], filter: {datetime_geq: $since}) {
+        datetime
+        action
+        source
+        ruleId
+        rayName
+        clientIP
+        clientCountryName
+        clientASNDescription
+        clientRequestHTTPMethodName
+        clientRequestHTTPHost
+        clientRequestPath
+        clientRequestQuery
+        userAgent
+      }
+    }
+  }
+}`
+
+// Events are kept for firewallEventsTTL, so on-call engineers refreshing the
+// endpoint during an attack do not add to the API load. Queries default to
+// the last firewallEventsSince and return at most firewallEventsMaxLimit
+// events.
+const (
+	firewallEventsTTL          = 30 * time.Second
+	firewallEventsSince        = time.Hour
+	firewallEventsDefaultLimit = 100
+	firewallEventsMaxLimit     = 1000
+)
+
+// firewallEventsPrefix is the path the endpoint is served under, followed by
+// <zone name or ID>/firewall-events.
+const firewallEventsPrefix = "/api/v1/zones/"
+
+type firewallEventsEntry struct {
+	fetched time.Time
+	events  []json.RawMessage
+}
+
+// firewallEventsHandler serves the most recent sampled firewall events of a
+// zone as JSON, for grabbing examples during an attack without logging into
+// the dashboard.
+type firewallEventsHandler struct {
+	gql   *collector.GraphQLClient
+	zones map[string]cloudflare.Zone
+
+	mu    sync.Mutex
+	cache map[string]firewallEventsEntry
+}
+
+// newFirewallEventsHandler returns a handler for the events of zones, which
+// can be selected by name or ID.
+func newFirewallEventsHandler(gql *collector.GraphQLClient, zones []cloudflare.Zone) *firewallEventsHandler {
+	h := &firewallEventsHandler{
+		gql:   gql,
+		zones: make(map[string]cloudflare.Zone, 2*len(zones)),
+		cache: map[string]firewallEventsEntry{},
+	}
+	for _, zone := range zones {
+		h.zones[zone.Name] = zone
+		h.zones[zone.ID] = zone
+	}
+	return h
+}
+
+func (h *firewallEventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, firewallEventsPrefix), "/")
+	if len(parts) != 2 || parts[1] != "firewall-events" {
+		http.NotFound(w, r)
+		return
+	}
+	zone, ok := h.zones[parts[0]]
+	if !ok {
+		http.Error(w, fmt.Sprintf("zone %q is not monitored", parts[0]), http.StatusNotFound)
+		return
+	}
+	since, err := parseSince(r.URL.Query().Get("since"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := firewallEventsDefaultLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > firewallEventsMaxLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", firewallEventsMaxLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := h.events(r, zone, since, limit)
+	if err != nil {
+		log.Errorf("failed to get firewall events of zone %s: %s", zone.Name, err)
+		http.Error(w, "failed to get firewall events from Cloudflare", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(events)
+}
+
+// events returns the events of zone since since, from the cache if they
+// were fetched within firewallEventsTTL. since is truncated to the minute
+// so that repeated requests for a relative time share a cache entry.
+func (h *firewallEventsHandler) events(r *http.Request, zone cloudflare.Zone, since time.Time, limit int) ([]json.RawMessage, error) {
+	since = since.UTC().Truncate(time.Minute)
+	key := fmt.Sprintf("%s/%s/%d", zone.ID, since.Format(time.RFC3339), limit)
+	h.mu.Lock()
+	entry, ok := h.cache[key]
+	for k, e := range h.cache {
+		if time.Since(e.fetched) > firewallEventsTTL {
+			delete(h.cache, k)
+		}
+	}
+	h.mu.Unlock()
+	if ok && time.Since(entry.fetched) <= firewallEventsTTL {
+		return entry.events, nil
+	}
+
+	var data struct {
+		Viewer struct {
+			Zones []struct {
+				Events []json.RawMessage `json:"events"`
+			} `json:"zones"`
+		} `json:"viewer"`
+	}
+	variables := map[string]interface{}{
+		"zoneTag": zone.ID,
+		"since":   since.Format(time.RFC3339),
+		"limit":   limit,
+	}
+	if err := h.gql.Query(r.Context(), firewallEventsQuery, variables, &data); err != nil {
+		return nil, err
+	}
+	events := []json.RawMessage{}
+	for _, z := range data.Viewer.Zones {
+		events = append(events, z.Events...)
+	}
+
+	h.mu.Lock()
+	h.cache[key] = firewallEventsEntry{fetched: time.Now(), events: events}
+	h.mu.Unlock()
+	return events, nil
+}
+
+// parseSince parses the since parameter, either a duration before now such
+// as 15m or an RFC 3339 timestamp. It defaults to firewallEventsSince ago.
+func parseSince(raw string, now time.Time) (time.Time, error) {
+	if raw == "" {
+		return now.Add(-firewallEventsSince), nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("since must be a duration such as 15m or an RFC 3339 timestamp")
+	}
+	return t, nil
+}
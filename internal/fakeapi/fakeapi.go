@@ -0,0 +1,555 @@
+// Package fakeapi serves canned Cloudflare API and status page responses so
+// the exporter can be exercised without network access or credentials.
+package fakeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robbiet480/cloudflare-go"
+)
+
+// APIPrefix is the path the Cloudflare v4 API is served under. Point a
+// cloudflare.API's BaseURL at the server URL plus this prefix.
+const APIPrefix = "/client/v4"
+
+// StatusPath is the path the status page summary is served under.
+const StatusPath = "/api/v2/summary.json"
+
+// Zones served by the fake API, one per plan so that every plan-specific
+// code path is covered.
+var Zones = []cloudflare.Zone{
+	newZone("0000000000000000000000000000free", "free.example.com", "free"),
+	newZone("00000000000000000000000000000pro", "pro.example.com", "pro"),
+	newZone("000000000000000000000000business", "business.example.com", "business"),
+	newZone("0000000000000000000000enterprise", "enterprise.example.com", "enterprise"),
+}
+
+// Colos are the colo IDs returned for analytics broken out by PoP. SJC-PIG
+// is included on purpose as it needs PoP normalization.
+var Colos = []string{"SJC", "SJC-PIG", "AMS"}
+
+// Now is the timestamp used for every analytics timeseries entry.
+var Now = time.Date(2018, 9, 1, 12, 0, 0, 0, time.UTC)
+
+func newZone(id, name, plan string) cloudflare.Zone {
+	zone := cloudflare.Zone{
+		ID:     id,
+		Name:   name,
+		Status: "active",
+		Type:   "full",
+	}
+	zone.Account.ID = "01a7362d577a6c3019a474fd6f485823"
+	zone.Account.Name = "Example Account"
+	zone.Owner.ID = "7c5dae5552338874e5053f2534d2767a"
+	zone.Owner.Email = "user@example.com"
+	zone.Owner.Type = "user"
+	zone.Plan.ID = plan
+	zone.Plan.Name = strings.Title(plan) + " Website"
+	zone.Plan.LegacyID = plan
+	return zone
+}
+
+// NewServer starts a server running Handler. Callers must Close it.
+func NewServer() *httptest.Server {
+	return httptest.NewServer(Handler())
+}
+
+// Handler returns a handler serving the fake Cloudflare API under APIPrefix
+// and the status page summary under StatusPath.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(APIPrefix+"/zones", ZoneList{Zones: Zones})
+	mux.HandleFunc(APIPrefix+"/zones/", serveZone)
+	mux.HandleFunc(APIPrefix+"/accounts/", serveAccount)
+	mux.HandleFunc(APIPrefix+"/certificates", serveCertificates)
+	mux.HandleFunc(APIPrefix+"/graphql", serveGraphQL)
+	mux.HandleFunc(StatusPath, serveStatus)
+	return mux
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"errors":   []interface{}{},
+		"messages": []interface{}{},
+		"result":   result,
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(cloudflare.Response{
+		Success: false,
+		Errors:  []cloudflare.ResponseInfo{{Code: code, Message: message}},
+	})
+}
+
+func findZone(id string) (cloudflare.Zone, bool) {
+	for _, zone := range Zones {
+		if zone.ID == id {
+			return zone, true
+		}
+	}
+	return cloudflare.Zone{}, false
+}
+
+// ZoneList serves the zone list, paginated as by the API: per_page zones,
+// 20 by default, on each page.
+type ZoneList struct {
+	Zones []cloudflare.Zone
+	// FailPage is a page answered with a server error, if not zero.
+	FailPage int
+}
+
+// NewZoneListServer starts a server serving list under APIPrefix+"/zones".
+// Callers must Close it.
+func NewZoneListServer(list ZoneList) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle(APIPrefix+"/zones", list)
+	return httptest.NewServer(mux)
+}
+
+func (l ZoneList) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, perPage := 1, 20
+	if n, err := strconv.Atoi(query.Get("page")); err == nil && n > 0 {
+		page = n
+	}
+	if n, err := strconv.Atoi(query.Get("per_page")); err == nil && n > 0 {
+		perPage = n
+	}
+	if page == l.FailPage {
+		writeError(w, http.StatusInternalServerError, 1000, "Internal server error")
+		return
+	}
+
+	name := query.Get("name")
+	zones := []cloudflare.Zone{}
+	for _, zone := range l.Zones {
+		if name == "" || zone.Name == name {
+			zones = append(zones, zone)
+		}
+	}
+	start, end := (page-1)*perPage, page*perPage
+	if start > len(zones) {
+		start = len(zones)
+	}
+	if end > len(zones) {
+		end = len(zones)
+	}
+	writeResult(w, zones[start:end])
+}
+
+func serveZone(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, APIPrefix+"/zones/"), "/", 2)
+	zone, ok := findZone(parts[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, 1001, "Invalid zone identifier")
+		return
+	}
+	if len(parts) == 1 {
+		writeResult(w, zone)
+		return
+	}
+
+	switch parts[1] {
+	case "analytics/dashboard":
+		writeResult(w, AnalyticsData(""))
+	case "analytics/colos":
+		if zone.Plan.LegacyID != "enterprise" {
+			writeError(w, http.StatusBadRequest, 1015, "Your plan does not allow analytics by colo")
+			return
+		}
+		data := []cloudflare.ZoneAnalyticsData{}
+		for _, colo := range Colos {
+			data = append(data, AnalyticsData(colo))
+		}
+		writeResult(w, data)
+	case "argo/smart_routing":
+		if zone.Plan.LegacyID == "free" {
+			writeError(w, http.StatusForbidden, 1013, "Argo is not available on this plan")
+			return
+		}
+		writeResult(w, map[string]interface{}{"id": "smart_routing", "value": "on", "editable": true})
+	case "load_balancers", "spectrum/apps":
+		writeResult(w, []interface{}{})
+	case "workers/routes":
+		writeResult(w, []map[string]interface{}{{"id": "9a7806061c88ada191ed06f989cc3dac", "pattern": zone.Name + "/api/*", "script": "api-gateway"}})
+	case "dns_records":
+		records := []cloudflare.DNSRecord{}
+		for _, record := range DNSRecords(zone) {
+			if proxied := r.URL.Query().Get("proxied"); proxied == "" || proxied == fmt.Sprint(record.Proxied) {
+				records = append(records, record)
+			}
+		}
+		writeResult(w, records)
+	case "addressing/regional_hostnames":
+		// Only the enterprise zone keeps its hostname to a region.
+		hostnames := []map[string]interface{}{}
+		if zone.Plan.LegacyID == "enterprise" {
+			hostnames = append(hostnames, map[string]interface{}{"hostname": zone.Name, "region_key": "eu"})
+		}
+		writeResult(w, hostnames)
+	case "hold":
+		writeResult(w, map[string]interface{}{"hold": false, "include_subdomains": false})
+	case "settings/always_online":
+		writeResult(w, map[string]interface{}{"id": "always_online", "value": "on", "editable": true})
+	case "ssl/certificate_packs":
+		writeResult(w, []map[string]interface{}{{
+			"id":                    "3822ff90-ea29-44df-9e55-21300bb9419b",
+			"type":                  "universal",
+			"hosts":                 []string{zone.Name, "*." + zone.Name},
+			"status":                "active",
+			"certificate_authority": "lets_encrypt",
+			"certificates":          []map[string]interface{}{{"id": "7e7b8deba8538af625850b7b2530034c", "expires_on": "2030-01-01T00:00:00Z"}},
+		}})
+	case "ssl/recommendation":
+		writeResult(w, map[string]interface{}{"id": "ssl_recommendation", "value": "strict"})
+	case "rulesets":
+		listed := []map[string]interface{}{}
+		for _, rs := range Rulesets {
+			summary := map[string]interface{}{}
+			for k, v := range rs {
+				if k != "rules" {
+					summary[k] = v
+				}
+			}
+			listed = append(listed, summary)
+		}
+		writeResult(w, listed)
+	case "dns_analytics/report/bytime":
+		query := r.URL.Query()
+		// The free zone rejects the dimensions of richer plans, while the
+		// pro zone allows them.
+		if dims := query.Get("dimensions"); zone.Plan.LegacyID == "free" && (strings.Contains(dims, "queryType") || strings.Contains(dims, "responseCached")) {
+			writeError(w, http.StatusBadRequest, 1004, "Invalid dimensions for this plan")
+			return
+		}
+		writeResult(w, dnsAnalyticsData(strings.Split(query.Get("dimensions"), ","), strings.Split(query.Get("metrics"), ",")))
+	default:
+		for _, rs := range Rulesets {
+			if parts[1] == "rulesets/"+rs["id"].(string) {
+				writeResult(w, rs)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, 7000, "No route for that URI")
+	}
+}
+
+// DNSRecords returns the DNS records of zone. The free zone is DNS-only,
+// i.e. has no proxied records.
+func DNSRecords(zone cloudflare.Zone) []cloudflare.DNSRecord {
+	return []cloudflare.DNSRecord{
+		{ID: "372e67954025e0ba6aaa6d586b9e0b59", Type: "A", Name: zone.Name, Content: "198.51.100.4", Proxiable: true, Proxied: zone.Plan.LegacyID != "free", TTL: 1, ZoneID: zone.ID, ZoneName: zone.Name},
+	}
+}
+
+// Rulesets are the rulesets available to every zone, with their rules. The
+// rule IDs match those of the canned firewall events, and the zone's own
+// ruleset deploys the Cloudflare Managed Ruleset.
+var Rulesets = []map[string]interface{}{
+	{
+		"id": "4d21379b4f9f4bb088e0729962c8b3cf", "name": "Cloudflare L7 DDoS Ruleset", "kind": "managed", "phase": "ddos_l7", "version": "1287", "last_updated": Now.Add(-72 * time.Hour),
+		"rules": []map[string]interface{}{
+			{"id": "fdfdac75430c4c47a959592f0aa5e68a", "description": "HTTP requests with unusual HTTP headers or URI path (signature #11)", "action": "block"},
+			{"id": "2e6d8b62a3bd4b4e9b5e2b3d2c77a4bd", "description": "HTTP requests from known botnet (signature #6)", "action": "managed_challenge"},
+		},
+	},
+	{
+		"id": "efb7b8c949ac4650a09736fc376e9aee", "name": "Cloudflare Managed Ruleset", "kind": "managed", "phase": "http_request_firewall_managed", "version": "52", "last_updated": Now.Add(-24 * time.Hour),
+		"rules": []map[string]interface{}{
+			{"id": "5de7edfa648c4d6891dc3e7f84534ffa", "description": "Apache Struts - Remote Code Execution - CVE:CVE-2017-5638", "action": "block"},
+		},
+	},
+	{
+		"id": "4814384a9e5d4991b9815dcfc25d2f1f", "name": "Cloudflare OWASP Core Ruleset", "kind": "managed", "phase": "http_request_firewall_managed", "version": "38", "last_updated": Now.Add(-48 * time.Hour),
+		"rules": []map[string]interface{}{
+			{"id": "6179ae15870a4bb7b2d480d4843b323c", "description": "949110: Inbound Anomaly Score Exceeded", "action": "block"},
+		},
+	},
+	{
+		"id": "1a3b5c7d9e0f4a2b8c6d4e2f0a1b3c5d", "name": "default", "kind": "zone", "phase": "http_request_firewall_managed", "version": "3", "last_updated": Now.Add(-96 * time.Hour),
+		"rules": []map[string]interface{}{
+			{"id": "0c1d2e3f4a5b4c6d8e7f9a0b1c2d3e4f", "description": "Deploy Cloudflare Managed Ruleset", "action": "execute", "enabled": true, "action_parameters": map[string]interface{}{"id": "efb7b8c949ac4650a09736fc376e9aee"}},
+		},
+	},
+}
+
+// AnalyticsData returns the dashboard analytics served for colo, or for the
+// whole zone if colo is empty.
+func AnalyticsData(colo string) cloudflare.ZoneAnalyticsData {
+	entry := cloudflare.ZoneAnalytics{
+		Since: Now.Add(-time.Minute),
+		Until: Now,
+	}
+	entry.Requests.All = 1250
+	entry.Requests.Cached = 1000
+	entry.Requests.Uncached = 250
+	entry.Requests.SSL.Encrypted = 1200
+	entry.Requests.SSL.Unencrypted = 50
+	entry.Requests.HTTPStatus = map[string]int{"200": 1200, "404": 50}
+	entry.Requests.ContentType = map[string]int{"html": 250, "png": 1000}
+	entry.Requests.Country = map[string]int{"US": 1000, "NL": 250}
+	entry.Requests.IPClass = map[string]int{"unknown": 1200, "searchEngine": 50}
+	entry.Bandwidth.All = 512000
+	entry.Bandwidth.Cached = 384000
+	entry.Bandwidth.Uncached = 128000
+	entry.Bandwidth.SSL.Encrypted = 500000
+	entry.Bandwidth.SSL.Unencrypted = 12000
+	entry.Bandwidth.ContentType = map[string]int{"html": 128000, "png": 384000}
+	entry.Bandwidth.Country = map[string]int{"US": 400000, "NL": 112000}
+	entry.Threats.All = 5
+	entry.Threats.Type = map[string]int{"user.ban.ip": 5}
+	entry.Threats.Country = map[string]int{"CN": 5}
+	entry.Pageviews.All = 250
+	entry.Pageviews.SearchEngines = map[string]int{"googlebot": 50}
+	entry.Uniques.All = 75
+
+	return cloudflare.ZoneAnalyticsData{
+		Totals:       entry,
+		Timeseries:   []cloudflare.ZoneAnalytics{entry},
+		ColocationID: colo,
+	}
+}
+
+func serveCertificates(w http.ResponseWriter, r *http.Request) {
+	zone, ok := findZone(r.URL.Query().Get("zone_id"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, 1001, "Invalid zone identifier")
+		return
+	}
+	writeResult(w, []cloudflare.OriginCACertificate{
+		{
+			ID:          "328578533902268680212849205732770752308931942346",
+			Hostnames:   []string{zone.Name, "*." + zone.Name},
+			ExpiresOn:   Now.Add(90 * 24 * time.Hour),
+			RequestType: "origin-rsa",
+		},
+	})
+}
+
+// Members are the members of the account owning Zones.
+var Members = []cloudflare.AccountMember{
+	{
+		ID:     "4536bcfad5faccb111b47003c79917fa",
+		User:   cloudflare.AccountMemberUserDetails{ID: "7c5dae5552338874e5053f2534d2767a", Email: "user@example.com"},
+		Status: "accepted",
+		Roles:  []cloudflare.AccountRole{{ID: "3536bcfad5faccb999b47003c79917fb", Name: "Administrator"}},
+	},
+	{
+		ID:     "5a7805061c76ada191ed06f989cc3dac",
+		User:   cloudflare.AccountMemberUserDetails{ID: "9a7806061c88ada191ed06f989cc3dac", Email: "ops@example.com"},
+		Status: "accepted",
+		Roles:  []cloudflare.AccountRole{{ID: "05784afa30c1afe1440e79d9351c7430", Name: "Analytics"}, {ID: "3536bcfad5faccb999b47003c79917fb", Name: "Administrator"}},
+	},
+	{
+		ID:     "6b8916172d87beb202fe17fa09dd4ebd",
+		User:   cloudflare.AccountMemberUserDetails{Email: "new@example.com"},
+		Status: "pending",
+		Roles:  []cloudflare.AccountRole{{ID: "05784afa30c1afe1440e79d9351c7430", Name: "Analytics"}},
+	},
+}
+
+// Tokens are the API tokens owned by the account owning Zones.
+var Tokens = []map[string]interface{}{
+	{"id": "ed17574386854bf78a67040be0a770b0", "name": "terraform", "status": "active", "expires_on": Now.Add(30 * 24 * time.Hour)},
+	{"id": "a2b0c1f4e2d14b6e8a4f3c7d9e8b1a22", "name": "ci-readonly", "status": "active"},
+	{"id": "c3d4e5f6a7b84c9d8e7f6a5b4c3d2e1f", "name": "old-deploy", "status": "expired", "expires_on": Now.Add(-24 * time.Hour)},
+}
+
+func serveAccount(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, APIPrefix+"/accounts/"), "/", 2)
+	if parts[0] != Zones[0].Account.ID {
+		writeError(w, http.StatusNotFound, 1003, "Invalid account identifier")
+		return
+	}
+	if len(parts) == 1 {
+		writeError(w, http.StatusNotFound, 7000, "No route for that URI")
+		return
+	}
+
+	switch parts[1] {
+	case "members":
+		writeResult(w, Members)
+	case "tokens":
+		writeResult(w, Tokens)
+	default:
+		writeError(w, http.StatusNotFound, 7000, "No route for that URI")
+	}
+}
+
+// dnsDimensionValues holds the value returned for every supported dimension.
+var dnsDimensionValues = map[string]string{
+	"queryName":      "www.example.com",
+	"queryType":      "A",
+	"responseCode":   "NOERROR",
+	"responseCached": "Cached",
+	"origin":         "false",
+	"tcp":            "false",
+	"ipVersion":      "4",
+	"coloName":       "SJC-PIG",
+}
+
+// dnsMetricValues holds the two time buckets returned for every supported
+// metric.
+var dnsMetricValues = map[string][]float64{
+	"queryCount":         {40, 42},
+	"uncachedCount":      {4, 5},
+	"staleCount":         {0, 1},
+	"responseTimeAvg":    {3.2, 3.5},
+	"responseTimeMedian": {2, 2},
+	"responseTime90th":   {8, 9},
+	"responseTime99th":   {21, 24},
+}
+
+func dnsAnalyticsData(dimensions, metrics []string) cloudflare.ZoneDNSAnalyticsByTimeData {
+	values := make([]string, 0, len(dimensions))
+	for _, dimension := range dimensions {
+		values = append(values, dnsDimensionValues[dimension])
+	}
+	series := make([][]float64, 0, len(metrics))
+	for _, metric := range metrics {
+		series = append(series, dnsMetricValues[metric])
+	}
+	return cloudflare.ZoneDNSAnalyticsByTimeData{
+		Rows: []cloudflare.ZoneDNSAnalyticsByTimeRow{
+			{
+				Dimensions: values,
+				Metrics:    series,
+			},
+		},
+		RowCount:      1,
+		TimeIntervals: [][]time.Time{{Now.Add(-2 * time.Minute), Now.Add(-time.Minute)}, {Now.Add(-time.Minute), Now}},
+	}
+}
+
+// graphQLGroups holds the groups returned for each supported GraphQL
+// dataset. Queries of the same dataset for different dimensions are told
+// apart by keying their groups on the dimension instead.
+var graphQLGroups = map[string][]map[string]interface{}{
+	"httpRequestsAdaptiveGroups": {
+		{"count": 900, "sum": map[string]interface{}{"edgeResponseBytes": 368000}, "dimensions": map[string]interface{}{"coloCode": "SJC"}},
+		{"count": 100, "sum": map[string]interface{}{"edgeResponseBytes": 40000}, "dimensions": map[string]interface{}{"coloCode": "SJC-PIG"}},
+		{"count": 250, "sum": map[string]interface{}{"edgeResponseBytes": 104000}, "dimensions": map[string]interface{}{"coloCode": "AMS"}},
+	},
+	"clientRefererHost": {
+		{"count": 700, "dimensions": map[string]interface{}{"clientRefererHost": ""}},
+		{"count": 320, "dimensions": map[string]interface{}{"clientRefererHost": "www.google.com"}},
+		{"count": 85, "dimensions": map[string]interface{}{"clientRefererHost": "news.ycombinator.com"}},
+	},
+	"clientASNDescription": {
+		{"count": 900, "dimensions": map[string]interface{}{"clientAsn": "7922", "clientASNDescription": "COMCAST-7922"}},
+		{"count": 150, "dimensions": map[string]interface{}{"clientAsn": "16509", "clientASNDescription": "AMAZON-02"}},
+	},
+	"userAgentBrowser": {
+		{"count": 800, "dimensions": map[string]interface{}{"userAgentBrowser": "Chrome"}},
+		{"count": 200, "dimensions": map[string]interface{}{"userAgentBrowser": "Safari"}},
+		{"count": 90, "dimensions": map[string]interface{}{"userAgentBrowser": "curl"}},
+	},
+	"edgeResponseStatus": {
+		{"count": 14, "dimensions": map[string]interface{}{"edgeResponseStatus": 521}},
+		{"count": 3, "dimensions": map[string]interface{}{"edgeResponseStatus": 524}},
+	},
+	"zarazTriggersAdaptiveGroups": {
+		{"count": 640, "dimensions": map[string]interface{}{"triggerName": "Pageview"}},
+	},
+	"zarazActionsAdaptiveGroups": {
+		{"count": 610, "dimensions": map[string]interface{}{"toolName": "Google Analytics 4"}},
+	},
+	"dnsAnalyticsAdaptiveGroups": {
+		{"count": 82, "dimensions": map[string]interface{}{"responseCode": "NOERROR", "coloName": "SJC-PIG"}},
+		{"count": 6, "dimensions": map[string]interface{}{"responseCode": "NXDOMAIN", "coloName": "AMS"}},
+	},
+	"aiInferenceAdaptiveGroups": {
+		{"count": 30, "sum": map[string]interface{}{"totalInputTokens": 4200, "totalOutputTokens": 9100}, "dimensions": map[string]interface{}{"modelId": "@cf/meta/llama-3-8b-instruct"}},
+	},
+	"aiGatewayRequestsAdaptiveGroups": {
+		{"count": 25, "dimensions": map[string]interface{}{"gateway": "default", "provider": "openai", "model": "gpt-4o-mini", "cached": false}},
+		{"count": 5, "dimensions": map[string]interface{}{"gateway": "default", "provider": "openai", "model": "gpt-4o-mini", "cached": true}},
+	},
+	"workersZoneSubrequestsAdaptiveGroups": {
+		{"count": 500, "dimensions": map[string]interface{}{"scriptName": "api-gateway", "cacheStatus": "hit"}},
+		{"count": 120, "dimensions": map[string]interface{}{"scriptName": "api-gateway", "cacheStatus": "miss"}},
+	},
+	"gatewayResolverQueriesAdaptiveGroups": {
+		{"count": 4000, "dimensions": map[string]interface{}{"locationName": "HQ", "protocol": "https", "resolverDecision": "allowedOnNoPolicyMatch", "categoryNames": []string{"Technology"}}},
+		{"count": 35, "dimensions": map[string]interface{}{"locationName": "HQ", "protocol": "https", "resolverDecision": "blockedByCategory", "categoryNames": []string{"Malware", "Phishing"}}},
+		{"count": 12, "dimensions": map[string]interface{}{"locationName": "HQ", "protocol": "tls", "resolverDecision": "blockedByCategory", "categoryNames": []string{"Malware"}}},
+	},
+	"firewallEventsAdaptiveGroups": {
+		{"count": 1200, "dimensions": map[string]interface{}{"attackId": "3f8a2c1d", "action": "block", "ruleId": "fdfdac75430c4c47a959592f0aa5e68a"}},
+		{"count": 300, "dimensions": map[string]interface{}{"attackId": "3f8a2c1d", "action": "managed_challenge", "ruleId": "2e6d8b62a3bd4b4e9b5e2b3d2c77a4bd"}},
+	},
+}
+
+// serveGraphQL answers zone and account queries for the datasets in
+// graphQLGroups. The groups must be aliased as "groups" in the query.
+func serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	// Dimensions come after the dataset in a query, so the key found last
+	// is the most specific one.
+	groups := []map[string]interface{}{}
+	last := -1
+	for key, g := range graphQLGroups {
+		if i := strings.Index(req.Query, key); i > last {
+			groups, last = g, i
+		}
+	}
+	viewer := map[string]interface{}{}
+	if zoneTag, ok := req.Variables["zoneTag"]; ok {
+		zones := []interface{}{}
+		if _, ok := findZone(fmt.Sprint(zoneTag)); ok {
+			zones = append(zones, map[string]interface{}{"groups": groups})
+		}
+		viewer["zones"] = zones
+	}
+	if accountTag, ok := req.Variables["accountTag"]; ok {
+		accounts := []interface{}{}
+		if fmt.Sprint(accountTag) == Zones[0].Account.ID {
+			accounts = append(accounts, map[string]interface{}{"groups": groups})
+		}
+		viewer["accounts"] = accounts
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":   map[string]interface{}{"viewer": viewer},
+		"errors": nil,
+	})
+}
+
+const statusSummary = `{
+  "page": {"id": "yh6f0r4529hb", "name": "Cloudflare", "url": "https://www.cloudflarestatus.com"},
+  "status": {"indicator": "minor", "description": "Minor Service Outage"},
+  "components": [
+    {"id": "group-na", "name": "North America", "status": "degraded_performance", "group": true},
+    {"id": "group-eu", "name": "Europe", "status": "operational", "group": true},
+    {"id": "group-cf", "name": "Cloudflare Sites and Services", "status": "operational", "group": true},
+    {"id": "sjc", "name": "San Jose, CA, United States - (SJC)", "status": "partial_outage", "group": false, "group_id": "group-na"},
+    {"id": "ams", "name": "Amsterdam, Netherlands - (AMS)", "status": "operational", "group": false, "group_id": "group-eu"},
+    {"id": "xyz", "name": "Nowhere, Atlantis - (XYZ)", "status": "operational", "group": false, "group_id": "group-eu"},
+    {"id": "api", "name": "API", "status": "operational", "group": false, "group_id": "group-cf"}
+  ],
+  "incidents": [],
+  "scheduled_maintenances": []
+}`
+
+func serveStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(statusSummary))
+}
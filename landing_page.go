@@ -0,0 +1,150 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/version"
+	"github.com/robbiet480/cloudflare-go"
+)
+
+// The landing page modes: full shows the authenticated email, the zones
+// and the status of their collectors, minimal only links to the metrics,
+// and none serves no landing page at all.
+const (
+	landingPageFull    = "full"
+	landingPageMinimal = "minimal"
+	landingPageNone    = "none"
+)
+
+var landingPageModes = []string{landingPageFull, landingPageMinimal, landingPageNone}
+
+var minimalLandingPageTemplate = template.Must(template.New("minimal").Parse(`<html>
+  <head><title>Cloudflare Exporter</title></head>
+  <body>
+    <h1>Cloudflare Exporter</h1>
+    <p><a href="{{.}}">Metrics</a></p>
+  </body>
+</html>
+`))
+
+var landingPageTemplate = template.Must(template.New("landing").Funcs(template.FuncMap{
+	"ago": func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return time.Since(t).Truncate(time.Second).String() + " ago"
+	},
+}).Parse(`<html>
+  <head>
+    <title>Cloudflare Exporter</title>
+    <style>table, th, td { border: 1px solid black; text-align: left; }</style>
+  </head>
+  <body>
+    <h1>Cloudflare Exporter</h1>
+    <p><a href="{{.MetricsPath}}">Metrics</a></p>
+    <h2>Config</h2>
+    {{if .StatusOnly}}
+    <p>Status-only mode: only the Cloudflare status page is exported.</p>
+    {{else}}
+    <h3>Authentication</h3>
+    <p>{{if .Email}}Authenticated as {{.Email}}{{else}}Authenticated by the API proxy{{end}}</p>
+    <h3>Zones</h3>
+    {{range .Zones}}
+    <h4><a target="_blank" href="https://www.cloudflare.com/a/overview/{{.Zone.Name}}">{{.Zone.Name}}</a> ({{.Zone.ID}}){{if .MetricsPath}} - <a href="{{.MetricsPath}}">Metrics</a>{{end}}</h4>
+    <p>Account: {{if .Zone.Account.ID}}{{.Zone.Account.Name}} ({{.Zone.Account.ID}}){{else}}unknown{{end}}{{if .Zone.Host.Name}}, hosted by {{.Zone.Host.Name}}{{end}}</p>
+    <table>
+      <thead>
+        <tr>
+          <th>Collector</th>
+          <th>Circuit</th>
+          <th>Last success</th>
+          <th>Series</th>
+          <th>Last error</th>
+        </tr>
+      </thead>
+      <tbody>
+        {{range .Status}}
+        <tr>
+          <td>{{.Name}}</td>
+          <td>{{.Breaker}}</td>
+          <td>{{ago .LastSuccess}}</td>
+          <td>{{.Series}}</td>
+          <td>{{if .LastError}}{{ago .LastErrorTime}}: {{.LastError}}{{end}}</td>
+        </tr>
+        {{end}}
+      </tbody>
+    </table>
+    {{end}}
+    {{end}}
+    <h2>Misc</h2>
+    <p><a href="/-/config">Effective configuration</a>, with credentials redacted</p>
+    <p><a href="/pops.json">Here's all the Points of Presence (PoPs) I know about</a></p>
+    <h2>Build</h2>
+    <pre>{{.Version}} {{.BuildContext}}</pre>
+  </body>
+</html>
+`))
+
+type landingPageData struct {
+	MetricsPath  string
+	StatusOnly   bool
+	Email        string
+	Zones        []landingPageZone
+	Version      string
+	BuildContext string
+}
+
+type landingPageZone struct {
+	Zone        cloudflare.Zone
+	MetricsPath string
+	Status      []CollectorStatus
+}
+
+// landingPage renders an overview of the monitored zones with the live
+// status of their collectors. zonePath returns the path a zone's metrics are
+// served under if it is not metricsPath, or "".
+func landingPage(metricsPath, email string, zones []*ZoneExporter, zonePath func(zoneName string) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := landingPageData{
+			MetricsPath:  metricsPath,
+			Email:        email,
+			Version:      version.Info(),
+			BuildContext: version.BuildContext(),
+		}
+		for _, z := range zones {
+			data.Zones = append(data.Zones, landingPageZone{Zone: z.Zone(), MetricsPath: zonePath(z.Zone().Name), Status: z.Status()})
+		}
+		if err := landingPageTemplate.Execute(w, data); err != nil {
+			log.Errorf("failed to render landing page: %s", err)
+		}
+	}
+}
+
+// statusOnlyLandingPage renders the landing page of the status-only mode,
+// which has no zones.
+func statusOnlyLandingPage(metricsPath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := landingPageData{
+			MetricsPath:  metricsPath,
+			StatusOnly:   true,
+			Version:      version.Info(),
+			BuildContext: version.BuildContext(),
+		}
+		if err := landingPageTemplate.Execute(w, data); err != nil {
+			log.Errorf("failed to render landing page: %s", err)
+		}
+	}
+}
+
+// minimalLandingPage renders a landing page linking to the metrics only,
+// which tells nothing about the configuration to whoever can reach it.
+func minimalLandingPage(metricsPath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := minimalLandingPageTemplate.Execute(w, metricsPath); err != nil {
+			log.Errorf("failed to render landing page: %s", err)
+		}
+	}
+}
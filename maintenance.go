@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+// maintenancePrefix is the path the maintenance endpoint is served under,
+// followed by the zone name or ID.
+const maintenancePrefix = "/api/v1/maintenance/"
+
+// maintenanceEntry is a zone under maintenance, until Until or, if it is
+// zero, until it is cleared.
+type maintenanceEntry struct {
+	// Zone is the name of the zone, as of the last refresh of the zone.
+	Zone   string    `json:"zone"`
+	ZoneID string    `json:"zone_id"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until,omitempty"`
+}
+
+// maintenance tracks the zones under maintenance and exports them, so alert
+// rules can be silenced centrally rather than in every rule. Zones are marked
+// at startup with --zone.maintenance or through its endpoint. Marks made
+// through the endpoint are lost on restart.
+type maintenance struct {
+	// zones returns the monitored zones as of their last refresh, so zones
+	// are found by their current name.
+	zones func() []cloudflare.Zone
+	// token is the bearer token required to mark and clear zones.
+	token string
+	// identity selects the labels identifying zones, as on zone metrics.
+	identity string
+	desc     *prometheus.Desc
+
+	mu sync.Mutex
+	// entries are the zones under maintenance by ID, which survives
+	// renames.
+	entries map[string]maintenanceEntry
+}
+
+// newMaintenance returns the maintenance of zones, with the zones named in
+// marked under maintenance until cleared. Marking and clearing zones
+// through the endpoint requires token. Zones are labelled as identity
+// selects, so the metric joins with zone metrics.
+func newMaintenance(zones func() []cloudflare.Zone, marked []string, token, identity string) *maintenance {
+	m := &maintenance{
+		zones:    zones,
+		token:    token,
+		identity: identity,
+		desc: prometheus.NewDesc(
+			"cloudflare_zone_maintenance",
+			"Zones marked as under maintenance, with a constant '1' value, for alert rules to be silenced by during planned work.",
+			collector.ZoneIdentityLabels(identity), nil,
+		),
+		entries: map[string]maintenanceEntry{},
+	}
+	now := time.Now()
+	for _, z := range marked {
+		zone, ok := m.zone(z)
+		if !ok {
+			log.Warnf("Zone %q marked as under maintenance is not monitored", z)
+			continue
+		}
+		m.entries[zone.ID] = maintenanceEntry{Zone: zone.Name, ZoneID: zone.ID, Since: now}
+	}
+	return m
+}
+
+// zone returns the monitored zone named or identified by z.
+func (m *maintenance) zone(z string) (cloudflare.Zone, bool) {
+	for _, zone := range m.zones() {
+		if zone.Name == z || zone.ID == z {
+			return zone, true
+		}
+	}
+	return cloudflare.Zone{}, false
+}
+
+// current returns the zones under maintenance at now, by name, dropping
+// expired entries.
+func (m *maintenance) current(now time.Time) []maintenanceEntry {
+	names := map[string]string{}
+	for _, zone := range m.zones() {
+		names[zone.ID] = zone.Name
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make([]maintenanceEntry, 0, len(m.entries))
+	for id, e := range m.entries {
+		if !e.Until.IsZero() && !now.Before(e.Until) {
+			delete(m.entries, id)
+			continue
+		}
+		if name, ok := names[id]; ok {
+			e.Zone = name
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Zone < entries[j].Zone })
+	return entries
+}
+
+func (m *maintenance) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.desc
+}
+
+func (m *maintenance) Collect(ch chan<- prometheus.Metric) {
+	for _, e := range m.current(time.Now()) {
+		zone := cloudflare.Zone{ID: e.ZoneID, Name: e.Zone}
+		ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, 1, collector.ZoneIdentityValues(zone, m.identity)...)
+	}
+}
+
+// ServeHTTP lists the zones under maintenance on GET of the prefix, and on
+// PUT or DELETE of a zone marks or clears it. A PUT can end the maintenance
+// with until, a duration from now such as 2h or an RFC 3339 timestamp. PUT
+// and DELETE require the token in an Authorization: Bearer header.
+func (m *maintenance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	z := strings.TrimPrefix(r.URL.Path, maintenancePrefix)
+	if z == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(m.current(time.Now()))
+		return
+	}
+	if (r.Method == http.MethodPut || r.Method == http.MethodDelete) && !m.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	zone, ok := m.zone(z)
+	if !ok {
+		http.Error(w, fmt.Sprintf("zone %q is not monitored", z), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		now := time.Now()
+		until, err := parseUntil(r.URL.Query().Get("until"), now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m.mu.Lock()
+		m.entries[zone.ID] = maintenanceEntry{Zone: zone.Name, ZoneID: zone.ID, Since: now, Until: until}
+		m.mu.Unlock()
+		log.Infof("Marked zone %s as under maintenance", zone.Name)
+	case http.MethodDelete:
+		m.mu.Lock()
+		delete(m.entries, zone.ID)
+		m.mu.Unlock()
+		log.Infof("Cleared maintenance of zone %s", zone.Name)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorized reports whether r carries the token of m as a bearer token.
+func (m *maintenance) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return m.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(m.token)) == 1
+}
+
+// parseUntil parses the until parameter, either a duration after now such
+// as 2h or an RFC 3339 timestamp. It is zero if raw is empty.
+func parseUntil(raw string, now time.Time) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil || !t.After(now) {
+		return time.Time{}, fmt.Errorf("until must be a positive duration such as 2h or a future RFC 3339 timestamp")
+	}
+	return t, nil
+}
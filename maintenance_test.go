@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+func TestMaintenance(t *testing.T) {
+	zones := []cloudflare.Zone{{ID: "023e105f4ecef8ad9ca31a8372d0c353", Name: "example.com"}}
+	m := newMaintenance(func() []cloudflare.Zone { return zones }, nil, "token", collector.ZoneIdentityBoth)
+
+	do := func(method, path, token string) int {
+		r := httptest.NewRequest(method, maintenancePrefix+path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w.Code
+	}
+	for _, test := range []struct {
+		method, path, token string
+		want                int
+	}{
+		{http.MethodPut, "example.com", "", http.StatusUnauthorized},
+		{http.MethodPut, "example.com", "wrong", http.StatusUnauthorized},
+		{http.MethodPut, "example.com", "token", http.StatusNoContent},
+		{http.MethodGet, "", "", http.StatusOK},
+		{http.MethodDelete, "example.com", "", http.StatusUnauthorized},
+	} {
+		if got := do(test.method, test.path, test.token); got != test.want {
+			t.Errorf("%s %s with token %q: got status %d, want %d", test.method, test.path, test.token, got, test.want)
+		}
+	}
+
+	// The zone is renamed by a refresh.
+	zones = []cloudflare.Zone{{ID: "023e105f4ecef8ad9ca31a8372d0c353", Name: "example.org"}}
+	if got := do(http.MethodPut, "example.com", "token"); got != http.StatusNotFound {
+		t.Errorf("PUT of the old name: got status %d, want %d", got, http.StatusNotFound)
+	}
+	entries := m.current(time.Now())
+	if len(entries) != 1 || entries[0].Zone != "example.org" {
+		t.Errorf("got zones under maintenance %v, want the renamed zone", entries)
+	}
+	if got := do(http.MethodDelete, "example.org", "token"); got != http.StatusNoContent {
+		t.Errorf("DELETE of the new name: got status %d, want %d", got, http.StatusNoContent)
+	}
+}
+
+func TestMaintenanceLabels(t *testing.T) {
+	zones := []cloudflare.Zone{{ID: "023e105f4ecef8ad9ca31a8372d0c353", Name: "example.com"}}
+	for identity, labels := range map[string]string{
+		collector.ZoneIdentityBoth: `zone_id="023e105f4ecef8ad9ca31a8372d0c353",zone_name="example.com"`,
+		collector.ZoneIdentityName: `zone_name="example.com"`,
+		collector.ZoneIdentityID:   `zone_id="023e105f4ecef8ad9ca31a8372d0c353"`,
+	} {
+		m := newMaintenance(func() []cloudflare.Zone { return zones }, []string{"example.com"}, "token", identity)
+		want := `# HELP cloudflare_zone_maintenance Zones marked as under maintenance, with a constant '1' value, for alert rules to be silenced by during planned work.
+# TYPE cloudflare_zone_maintenance gauge
+cloudflare_zone_maintenance{` + labels + `} 1
+`
+		if err := testutil.CollectAndCompare(m, strings.NewReader(want)); err != nil {
+			t.Errorf("identity %s: %s", identity, err)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+// metadataHandler serves the metrics of the collectors of zones and
+// accounts as JSON, sorted by name, from the definitions they are built
+// from, see collector.Definitions. Nothing is collected to serve it.
+func metadataHandler(metrics []collector.DefinedMetric) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(metrics)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+)
+
+// openMetricsContentType is served to scrapers that accept OpenMetrics. The
+// vendored client_golang predates OpenMetrics support, so the text format is
+// written here.
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// acceptsOpenMetrics reports whether the Accept header of r asks for
+// OpenMetrics.
+func acceptsOpenMetrics(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == "application/openmetrics-text" {
+			return true
+		}
+	}
+	return false
+}
+
+// openMetricsHandler serves OpenMetrics to scrapers asking for it and
+// delegates everything else to next.
+func openMetricsHandler(gatherer prometheus.Gatherer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsOpenMetrics(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		mfs, err := gatherer.Gather()
+		if err != nil {
+			// Like promhttp.ContinueOnError, serve what could be gathered.
+			log.Errorf("error gathering metrics: %s", err)
+		}
+		w.Header().Set("Content-Type", openMetricsContentType)
+		bw := bufio.NewWriter(w)
+		for _, mf := range mfs {
+			writeOpenMetricsFamily(bw, mf)
+		}
+		io.WriteString(bw, "# EOF\n")
+		if err := bw.Flush(); err != nil {
+			log.Errorf("error writing metrics: %s", err)
+		}
+	})
+}
+
+func writeOpenMetricsFamily(w io.Writer, mf *dto.MetricFamily) {
+	name := mf.GetName()
+	var typ string
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		typ = "counter"
+		name = strings.TrimSuffix(name, "_total")
+	case dto.MetricType_GAUGE:
+		typ = "gauge"
+	case dto.MetricType_SUMMARY:
+		typ = "summary"
+	case dto.MetricType_HISTOGRAM:
+		typ = "histogram"
+	default:
+		typ = "unknown"
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+	if mf.Help != nil {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeOpenMetrics(mf.GetHelp()))
+	}
+
+	for _, m := range mf.Metric {
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			writeOpenMetricsSample(w, name+"_total", m, "", "", m.GetCounter().GetValue())
+		case dto.MetricType_GAUGE:
+			writeOpenMetricsSample(w, name, m, "", "", m.GetGauge().GetValue())
+		case dto.MetricType_SUMMARY:
+			s := m.GetSummary()
+			for _, q := range s.Quantile {
+				writeOpenMetricsSample(w, name, m, "quantile", formatOpenMetricsFloat(q.GetQuantile()), q.GetValue())
+			}
+			writeOpenMetricsSample(w, name+"_sum", m, "", "", s.GetSampleSum())
+			writeOpenMetricsSample(w, name+"_count", m, "", "", float64(s.GetSampleCount()))
+		case dto.MetricType_HISTOGRAM:
+			h := m.GetHistogram()
+			infSeen := false
+			for _, b := range h.Bucket {
+				if math.IsInf(b.GetUpperBound(), 1) {
+					infSeen = true
+				}
+				writeOpenMetricsSample(w, name+"_bucket", m, "le", formatOpenMetricsFloat(b.GetUpperBound()), float64(b.GetCumulativeCount()))
+			}
+			if !infSeen {
+				writeOpenMetricsSample(w, name+"_bucket", m, "le", "+Inf", float64(h.GetSampleCount()))
+			}
+			writeOpenMetricsSample(w, name+"_sum", m, "", "", h.GetSampleSum())
+			writeOpenMetricsSample(w, name+"_count", m, "", "", float64(h.GetSampleCount()))
+		default:
+			writeOpenMetricsSample(w, name, m, "", "", m.GetUntyped().GetValue())
+		}
+	}
+}
+
+// writeOpenMetricsSample writes a sample line for m, adding the extra label
+// if extraName is not empty.
+func writeOpenMetricsSample(w io.Writer, name string, m *dto.Metric, extraName, extraValue string, value float64) {
+	io.WriteString(w, name)
+	labels := make([]string, 0, len(m.Label)+1)
+	for _, l := range m.Label {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, l.GetName(), escapeOpenMetrics(l.GetValue())))
+	}
+	if extraName != "" {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, extraName, extraValue))
+	}
+	if len(labels) > 0 {
+		fmt.Fprintf(w, "{%s}", strings.Join(labels, ","))
+	}
+	fmt.Fprintf(w, " %s", formatOpenMetricsFloat(value))
+	if m.TimestampMs != nil {
+		fmt.Fprintf(w, " %s", formatOpenMetricsFloat(float64(m.GetTimestampMs())/1000))
+	}
+	io.WriteString(w, "\n")
+}
+
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeOpenMetrics(s string) string {
+	return openMetricsEscaper.Replace(s)
+}
+
+func formatOpenMetricsFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+// popsHandler serves the known PoPs, as JSON or with format=csv as CSV.
+// They can be filtered by region, source and code prefix, all compared
+// case-insensitively. Regions are served with the names of
+// --labels.region-name, and match by either name.
+func popsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	region := query.Get("region")
+	source := query.Get("source")
+	prefix := strings.ToUpper(query.Get("code"))
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	pops := []collector.Pop{}
+	for _, p := range collector.Pops() {
+		name := collector.RegionName(p.Region)
+		if region != "" && !strings.EqualFold(region, p.Region) && !strings.EqualFold(region, name) {
+			continue
+		}
+		if source != "" && !strings.EqualFold(source, p.Source) {
+			continue
+		}
+		if !strings.HasPrefix(p.Code, prefix) {
+			continue
+		}
+		p.Region = name
+		pops = append(pops, p)
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"code", "name", "region", "source", "updated"})
+		for _, p := range pops {
+			updated := ""
+			if !p.Updated.IsZero() {
+				updated = p.Updated.UTC().Format(time.RFC3339)
+			}
+			cw.Write([]string{p.Code, p.Name, p.Region, p.Source, updated})
+		}
+		cw.Flush()
+		return
+	}
+	marshalledPoPs, _ := json.Marshal(pops)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(marshalledPoPs)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	scrapeResponseBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_exporter_scrape_response_bytes",
+			Help: "Uncompressed size of the last response served on each metrics path.",
+		},
+		[]string{"path"},
+	)
+	scrapeResponseLarge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloudflare_exporter_scrape_response_too_large",
+			Help: "Whether the last response served on each metrics path exceeded --web.warn-response-size.",
+		},
+		[]string{"path"},
+	)
+	scrapeResponseRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_exporter_scrape_responses_rejected_total",
+			Help: "Scrapes failed because their response exceeded --web.max-response-size, by metrics path.",
+		},
+		[]string{"path"},
+	)
+)
+
+// errResponseTooLarge is returned by writes over --web.max-response-size.
+var errResponseTooLarge = errors.New("response exceeds the maximum size")
+
+// limitedResponse streams a response to w, compressed with gzip if
+// compress is set, counting its uncompressed size. The status and headers
+// are held until the first write, so a response whose first write is over
+// the maximum can still be answered with an error instead.
+type limitedResponse struct {
+	w        http.ResponseWriter
+	max      int64
+	compress bool
+
+	header   http.Header
+	status   int
+	started  bool
+	gz       *gzip.Writer
+	size     int64
+	exceeded bool
+}
+
+func (l *limitedResponse) Header() http.Header { return l.header }
+
+func (l *limitedResponse) WriteHeader(status int) {
+	if !l.started {
+		l.status = status
+	}
+}
+
+func (l *limitedResponse) Write(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, errResponseTooLarge
+	}
+	l.size += int64(len(p))
+	if l.max > 0 && l.size > l.max {
+		l.exceeded = true
+		return 0, errResponseTooLarge
+	}
+	l.start()
+	if l.gz != nil {
+		return l.gz.Write(p)
+	}
+	return l.w.Write(p)
+}
+
+// start sends the status and headers, unless they were sent already.
+func (l *limitedResponse) start() {
+	if l.started {
+		return
+	}
+	l.started = true
+	for name, values := range l.header {
+		l.w.Header()[name] = values
+	}
+	l.w.Header().Add("Vary", "Accept-Encoding")
+	if l.compress && l.header.Get("Content-Encoding") == "" {
+		l.w.Header().Set("Content-Encoding", "gzip")
+		l.w.Header().Del("Content-Length")
+		l.gz = gzip.NewWriter(l.w)
+	}
+	l.w.WriteHeader(l.status)
+}
+
+// finish completes the response once the handler returned.
+func (l *limitedResponse) finish() {
+	l.start()
+	if l.gz != nil {
+		if err := l.gz.Close(); err != nil {
+			log.Errorf("error writing metrics: %s", err)
+		}
+	}
+}
+
+// acceptsGzip reports whether the Accept-Encoding header of r allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(encoding, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// limitResponseSize measures the responses of next, failing those over
+// opts.MaxResponseSize and flagging those over opts.WarnResponseSize, and
+// compresses them with gzip for scrapers accepting it, whichever the
+// format. Snappy is not offered, as Prometheus only negotiates gzip for
+// scrapes. Sizes are uncompressed, 0 meaning no limit.
+//
+// Responses are streamed as they are written, so their size is only known
+// once they are complete. A response growing over opts.MaxResponseSize is
+// cut off by aborting the connection, which the scraper sees as a failed
+// scrape rather than as a complete, truncated one.
+func limitResponseSize(opts scrapeOpts, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := &limitedResponse{w: w, max: opts.MaxResponseSize, compress: acceptsGzip(r), header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(res, r)
+
+		path := r.URL.Path
+		scrapeResponseBytes.WithLabelValues(path).Set(float64(res.size))
+		large := 0.0
+		if opts.WarnResponseSize > 0 && res.size > opts.WarnResponseSize {
+			large = 1
+			log.Warnf("response of %d bytes on %s exceeds the warning threshold of %d bytes", res.size, path, opts.WarnResponseSize)
+		}
+		scrapeResponseLarge.WithLabelValues(path).Set(large)
+		if res.exceeded {
+			scrapeResponseRejected.WithLabelValues(path).Inc()
+			log.Errorf("response on %s exceeds the maximum of %d bytes", path, opts.MaxResponseSize)
+			if res.started {
+				panic(http.ErrAbortHandler)
+			}
+			http.Error(w, fmt.Sprintf("Response exceeds the maximum of %d bytes, select fewer collectors or zones.", opts.MaxResponseSize), http.StatusInternalServerError)
+			return
+		}
+		res.finish()
+	})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLimitResponseSize(t *testing.T) {
+	// body writes the response in writes of 10 bytes.
+	body := func(writes int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			for i := 0; i < writes; i++ {
+				w.Write([]byte("0123456789"))
+			}
+		})
+	}
+	server := httptest.NewServer(http.NewServeMux())
+	defer server.Close()
+	mux := server.Config.Handler.(*http.ServeMux)
+	opts := scrapeOpts{MaxResponseSize: 25}
+	mux.Handle("/small", limitResponseSize(opts, body(2)))
+	mux.Handle("/large", limitResponseSize(opts, body(5)))
+	mux.Handle("/first", limitResponseSize(scrapeOpts{MaxResponseSize: 5}, body(1)))
+
+	get := func(path string, gzipped bool) (*http.Response, string, error) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if gzipped {
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+		res, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			return nil, "", err
+		}
+		defer res.Body.Close()
+		r := io.Reader(res.Body)
+		if res.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(res.Body)
+			if err != nil {
+				return res, "", err
+			}
+			r = gz
+		}
+		b, err := ioutil.ReadAll(r)
+		return res, string(b), err
+	}
+
+	for _, gzipped := range []bool{false, true} {
+		if res, b, err := get("/small", gzipped); err != nil || res.StatusCode != http.StatusOK || b != strings.Repeat("0123456789", 2) {
+			t.Errorf("small response, gzip %t: got %v, %q, %v", gzipped, res, b, err)
+		}
+		if _, _, err := get("/large", gzipped); err == nil {
+			t.Errorf("large response, gzip %t: got a complete response", gzipped)
+		}
+	}
+	if res, _, err := get("/first", false); err != nil || res.StatusCode != http.StatusInternalServerError {
+		t.Errorf("response over the maximum on its first write: got %v, %v, want status 500", res, err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+// rulesConfig is the part of the configuration that decides which metrics
+// the generated rules can be built on.
+type rulesConfig struct {
+	Selection    collector.Selection
+	Dashboard    collector.DashboardOptions
+	DNS          collector.DNSOptions
+	ZoneIdentity string
+}
+
+// rule is a Prometheus recording or alerting rule.
+type rule struct {
+	Record      string
+	Alert       string
+	Expr        string
+	For         string
+	Severity    string
+	Summary     string
+	Description string
+}
+
+// ruleGroup is a named group of rules, evaluated together.
+type ruleGroup struct {
+	Name  string
+	Rules []rule
+}
+
+// enabled reports whether the named zone collector runs for some zones.
+func (c rulesConfig) enabled(name string) bool {
+	for _, disabled := range c.Selection.Disable {
+		if disabled == name {
+			return false
+		}
+	}
+	return true
+}
+
+// wants reports whether item is requested, an empty list requesting all.
+func wants(list []string, item string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, s := range list {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
+// zoneMetric selects a zone metric whether it is exported globally or, for
+// plans broken out by PoP, under the cloudflare_pop namespace.
+func zoneMetric(name, selector string) string {
+	return fmt.Sprintf("%s_%s%s or %s_pop_%s%s", collector.Namespace, name, selector, collector.Namespace, name, selector)
+}
+
+// zoneRate is zoneMetric for the rate of counters.
+func zoneRate(name, selector string) string {
+	return fmt.Sprintf("rate(%s_%s%s[5m]) or rate(%s_pop_%s%s[5m])", collector.Namespace, name, selector, collector.Namespace, name, selector)
+}
+
+// ruleGroups returns the recording and alerting rules for the metrics that
+// config produces. Rules for metrics of disabled collectors, metrics or
+// dimensions are left out.
+func ruleGroups(config rulesConfig) []ruleGroup {
+	const byZone = "sum by (zone_id, zone_name)"
+	zone := "{{ $labels.zone_name }}"
+	if config.ZoneIdentity == collector.ZoneIdentityID {
+		zone = "{{ $labels.zone_id }}"
+	}
+	recording := ruleGroup{Name: "cloudflare_exporter.recording"}
+	alerting := ruleGroup{Name: "cloudflare_exporter.alerts"}
+
+	if config.enabled("dashboard_analytics") {
+		total := fmt.Sprintf("%s (%s)", byZone, zoneMetric("requests_total", ""))
+		recording.Rules = append(recording.Rules,
+			rule{Record: "zone:cloudflare_requests_cache_hit:ratio", Expr: fmt.Sprintf("%s (%s) / %s", byZone, zoneMetric("requests_cached", ""), total)},
+		)
+	}
+	// The status code ratios need the status breakdown.
+	if config.enabled("dashboard_analytics") && wants(config.Dashboard.RequestBreakdowns, "status") {
+		total := fmt.Sprintf("%s (%s)", byZone, zoneMetric("requests_total", ""))
+		recording.Rules = append(recording.Rules,
+			rule{Record: "zone:cloudflare_requests_5xx:ratio", Expr: fmt.Sprintf(`%s (%s) / %s`, byZone, zoneMetric("requests_by_status", `{status_code=~"5.."}`), total)},
+			rule{Record: "zone:cloudflare_requests_52x:ratio", Expr: fmt.Sprintf(`%s (%s) / %s`, byZone, zoneMetric("requests_by_status", `{status_code=~"52[0-9]"}`), total)},
+		)
+		alerting.Rules = append(alerting.Rules, rule{
+			Alert:       "CloudflareOrigin52xSpike",
+			Expr:        "zone:cloudflare_requests_52x:ratio > 0.05",
+			For:         "10m",
+			Severity:    "critical",
+			Summary:     "Cloudflare cannot reach the origin of " + zone,
+			Description: "{{ $value | humanizePercentage }} of requests to " + zone + " get a 52x status code, meaning Cloudflare could not get a valid response from the origin.",
+		})
+	}
+
+	switch {
+	case config.DNS.ByAccount:
+		const byAccount = "sum by (account_id, account_name)"
+		recording.Rules = append(recording.Rules,
+			rule{Record: "account:cloudflare_dns_queries:sum", Expr: fmt.Sprintf("%s (cloudflare_account_dns_queries)", byAccount)},
+			rule{Record: "account:cloudflare_dns_queries_nxdomain:ratio", Expr: fmt.Sprintf(`%s (cloudflare_account_dns_queries{response_code="NXDOMAIN"}) / account:cloudflare_dns_queries:sum`, byAccount)},
+		)
+		alerting.Rules = append(alerting.Rules, rule{
+			Alert:       "CloudflareDNSNXDOMAINFlood",
+			Expr:        "account:cloudflare_dns_queries_nxdomain:ratio > 0.5 and account:cloudflare_dns_queries:sum > 3000",
+			For:         "10m",
+			Severity:    "warning",
+			Summary:     "Flood of NXDOMAIN answers in account {{ $labels.account_name }}",
+			Description: "{{ $value | humanizePercentage }} of DNS queries to the zones of {{ $labels.account_name }} are for names that do not exist, which may be a random subdomain attack.",
+		})
+	case config.enabled("dns_analytics") && wants(config.DNS.Metrics, "queryCount") && wants(config.DNS.Dimensions, "responseCode"):
+		recording.Rules = append(recording.Rules,
+			rule{Record: "zone:cloudflare_dns_queries:rate5m", Expr: fmt.Sprintf("%s (%s)", byZone, zoneRate("dns_record_queries_total", ""))},
+			rule{Record: "zone:cloudflare_dns_queries_nxdomain:ratio", Expr: fmt.Sprintf(`%s (%s) / zone:cloudflare_dns_queries:rate5m`, byZone, zoneRate("dns_record_queries_total", `{response_code="NXDOMAIN"}`))},
+		)
+		alerting.Rules = append(alerting.Rules, rule{
+			Alert:       "CloudflareDNSNXDOMAINFlood",
+			Expr:        "zone:cloudflare_dns_queries_nxdomain:ratio > 0.5 and zone:cloudflare_dns_queries:rate5m > 10",
+			For:         "10m",
+			Severity:    "warning",
+			Summary:     "Flood of NXDOMAIN answers for " + zone,
+			Description: "{{ $value | humanizePercentage }} of DNS queries to " + zone + " are for names that do not exist, which may be a random subdomain attack.",
+		})
+	}
+
+	if config.enabled("ssl") {
+		alerting.Rules = append(alerting.Rules, rule{
+			Alert:       "CloudflareCertificateValidationStuck",
+			Expr:        `cloudflare_ssl_certificate_pack_status{status=~"pending_validation|validation_timed_out"} == 1`,
+			For:         "24h",
+			Severity:    "warning",
+			Summary:     "Edge certificate of " + zone + " is stuck on validation",
+			Description: "Certificate pack {{ $labels.certificate_pack_id }} of " + zone + " is {{ $labels.status }}, so Cloudflare cannot issue or renew it until control of its hostnames is validated.",
+		})
+	}
+
+	alerting.Rules = append(alerting.Rules, rule{
+		Alert:       "CloudflarePoPOutage",
+		Expr:        "cloudflare_pop_status == 0",
+		For:         "15m",
+		Severity:    "info",
+		Summary:     "Cloudflare PoP {{ $labels.pop_name }} is {{ $labels.status }}",
+		Description: "The Cloudflare status page reports {{ $labels.pop_name }} ({{ $labels.pop_id }}) as {{ $labels.status }}. Traffic is served from other PoPs meanwhile.",
+	})
+	return []ruleGroup{recording, alerting}
+}
+
+// quote returns s as a YAML double-quoted scalar, which JSON strings are
+// valid as.
+func quote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// generateRules renders the rule groups for config as a Prometheus rules
+// file.
+func generateRules(config rulesConfig) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by cloudflare_exporter generate-rules for the metrics of its configuration.\n")
+	buf.WriteString("groups:\n")
+	for _, group := range ruleGroups(config) {
+		fmt.Fprintf(&buf, "- name: %s\n  rules:\n", quote(group.Name))
+		for _, r := range group.Rules {
+			if r.Record != "" {
+				fmt.Fprintf(&buf, "  - record: %s\n", quote(r.Record))
+			} else {
+				fmt.Fprintf(&buf, "  - alert: %s\n", quote(r.Alert))
+			}
+			fmt.Fprintf(&buf, "    expr: %s\n", quote(r.Expr))
+			if r.For != "" {
+				fmt.Fprintf(&buf, "    for: %s\n", r.For)
+			}
+			if r.Severity != "" {
+				fmt.Fprintf(&buf, "    labels:\n      severity: %s\n", quote(r.Severity))
+			}
+			if r.Summary != "" {
+				fmt.Fprintf(&buf, "    annotations:\n      summary: %s\n      description: %s\n", quote(r.Summary), quote(r.Description))
+			}
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// sharedGatherer lets concurrent scrapes share a single collection: a scrape
+// arriving while another is being gathered waits for and serves its result
+// instead of calling the Cloudflare API again. The shared collection runs
+// within the deadline of the scrape that started it.
+type sharedGatherer struct {
+	mu       sync.Mutex
+	inFlight *gatherCall
+}
+
+type gatherCall struct {
+	done chan struct{}
+	mfs  []*dto.MetricFamily
+	err  error
+}
+
+// share returns a gatherer gathering from g, or waiting for the gathering
+// in flight if there is one.
+func (g *sharedGatherer) share(gatherer prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		g.mu.Lock()
+		if call := g.inFlight; call != nil {
+			g.mu.Unlock()
+			<-call.done
+			return call.mfs, call.err
+		}
+		call := &gatherCall{done: make(chan struct{})}
+		g.inFlight = call
+		g.mu.Unlock()
+
+		call.mfs, call.err = gatherer.Gather()
+
+		g.mu.Lock()
+		g.inFlight = nil
+		g.mu.Unlock()
+		close(call.done)
+		return call.mfs, call.err
+	})
+}
+
+// limitInFlight responds with 503 to requests beyond the first max
+// concurrent ones, like promhttp's MaxRequestsInFlight. A max of 0 or less
+// means no limit.
+func limitInFlight(max int, next http.Handler) http.Handler {
+	if max <= 0 {
+		return next
+	}
+	sem := make(chan struct{}, max)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		default:
+			http.Error(w, fmt.Sprintf("Limit of concurrent scrapes reached (%d), try again later.", max), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
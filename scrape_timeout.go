@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// scrapeTimeoutHeader is set by Prometheus to the scrape timeout in seconds.
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// collectorContext returns a context expiring when ctx does or after
+// timeout, whichever comes first. A zero timeout leaves ctx's deadline.
+func collectorContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// withScrapeDeadline makes collections for scrapes carrying the Prometheus
+// scrape timeout header end offset before the timeout, so whatever has been
+// collected by then is returned instead of nothing. The deadline is set on
+// the request's context, which collectorSelector collects within, so each
+// scrape keeps its own deadline.
+func withScrapeDeadline(offset time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(scrapeTimeoutHeader); v != "" {
+			if seconds, err := strconv.ParseFloat(v, 64); err == nil && seconds > 0 {
+				timeout := time.Duration(seconds*float64(time.Second)) - offset
+				ctx, cancel := context.WithTimeout(r.Context(), timeout)
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithScrapeDeadline(t *testing.T) {
+	for _, test := range []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"10", 9500 * time.Millisecond},
+		{"2.5", 2 * time.Second},
+		{"invalid", 0},
+	} {
+		var got time.Duration
+		h := withScrapeDeadline(500*time.Millisecond, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if deadline, ok := r.Context().Deadline(); ok {
+				got = time.Until(deadline)
+			}
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if test.header != "" {
+			r.Header.Set(scrapeTimeoutHeader, test.header)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if got > test.want || got < test.want-time.Second {
+			t.Errorf("timeout header %q: got deadline in %s, want %s", test.header, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// secretSource fetches a secret from a secret manager.
+type secretSource interface {
+	Fetch() (string, error)
+}
+
+// newSecretSource returns the source of the secret at uri, fetched with
+// client. Supported URIs are:
+//
+//	vault://<path>#<field>
+//	aws-secretsmanager://<secret id>[#<field>]
+//	gcp-secretmanager://projects/<project>/secrets/<secret>/versions/<version>[#<field>]
+//
+// A field picks a key of a JSON secret.
+func newSecretSource(uri string, client *http.Client) (secretSource, error) {
+	scheme := strings.SplitN(uri, "://", 2)
+	if len(scheme) != 2 || scheme[1] == "" {
+		return nil, fmt.Errorf("invalid secret URI %q", uri)
+	}
+	path, field := scheme[1], ""
+	if i := strings.LastIndex(path, "#"); i >= 0 {
+		path, field = path[:i], path[i+1:]
+	}
+
+	switch scheme[0] {
+	case "vault":
+		if field == "" {
+			return nil, fmt.Errorf("missing field in Vault secret URI %q", uri)
+		}
+		addr := os.Getenv("VAULT_ADDR")
+		if addr == "" {
+			addr = "https://127.0.0.1:8200"
+		}
+		return &vaultSecret{client: client, addr: strings.TrimSuffix(addr, "/"), path: path, field: field}, nil
+	case "aws-secretsmanager":
+		region := os.Getenv("AWS_REGION")
+		if region == "" {
+			region = os.Getenv("AWS_DEFAULT_REGION")
+		}
+		if region == "" {
+			return nil, fmt.Errorf("AWS_REGION must be set for AWS Secrets Manager secrets")
+		}
+		endpoint := fmt.Sprintf("https://secretsmanager.%s.amazonaws.com/", region)
+		return &awsSecret{client: client, endpoint: endpoint, region: region, id: path, field: field}, nil
+	case "gcp-secretmanager":
+		return &gcpSecret{client: client, endpoint: gcpSecretManagerURL, tokenURL: gcpMetadataTokenURL, name: path, field: field}, nil
+	}
+	return nil, fmt.Errorf("unsupported secret URI scheme %q, expected vault, aws-secretsmanager or gcp-secretmanager", scheme[0])
+}
+
+// doSecretRequest sends req and decodes the JSON response into result.
+func doSecretRequest(client *http.Client, req *http.Request, result interface{}) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP status %d", res.StatusCode)
+	}
+	return json.Unmarshal(body, result)
+}
+
+// secretField returns field of the JSON object secret, or secret itself if
+// field is empty.
+func secretField(secret, field string) (string, error) {
+	if field == "" {
+		return secret, nil
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %s", err)
+	}
+	value, ok := values[field].(string)
+	if !ok {
+		return "", fmt.Errorf("secret has no string field %q", field)
+	}
+	return value, nil
+}
+
+// vaultSecret is a field of a Vault secret, read with the token in
+// VAULT_TOKEN. Both versions of the KV secrets engine are supported.
+type vaultSecret struct {
+	client *http.Client
+	addr   string
+	path   string
+	field  string
+}
+
+func (s *vaultSecret) Fetch() (string, error) {
+	req, err := http.NewRequest(http.MethodGet, s.addr+"/v1/"+s.path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := doSecretRequest(s.client, req, &result); err != nil {
+		return "", fmt.Errorf("failed to read Vault secret %s: %s", s.path, err)
+	}
+	data := result.Data
+	// KV version 2 nests the secret and its metadata.
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	value, ok := data[s.field].(string)
+	if !ok {
+		return "", fmt.Errorf("no string field %q in Vault secret %s", s.field, s.path)
+	}
+	return value, nil
+}
+
+// awsSecret is an AWS Secrets Manager secret, read with the credentials in
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+type awsSecret struct {
+	client   *http.Client
+	endpoint string
+	region   string
+	id       string
+	field    string
+}
+
+func (s *awsSecret) Fetch() (string, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": s.id})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signAWSRequest(req, body, "secretsmanager", s.region, time.Now())
+
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := doSecretRequest(s.client, req, &result); err != nil {
+		return "", fmt.Errorf("failed to read AWS secret %s: %s", s.id, err)
+	}
+	return secretField(result.SecretString, s.field)
+}
+
+// signAWSRequest signs req with AWS Signature Version 4, using the
+// credentials from the environment.
+func signAWSRequest(req *http.Request, body []byte, service, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", os.Getenv("AWS_ACCESS_KEY_ID"), scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+const (
+	// gcpSecretManagerURL is the base URL of the GCP Secret Manager API.
+	gcpSecretManagerURL = "https://secretmanager.googleapis.com/v1/"
+	// gcpMetadataTokenURL serves access tokens of the default service
+	// account on Google Cloud.
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// gcpSecret is a GCP Secret Manager secret version, read with the access
+// token in GOOGLE_OAUTH_ACCESS_TOKEN or else of the instance's default
+// service account.
+type gcpSecret struct {
+	client   *http.Client
+	endpoint string
+	tokenURL string
+	name     string
+	field    string
+}
+
+func (s *gcpSecret) Fetch() (string, error) {
+	token, err := s.token()
+	if err != nil {
+		return "", fmt.Errorf("failed to get GCP access token: %s", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, s.endpoint+s.name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	var result struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := doSecretRequest(s.client, req, &result); err != nil {
+		return "", fmt.Errorf("failed to read GCP secret %s: %s", s.name, err)
+	}
+	secret, err := base64.StdEncoding.DecodeString(result.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode GCP secret %s: %s", s.name, err)
+	}
+	return secretField(string(secret), s.field)
+}
+
+func (s *gcpSecret) token() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, s.tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var result struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doSecretRequest(s.client, req, &result); err != nil {
+		return "", err
+	}
+	return result.AccessToken, nil
+}
+
+// apiCredentials holds the current Cloudflare API key, which may be replaced
+// while requests are made.
+type apiCredentials struct {
+	mu  sync.RWMutex
+	key string
+
+	lastRefresh prometheus.Gauge
+}
+
+func newAPICredentials() *apiCredentials {
+	return &apiCredentials{
+		lastRefresh: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "cloudflare_exporter_credentials_last_refresh_timestamp_seconds",
+			Help: "When the Cloudflare API key was last fetched from its secret manager.",
+		}),
+	}
+}
+
+// refresh fetches the key from source.
+func (c *apiCredentials) refresh(source secretSource) error {
+	key, err := source.Fetch()
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("secret is empty")
+	}
+	c.mu.Lock()
+	c.key = key
+	c.mu.Unlock()
+	c.lastRefresh.SetToCurrentTime()
+	return nil
+}
+
+// refreshEvery refreshes the key from source every interval, keeping the
+// current key if that fails.
+func (c *apiCredentials) refreshEvery(source secretSource, interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := c.refresh(source); err != nil {
+			log.Errorf("failed to refresh Cloudflare API key: %s", err)
+		}
+	}
+}
+
+// roundTripper returns a RoundTripper replacing the API key of requests
+// authenticated with one by the current key.
+func (c *apiCredentials) roundTripper(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("X-Auth-Key") == "" {
+			return next.RoundTrip(req)
+		}
+		c.mu.RLock()
+		key := c.key
+		c.mu.RUnlock()
+		r := withHeaders(req)
+		r.Header.Set("X-Auth-Key", key)
+		return next.RoundTrip(r)
+	})
+}
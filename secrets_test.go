@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setenv sets the environment variables of vars until the returned func is
+// called.
+func setenv(vars map[string]string) func() {
+	old := map[string]*string{}
+	for name, value := range vars {
+		if v, ok := os.LookupEnv(name); ok {
+			old[name] = &v
+		} else {
+			old[name] = nil
+		}
+		if value == "" {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, value)
+		}
+	}
+	return func() {
+		for name, value := range old {
+			if value == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *value)
+			}
+		}
+	}
+}
+
+// TestSignAWSRequest checks signatures against the get-vanilla and
+// post-vanilla cases of the AWS Signature Version 4 test suite.
+func TestSignAWSRequest(t *testing.T) {
+	defer setenv(map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		"AWS_SESSION_TOKEN":     "",
+	})()
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	for method, signature := range map[string]string{
+		http.MethodGet:  "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		http.MethodPost: "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+	} {
+		req, err := http.NewRequest(method, "https://example.amazonaws.com/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signAWSRequest(req, nil, "service", "us-east-1", now)
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=" + signature
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("%s: got Authorization %q, want %q", method, got, want)
+		}
+		if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+			t.Errorf("%s: got X-Amz-Date %q", method, got)
+		}
+	}
+}
+
+func TestAWSSecret(t *testing.T) {
+	defer setenv(map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		"AWS_SESSION_TOKEN":     "session",
+	})()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || string(body) != `{"SecretId":"cloudflare"}` {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || r.Header.Get("X-Amz-Security-Token") != "session" {
+			http.Error(w, "unsigned request", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"SecretString": "{\"api_key\": \"aws-key\"}"}`))
+	}))
+	defer server.Close()
+
+	s := &awsSecret{client: server.Client(), endpoint: server.URL + "/", region: "us-east-1", id: "cloudflare", field: "api_key"}
+	key, err := s.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "aws-key" {
+		t.Errorf("got key %q, want %q", key, "aws-key")
+	}
+}
+
+func TestVaultSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/cloudflare":
+			w.Write([]byte(`{"data": {"api_key": "v1-key"}}`))
+		case "/v1/kv/data/cloudflare":
+			w.Write([]byte(`{"data": {"data": {"api_key": "v2-key"}, "metadata": {"version": 3}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer setenv(map[string]string{"VAULT_ADDR": server.URL + "/", "VAULT_TOKEN": "token"})()
+
+	for uri, want := range map[string]string{
+		"vault://secret/cloudflare#api_key":  "v1-key",
+		"vault://kv/data/cloudflare#api_key": "v2-key",
+	} {
+		source, err := newSecretSource(uri, server.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := source.Fetch()
+		if err != nil {
+			t.Errorf("%s: %s", uri, err)
+			continue
+		}
+		if key != want {
+			t.Errorf("%s: got key %q, want %q", uri, key, want)
+		}
+	}
+
+	source, err := newSecretSource("vault://secret/cloudflare#token", server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Fetch(); err == nil {
+		t.Error("expected an error for a missing field")
+	}
+}
+
+func TestGCPSecret(t *testing.T) {
+	defer setenv(map[string]string{"GOOGLE_OAUTH_ACCESS_TOKEN": ""})()
+	payloads := map[string]string{
+		"plain": base64.StdEncoding.EncodeToString([]byte("gcp-key")),
+		"json":  base64.StdEncoding.EncodeToString([]byte(`{"api_key": "gcp-json-key"}`)),
+		"bad":   "not base64!",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				http.Error(w, "missing Metadata-Flavor", http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"access_token": "token", "expires_in": 3599, "token_type": "Bearer"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthenticated", http.StatusUnauthorized)
+			return
+		}
+		var secret string
+		if _, err := fmt.Sscanf(r.URL.Path, "/v1/projects/example/secrets/%s", &secret); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		payload, ok := payloads[strings.TrimSuffix(secret, "/versions/latest:access")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"name": "` + r.URL.Path + `", "payload": {"data": "` + payload + `"}}`))
+	}))
+	defer server.Close()
+
+	fetch := func(secret, field string) (string, error) {
+		s := &gcpSecret{
+			client:   server.Client(),
+			endpoint: server.URL + "/v1/",
+			tokenURL: server.URL + "/token",
+			name:     "projects/example/secrets/" + secret + "/versions/latest",
+			field:    field,
+		}
+		return s.Fetch()
+	}
+	if key, err := fetch("plain", ""); err != nil || key != "gcp-key" {
+		t.Errorf("got key %q and error %v, want %q", key, err, "gcp-key")
+	}
+	if key, err := fetch("json", "api_key"); err != nil || key != "gcp-json-key" {
+		t.Errorf("got key %q and error %v, want %q", key, err, "gcp-json-key")
+	}
+	if _, err := fetch("json", "token"); err == nil {
+		t.Error("expected an error for a missing field")
+	}
+	if _, err := fetch("plain", "api_key"); err == nil {
+		t.Error("expected an error for a field of a secret that is not JSON")
+	}
+	if _, err := fetch("bad", ""); err == nil {
+		t.Error("expected an error for a payload that is not base64")
+	}
+	if _, err := fetch("missing", ""); err == nil {
+		t.Error("expected an error for a missing secret")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+// shard identifies the part of the zones and accounts a replica exports when
+// several replicas split them between themselves.
+type shard struct {
+	Index int
+	Total int
+}
+
+func (s shard) validate() error {
+	if s.Total < 1 {
+		return fmt.Errorf("shard total must be at least 1, got %d", s.Total)
+	}
+	if s.Index < 0 || s.Index >= s.Total {
+		return fmt.Errorf("shard index must be between 0 and %d, got %d", s.Total-1, s.Index)
+	}
+	return nil
+}
+
+// owns reports whether the object with the given ID belongs to the shard.
+// Hashing IDs keeps the assignment stable across replicas and restarts.
+func (s shard) owns(id string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32()%uint32(s.Total)) == s.Index
+}
+
+// zones returns the zones belonging to the shard.
+func (s shard) zones(zones []cloudflare.Zone) []cloudflare.Zone {
+	owned := []cloudflare.Zone{}
+	for _, zone := range zones {
+		if s.owns(zone.ID) {
+			owned = append(owned, zone)
+		}
+	}
+	return owned
+}
+
+// accounts returns the accounts belonging to the shard. Accounts are sharded
+// on their own, so account-wide metrics are exported by a single replica
+// even when the account's zones are spread over several.
+func (s shard) accounts(accounts []collector.Account) []collector.Account {
+	owned := []collector.Account{}
+	for _, account := range accounts {
+		if s.owns(account.ID) {
+			owned = append(owned, account)
+		}
+	}
+	return owned
+}
+
+// newShardZonesGauge returns a gauge telling which zones the shard owns,
+// identified by the labels identity selects.
+func newShardZonesGauge(s shard, zones []cloudflare.Zone, identity string) *prometheus.GaugeVec {
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "cloudflare_exporter_shard_zone",
+		Help:        "Zones exported by this replica, with a constant '1' value",
+		ConstLabels: prometheus.Labels{"shard": strconv.Itoa(s.Index), "shards": strconv.Itoa(s.Total)},
+	}, collector.ZoneIdentityLabels(identity))
+	for _, zone := range zones {
+		g.WithLabelValues(collector.ZoneIdentityValues(zone, identity)...).Set(1)
+	}
+	return g
+}
@@ -1,25 +1,91 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare_exporter/collector"
 )
 
 var popIDRegex = regexp.MustCompile(`(.*) - \((.*)\)`)
 
+var (
+	statusFetches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_exporter_statuspage_fetch_requests_total",
+			Help: "Cloudflare status page requests by response code.",
+		},
+		[]string{"code"},
+	)
+	statusFetchErrors = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cloudflare_exporter_statuspage_fetch_errors_total",
+			Help: "Cloudflare status page fetches that failed, from connection errors to unreadable summaries.",
+		},
+	)
+	statusFetchDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cloudflare_exporter_statuspage_fetch_duration_seconds",
+			Help:    "Latency of Cloudflare status page requests.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	registry.MustRegister(statusFetches, statusFetchErrors, statusFetchDuration)
+}
+
+// statusPageClient returns the client for status page requests, counting
+// and timing them on top of transport.
+func statusPageClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: promhttp.InstrumentRoundTripperCounter(statusFetches,
+			promhttp.InstrumentRoundTripperDuration(statusFetchDuration, transport),
+		),
+	}
+}
+
+// statusOpts configures the StatusExporter.
+type statusOpts struct {
+	// Timeout limits how long fetching the summary may take, besides the
+	// scrape deadline. Zero means no limit.
+	Timeout time.Duration
+	// Components restricts the exported PoPs, regions and services to
+	// those named, all of them if empty. PoPs are matched by code, name or
+	// region, so naming a region selects its PoPs too.
+	Components []string
+	// MinInterval is how long a fetched summary is served before the
+	// status page is asked again. Zero fetches it on every scrape.
+	MinInterval time.Duration
+}
+
 // StatusExporter collects metrics about Cloudflare system status.
 type StatusExporter struct {
+	summaryURL string
+	client     *http.Client
+	opts       statusOpts
+
+	mu      sync.Mutex
+	fetched time.Time
+	summary statusPageSummary
+
 	popStatus     *prometheus.Desc
 	serviceStatus *prometheus.Desc
 	regionStatus  *prometheus.Desc
 	overallStatus *prometheus.Desc
+	dataAge       *prometheus.Desc
 }
 
 type statusPageSummary struct {
@@ -53,23 +119,28 @@ func getStatusFloat(status string) float64 {
 	return float64(0)
 }
 
-// NewStatusExporter returns an initialized StatusExporter.
-func NewStatusExporter() *StatusExporter {
+// NewStatusExporter returns an initialized StatusExporter reading the status
+// page summary from summaryURL with client.
+func NewStatusExporter(summaryURL string, client *http.Client, opts statusOpts) *StatusExporter {
 	return &StatusExporter{
+		summaryURL: summaryURL,
+		client:     client,
+		opts:       opts,
+
 		popStatus: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "pop", "status"),
+			prometheus.BuildFQName(collector.Namespace, "pop", "status"),
 			"Cloudflare Point of Presence (PoP) status",
-			[]string{"status", "pop_name", "pop_id", "region_name"}, nil,
+			collector.WithLabels([]string{"status"}, collector.PopLabels...), nil,
 		),
 
 		regionStatus: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "region", "status"),
+			prometheus.BuildFQName(collector.Namespace, "region", "status"),
 			"Cloudflare Region status",
 			[]string{"status", "region_name"}, nil,
 		),
 
 		serviceStatus: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "service", "status"),
+			prometheus.BuildFQName(collector.Namespace, "service", "status"),
 			"Cloudflare service status",
 			[]string{"status", "service_name"}, nil,
 		),
@@ -79,6 +150,12 @@ func NewStatusExporter() *StatusExporter {
 			"Cloudflare status",
 			[]string{"indicator", "description"}, nil,
 		),
+
+		dataAge: prometheus.NewDesc(
+			"cloudflare_exporter_statuspage_data_age_seconds",
+			"How long ago the served status page summary was fetched, with a minimum interval",
+			nil, nil,
+		),
 	}
 }
 
@@ -89,36 +166,27 @@ func (e *StatusExporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- e.regionStatus
 	ch <- e.serviceStatus
 	ch <- e.overallStatus
+	ch <- e.dataAge
 }
 
 // Collect fetches the statistics about Cloudflare system status, and
 // delivers them as Prometheus metrics. It implements prometheus.Collector.
 func (e *StatusExporter) Collect(ch chan<- prometheus.Metric) {
-	req, err := http.NewRequest(http.MethodGet, "https://www.cloudflarestatus.com/api/v2/summary.json", nil)
+	e.collectOnly(context.Background(), ch, nil)
+}
+
+// collectOnly is Collect within the deadline of ctx. The status page is a
+// single collector, selected by the caller with statusCollector, so only
+// is ignored.
+func (e *StatusExporter) collectOnly(ctx context.Context, ch chan<- prometheus.Metric, only map[string]bool) {
+	statusSummary, fetched, err := e.latest(ctx)
 	if err != nil {
+		statusFetchErrors.Inc()
 		log.Errorf("failed to get cloudflare status: %s", err)
 		return
 	}
-
-	req.Header.Set("User-Agent", userAgentHeader)
-
-	res, getErr := http.DefaultClient.Do(req)
-	if getErr != nil {
-		log.Errorf("failed to get cloudflare status: %s", getErr)
-		return
-	}
-
-	body, readErr := ioutil.ReadAll(res.Body)
-	if readErr != nil {
-		log.Errorf("failed to get cloudflare status: %s", readErr)
-		return
-	}
-
-	statusSummary := statusPageSummary{}
-	jsonErr := json.Unmarshal(body, &statusSummary)
-	if jsonErr != nil {
-		log.Errorf("failed to get cloudflare status: %s", jsonErr)
-		return
+	if e.opts.MinInterval > 0 {
+		ch <- prometheus.MustNewConstMetric(e.dataAge, prometheus.GaugeValue, time.Since(fetched).Seconds())
 	}
 
 	groupMap := map[string]string{}
@@ -126,8 +194,9 @@ func (e *StatusExporter) Collect(ch chan<- prometheus.Metric) {
 	for _, component := range statusSummary.Components {
 		if component.Group {
 			groupMap[component.ID] = component.Name
-			if !strings.Contains(component.Name, "Cloudflare") {
-				ch <- prometheus.MustNewConstMetric(e.regionStatus, prometheus.GaugeValue, getStatusFloat(component.Status), component.Status, component.Name)
+			region := collector.RegionName(component.Name)
+			if !strings.Contains(component.Name, "Cloudflare") && e.selected(component.Name, region) {
+				ch <- prometheus.MustNewConstMetric(e.regionStatus, prometheus.GaugeValue, getStatusFloat(component.Status), component.Status, region)
 			}
 		}
 	}
@@ -138,15 +207,82 @@ func (e *StatusExporter) Collect(ch chan<- prometheus.Metric) {
 		}
 		matches := popIDRegex.FindStringSubmatch(component.Name)
 		if len(matches) > 0 {
-			popName := matches[1]
-			popCode := matches[2]
-			regionName := groupMap[component.GroupID]
-			ch <- prometheus.MustNewConstMetric(e.popStatus, prometheus.GaugeValue, getStatusFloat(component.Status), component.Status, popName, popCode, regionName)
-			addPop(pop{Name: popName, Code: popCode, Region: regionName})
-		} else {
+			collector.AddPop(collector.Pop{Name: matches[1], Code: matches[2], Region: groupMap[component.GroupID]})
+			// Resolve through the PoP registry so label values match the ones
+			// used by the analytics collectors.
+			p := collector.GetPop(matches[2])
+			if !e.selected(matches[2], matches[1], groupMap[component.GroupID], p.LabelValues()[2]) {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(e.popStatus, prometheus.GaugeValue, getStatusFloat(component.Status), collector.WithLabels([]string{component.Status}, p.LabelValues()...)...)
+		} else if e.selected(component.Name) {
 			ch <- prometheus.MustNewConstMetric(e.serviceStatus, prometheus.GaugeValue, getStatusFloat(component.Status), component.Status, component.Name)
 		}
 	}
 
 	ch <- prometheus.MustNewConstMetric(e.overallStatus, prometheus.GaugeValue, getStatusFloat(statusSummary.Status.Indicator), statusSummary.Status.Indicator, statusSummary.Status.Description)
 }
+
+// selected reports whether a component known by any of names is to be
+// exported. Names are compared case-insensitively.
+func (e *StatusExporter) selected(names ...string) bool {
+	if len(e.opts.Components) == 0 {
+		return true
+	}
+	for _, component := range e.opts.Components {
+		for _, name := range names {
+			if strings.EqualFold(component, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// latest returns the last fetched summary and when it was fetched if that is
+// less than e.opts.MinInterval ago, or else fetches it again.
+func (e *StatusExporter) latest(ctx context.Context) (statusPageSummary, time.Time, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.fetched.IsZero() && time.Since(e.fetched) < e.opts.MinInterval {
+		return e.summary, e.fetched, nil
+	}
+	start := time.Now()
+	summary, err := e.fetch(ctx)
+	if err != nil {
+		return summary, start, err
+	}
+	e.summary, e.fetched = summary, start
+	return summary, start, nil
+}
+
+// fetch gets the status page summary within the deadline of ctx and
+// e.opts.Timeout.
+func (e *StatusExporter) fetch(ctx context.Context) (statusPageSummary, error) {
+	statusSummary := statusPageSummary{}
+	req, err := http.NewRequest(http.MethodGet, e.summaryURL, nil)
+	if err != nil {
+		return statusSummary, err
+	}
+
+	req.Header.Set("User-Agent", userAgentHeader)
+	ctx, cancel := collectorContext(ctx, e.opts.Timeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return statusSummary, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return statusSummary, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return statusSummary, err
+	}
+	err = json.Unmarshal(body, &statusSummary)
+	return statusSummary, err
+}
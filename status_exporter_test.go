@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/expfmt"
+	"github.com/robbiet480/cloudflare_exporter/internal/fakeapi"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// compareGolden compares the exposition of c with the golden file
+// testdata/name.prom, or writes it with -update.
+func compareGolden(t *testing.T, c prometheus.Collector, name string) {
+	path := filepath.Join("testdata", name+".prom")
+
+	if *update {
+		reg := prometheus.NewPedanticRegistry()
+		if err := reg.Register(c); err != nil {
+			t.Fatal(err)
+		}
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		for _, family := range families {
+			if _, err := expfmt.MetricFamilyToText(&buf, family); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	golden, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s, run the tests with -update to create it", err)
+	}
+	if err := testutil.CollectAndCompare(c, bytes.NewReader(golden)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatusExporterGolden(t *testing.T) {
+	server := fakeapi.NewServer()
+	defer server.Close()
+
+	for _, test := range []struct {
+		name string
+		opts statusOpts
+	}{
+		{"status", statusOpts{}},
+		{"status_components", statusOpts{Components: []string{"Europe", "API"}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			e := NewStatusExporter(server.URL+fakeapi.StatusPath, http.DefaultClient, test.opts)
+			compareGolden(t, e, test.name)
+		})
+	}
+}
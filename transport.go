@@ -0,0 +1,200 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// transportOpts configures the transport of outbound requests.
+type transportOpts struct {
+	// CloudflareProxy is the proxy URL for Cloudflare API requests.
+	CloudflareProxy string
+	// StatusProxy is the proxy URL for status page requests.
+	StatusProxy string
+	// StatusURL is the status page summary URL, whose host tells status
+	// page requests apart.
+	StatusURL string
+	// CAFile is a PEM bundle of CA certificates trusted besides the system
+	// ones, e.g. of a TLS-intercepting proxy.
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and its key,
+	// presented to servers asking for one.
+	CertFile string
+	KeyFile  string
+	// MinVersion is the minimum TLS version, 1.0, 1.1 or 1.2. Empty leaves
+	// Go's default.
+	MinVersion string
+}
+
+// tlsVersions maps the accepted minimum TLS versions to their constants.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+}
+
+// newTransport returns a transport with the settings of
+// http.DefaultTransport, proxying requests as configured by opts.
+func newTransport(opts transportOpts) (*http.Transport, error) {
+	proxy, err := proxyFunc(opts)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Transport{
+		Proxy:           proxy,
+		TLSClientConfig: tlsConfig,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			DualStack: true,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}, nil
+}
+
+// proxyFunc returns the proxy function of the transport: requests to the
+// status page host go through opts.StatusProxy and all others through
+// opts.CloudflareProxy. Where no proxy is configured, the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables are honoured. Proxy
+// credentials are taken from the user info of the proxy URL.
+func proxyFunc(opts transportOpts) (func(*http.Request) (*url.URL, error), error) {
+	cloudflareProxy, err := parseProxyURL(opts.CloudflareProxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Cloudflare API proxy URL: %s", err)
+	}
+	statusProxy, err := parseProxyURL(opts.StatusProxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid status page proxy URL: %s", err)
+	}
+	statusHost := ""
+	if u, err := url.Parse(opts.StatusURL); err == nil {
+		statusHost = u.Host
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		proxy := cloudflareProxy
+		if req.URL.Host == statusHost {
+			proxy = statusProxy
+		}
+		if proxy == nil {
+			return http.ProxyFromEnvironment(req)
+		}
+		return proxy, nil
+	}, nil
+}
+
+// parseProxyURL parses a proxy URL, returning nil for an empty one.
+func parseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	// Parse errors quote the URL, which may hold credentials.
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("malformed URL")
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected http, https or socks5", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing proxy host")
+	}
+	return u, nil
+}
+
+// newTLSConfig returns the TLS configuration of the transport, or nil to use
+// Go's defaults if opts has no TLS options.
+func newTLSConfig(opts transportOpts) (*tls.Config, error) {
+	if opts.CAFile == "" && opts.CertFile == "" && opts.KeyFile == "" && opts.MinVersion == "" {
+		return nil, nil
+	}
+	config := &tls.Config{}
+
+	if opts.CAFile != "" {
+		pem, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %s", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("client certificate and key files must be provided together")
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %s", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.MinVersion != "" {
+		version, ok := tlsVersions[opts.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported minimum TLS version %q, expected 1.0, 1.1 or 1.2", opts.MinVersion)
+		}
+		config.MinVersion = version
+	}
+	return config, nil
+}
+
+// keylessRoundTripper returns a RoundTripper for an authenticating proxy at
+// proxyHost in front of the Cloudflare API: the exporter's own credential
+// headers are dropped and headers added to requests to the proxy instead.
+// Requests to other hosts, such as the status page, are left alone so the
+// headers do not leak.
+func keylessRoundTripper(next http.RoundTripper, proxyHost string, headers map[string]string) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host != proxyHost {
+			return next.RoundTrip(req)
+		}
+		r := withHeaders(req)
+		r.Header.Del("X-Auth-Key")
+		r.Header.Del("X-Auth-Email")
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		return next.RoundTrip(r)
+	})
+}
+
+// withHeaders returns a shallow copy of req with a copy of its headers, as
+// RoundTrippers must not modify the request they are given.
+func withHeaders(req *http.Request) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	return r
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
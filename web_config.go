@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/common/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// webConfig secures the web server with TLS and basic authentication. It
+// has the layout of the Prometheus web configuration file, read as JSON,
+// which is also valid YAML for tools sharing the file.
+type webConfig struct {
+	TLSServerConfig struct {
+		CertFile       string `json:"cert_file"`
+		KeyFile        string `json:"key_file"`
+		ClientCAFile   string `json:"client_ca_file"`
+		ClientAuthType string `json:"client_auth_type"`
+	} `json:"tls_server_config"`
+	// BasicAuthUsers maps user names to bcrypt hashes of their password.
+	BasicAuthUsers map[string]string `json:"basic_auth_users"`
+}
+
+// clientAuthTypes are the valid values of client_auth_type.
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+// loadWebConfig reads the web configuration file at path.
+func loadWebConfig(path string) (*webConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read web configuration: %s", err)
+	}
+	c := &webConfig{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("invalid web configuration %s: %s", path, err)
+	}
+	tlsConfig := c.TLSServerConfig
+	if (tlsConfig.CertFile == "") != (tlsConfig.KeyFile == "") {
+		return nil, fmt.Errorf("invalid web configuration %s: cert_file and key_file must be set together", path)
+	}
+	if _, ok := clientAuthTypes[tlsConfig.ClientAuthType]; !ok {
+		return nil, fmt.Errorf("invalid web configuration %s: unknown client_auth_type %q", path, tlsConfig.ClientAuthType)
+	}
+	if tlsConfig.ClientCAFile != "" && tlsConfig.CertFile == "" {
+		return nil, fmt.Errorf("invalid web configuration %s: client_ca_file requires cert_file", path)
+	}
+	for user, hash := range c.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("invalid web configuration %s: password of user %s is not a bcrypt hash: %s", path, user, err)
+		}
+	}
+	return c, nil
+}
+
+// tlsConfig returns the TLS configuration of the server, or nil to serve
+// plain HTTP.
+func (c *webConfig) tlsConfig() (*tls.Config, error) {
+	if c.TLSServerConfig.CertFile == "" {
+		return nil, nil
+	}
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: clientAuthTypes[c.TLSServerConfig.ClientAuthType],
+	}
+	if c.TLSServerConfig.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(c.TLSServerConfig.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %s", err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", c.TLSServerConfig.ClientCAFile)
+		}
+	}
+	return config, nil
+}
+
+// basicAuth requires the credentials of one of users on every request but
+// those to the paths in public, which authenticate themselves.
+type basicAuth struct {
+	users  map[string]string
+	public map[string]bool
+	next   http.Handler
+
+	// verified holds the digests of credentials checked against their
+	// bcrypt hash, which is deliberately slow, so scrapes only pay for it
+	// once.
+	mu       sync.Mutex
+	verified map[[sha256.Size]byte]bool
+}
+
+func (a *basicAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.public[r.URL.Path] || a.authenticated(r) {
+		a.next.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", `Basic realm="cloudflare_exporter"`)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+func (a *basicAuth) authenticated(r *http.Request) bool {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	hash, ok := a.users[user]
+	if !ok {
+		return false
+	}
+	digest := sha256.Sum256([]byte(user + "\x00" + password + "\x00" + hash))
+	a.mu.Lock()
+	verified := a.verified[digest]
+	a.mu.Unlock()
+	if verified {
+		return true
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false
+	}
+	a.mu.Lock()
+	a.verified[digest] = true
+	a.mu.Unlock()
+	return true
+}
+
+// listenAndServe serves handler on addr, with the TLS and basic
+// authentication of config if it is not nil. Requests to the paths in
+// public skip basic authentication.
+func listenAndServe(addr string, config *webConfig, public []string, handler http.Handler) error {
+	if config == nil {
+		return http.ListenAndServe(addr, handler)
+	}
+	if len(config.BasicAuthUsers) > 0 {
+		a := &basicAuth{users: config.BasicAuthUsers, public: map[string]bool{}, next: handler, verified: map[[sha256.Size]byte]bool{}}
+		for _, path := range public {
+			a.public[path] = true
+		}
+		handler = a
+		log.Infof("Requiring basic authentication for users %s", strings.Join(userNames(config.BasicAuthUsers), ", "))
+	}
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Addr: addr, Handler: handler, TLSConfig: tlsConfig}
+	if tlsConfig == nil {
+		return server.ListenAndServe()
+	}
+	log.Infoln("Serving TLS")
+	return server.ListenAndServeTLS(config.TLSServerConfig.CertFile, config.TLSServerConfig.KeyFile)
+}
+
+// userNames returns the names of users, sorted.
+func userNames(users map[string]string) []string {
+	names := make([]string, 0, len(users))
+	for name := range users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &basicAuth{
+		users:    map[string]string{"prometheus": string(hash)},
+		public:   map[string]bool{"/webhook": true},
+		next:     http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		verified: map[[sha256.Size]byte]bool{},
+	}
+	for _, test := range []struct {
+		path, user, password string
+		want                 int
+	}{
+		{"/-/config", "", "", http.StatusUnauthorized},
+		{"/-/config", "prometheus", "wrong", http.StatusUnauthorized},
+		{"/-/config", "other", "secret", http.StatusUnauthorized},
+		{"/-/config", "prometheus", "secret", http.StatusOK},
+		// Served from the verified credentials.
+		{"/metrics", "prometheus", "secret", http.StatusOK},
+		{"/webhook", "", "", http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.user != "" {
+			r.SetBasicAuth(test.user, test.password)
+		}
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, r)
+		if w.Code != test.want {
+			t.Errorf("%s as %q/%q: got status %d, want %d", test.path, test.user, test.password, w.Code, test.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+// webhookAuthHeader carries the secret configured for a Cloudflare
+// notification webhook destination.
+const webhookAuthHeader = "cf-webhook-auth"
+
+// otherZones is the value of the zone labels of notifications about zones
+// that are not monitored. Names come from the payload, so they would
+// otherwise create series at the will of the sender.
+const otherZones = "other"
+
+// webhookMaxBody is the largest notification accepted, well above what
+// Cloudflare sends.
+const webhookMaxBody = 1 << 20
+
+// webhookNotification is the part of a Cloudflare notification webhook
+// payload that is exported. Data depends on the alert type, and names the
+// zone for zone alerts.
+type webhookNotification struct {
+	AlertType string                 `json:"alert_type"`
+	Name      string                 `json:"name"`
+	Text      string                 `json:"text"`
+	Timestamp int64                  `json:"ts"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// zoneName returns the zone a notification is about, or an empty string for
+// account alerts. It comes from the payload, so it is not necessarily a
+// monitored zone.
+func (n webhookNotification) zoneName() string {
+	for _, key := range []string{"zone_name", "zone"} {
+		if name, ok := n.Data[key].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// webhookReceiver turns Cloudflare notification webhooks, such as DDoS
+// attack alerts, health check status changes and certificate expiry
+// notices, into metrics. They arrive as they happen, between the polls of
+// the analytics collectors.
+type webhookReceiver struct {
+	secret string
+	// zones returns the monitored zones, the only ones notifications are
+	// labeled with, by the labels zoneIdentity selects.
+	zones        func() []cloudflare.Zone
+	zoneIdentity string
+	// forwarder forwards notifications to Alertmanager, if not nil.
+	forwarder *alertForwarder
+
+	notifications *prometheus.CounterVec
+	last          *prometheus.GaugeVec
+	rejected      *prometheus.CounterVec
+}
+
+// newWebhookReceiver returns a receiver accepting notifications carrying
+// secret, which must not be empty, and registers its metrics in reg.
+// Notifications are labelled with the labels zoneIdentity selects of their
+// zone, and those about zones not returned by zones with otherZones.
+func newWebhookReceiver(secret string, zones func() []cloudflare.Zone, zoneIdentity string, reg prometheus.Registerer) *webhookReceiver {
+	labels := append([]string{"alert_type"}, collector.ZoneIdentityLabels(zoneIdentity)...)
+	h := &webhookReceiver{
+		secret:       secret,
+		zones:        zones,
+		zoneIdentity: zoneIdentity,
+		notifications: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cloudflare_webhook_notifications_total",
+				Help: "Cloudflare notifications received by webhook, by alert type and zone.",
+			},
+			labels,
+		),
+		last: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cloudflare_webhook_last_notification_timestamp_seconds",
+				Help: "When Cloudflare sent the last notification received by webhook, by alert type and zone.",
+			},
+			labels,
+		),
+		rejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cloudflare_exporter_webhook_rejected_total",
+				Help: "Webhook requests rejected, by reason (method, auth or payload).",
+			},
+			[]string{"reason"},
+		),
+	}
+	reg.MustRegister(h.notifications, h.last, h.rejected)
+	return h
+}
+
+func (h *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.rejected.WithLabelValues("method").Inc()
+		http.Error(w, "notifications must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookAuthHeader)), []byte(h.secret)) != 1 {
+		h.rejected.WithLabelValues("auth").Inc()
+		http.Error(w, "invalid webhook secret", http.StatusUnauthorized)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, webhookMaxBody))
+	var n webhookNotification
+	if err == nil {
+		err = json.Unmarshal(body, &n)
+	}
+	if err != nil || n.AlertType == "" {
+		h.rejected.WithLabelValues("payload").Inc()
+		log.Debugf("Rejected webhook payload: %s", body)
+		http.Error(w, "payload is not a Cloudflare notification", http.StatusBadRequest)
+		return
+	}
+
+	sent := time.Now()
+	if n.Timestamp > 0 {
+		sent = time.Unix(n.Timestamp, 0)
+	}
+	zone := n.zoneName()
+	labels := append([]string{n.AlertType}, h.zoneLabels(zone)...)
+	h.notifications.WithLabelValues(labels...).Inc()
+	h.last.WithLabelValues(labels...).Set(float64(sent.Unix()))
+	log.Infof("Received %s notification %q for zone %q", n.AlertType, n.Name, zone)
+	if h.forwarder != nil {
+		go h.forwarder.forward(n, sent)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// zoneLabels returns the zone label values of a notification about the zone
+// named zone, empty for account alerts and otherZones if it is not
+// monitored.
+func (h *webhookReceiver) zoneLabels(zone string) []string {
+	values := make([]string, len(collector.ZoneIdentityLabels(h.zoneIdentity)))
+	if zone == "" {
+		return values
+	}
+	for _, z := range h.zones() {
+		if z.Name == zone {
+			return collector.ZoneIdentityValues(z, h.zoneIdentity)
+		}
+	}
+	for i := range values {
+		values[i] = otherZones
+	}
+	return values
+}
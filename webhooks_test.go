@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+func TestWebhookZoneLabel(t *testing.T) {
+	zones := func() []cloudflare.Zone {
+		return []cloudflare.Zone{{ID: "023e105f4ecef8ad9ca31a8372d0c353", Name: "example.com"}}
+	}
+	h := newWebhookReceiver("secret", zones, collector.ZoneIdentityName, prometheus.NewRegistry())
+	for _, zone := range []string{"example.com", "forged1.example", "forged2.example"} {
+		r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"alert_type": "dos_attack_l7", "data": {"zone_name": "`+zone+`"}}`))
+		r.Header.Set(webhookAuthHeader, "secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("notification for %s: got status %d", zone, w.Code)
+		}
+	}
+	for zone, want := range map[string]float64{"example.com": 1, otherZones: 2} {
+		if got := testutil.ToFloat64(h.notifications.WithLabelValues("dos_attack_l7", zone)); got != want {
+			t.Errorf("got %v notifications for zone %q, want %v", got, zone, want)
+		}
+	}
+}
+
+func TestWebhookZoneIdentity(t *testing.T) {
+	zones := func() []cloudflare.Zone {
+		return []cloudflare.Zone{{ID: "023e105f4ecef8ad9ca31a8372d0c353", Name: "example.com"}}
+	}
+	h := newWebhookReceiver("secret", zones, collector.ZoneIdentityBoth, prometheus.NewRegistry())
+	for _, body := range []string{
+		`{"alert_type": "dos_attack_l7", "data": {"zone_name": "example.com"}}`,
+		`{"alert_type": "dos_attack_l7", "data": {"zone_name": "forged.example"}}`,
+		`{"alert_type": "billing_usage_alert", "data": {}}`,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		r.Header.Set(webhookAuthHeader, "secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("notification %s: got status %d", body, w.Code)
+		}
+	}
+	want := `# HELP cloudflare_webhook_notifications_total Cloudflare notifications received by webhook, by alert type and zone.
+# TYPE cloudflare_webhook_notifications_total counter
+cloudflare_webhook_notifications_total{alert_type="billing_usage_alert",zone_id="",zone_name=""} 1
+cloudflare_webhook_notifications_total{alert_type="dos_attack_l7",zone_id="023e105f4ecef8ad9ca31a8372d0c353",zone_name="example.com"} 1
+cloudflare_webhook_notifications_total{alert_type="dos_attack_l7",zone_id="other",zone_name="other"} 1
+`
+	if err := testutil.CollectAndCompare(h.notifications, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebhookRequiresSecret(t *testing.T) {
+	h := newWebhookReceiver("secret", func() []cloudflare.Zone { return nil }, collector.ZoneIdentityBoth, prometheus.NewRegistry())
+	r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"alert_type": "forged"}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d for a notification without the secret, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -1,325 +1,193 @@
 package main
 
 import (
-	"fmt"
-	"strings"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
 )
 
-// ZoneExporter collects metrics for a Cloudflare zone.
-type ZoneExporter struct {
-	cf            *cloudflare.API
-	zone          cloudflare.Zone
-	dnsDimensions []string
-	dnsMetrics    []string
-
-	allRequests      *prometheus.Desc
-	cachedRequests   *prometheus.Desc
-	uncachedRequests *prometheus.Desc
-
-	encryptedRequests   *prometheus.Desc
-	unencryptedRequests *prometheus.Desc
-
-	byStatusRequests      *prometheus.Desc
-	byContentTypeRequests *prometheus.Desc
-	byCountryRequests     *prometheus.Desc
-	byIPClassRequests     *prometheus.Desc
-
-	totalBandwidth    *prometheus.Desc
-	cachedBandwidth   *prometheus.Desc
-	uncachedBandwidth *prometheus.Desc
-
-	encryptedBandwidth   *prometheus.Desc
-	unencryptedBandwidth *prometheus.Desc
-
-	byContentTypeBandwidth *prometheus.Desc
-	byCountryBandwidth     *prometheus.Desc
-
-	allThreats       *prometheus.Desc
-	byTypeThreats    *prometheus.Desc
-	byCountryThreats *prometheus.Desc
-
-	allPageviews            *prometheus.Desc
-	bySearchEnginePageviews *prometheus.Desc
-
-	uniqueIPAddresses *prometheus.Desc
+// CollectorStatus is the outcome of the latest runs of a zone's collector.
+type CollectorStatus struct {
+	Name          string
+	Breaker       collector.BreakerState
+	LastSuccess   time.Time
+	LastError     string
+	LastErrorTime time.Time
+	// Series is the number of series sent by the latest successful run.
+	Series int
+}
 
-	dnsQueryTotal      *prometheus.Desc
-	uncachedDNSQueries *prometheus.Desc
-	staleDNSQueries    *prometheus.Desc
+// zoneState is the zone a ZoneExporter collects for and the collectors it
+// runs. It is replaced as a whole when the zone is refreshed.
+type zoneState struct {
+	zone       cloudflare.Zone
+	collectors []collector.Collector
+	breakers   map[string]*collector.Breaker
+	schedules  map[string]*collector.Schedule
+	flights    map[string]*flight
+	// zoneDescs carry the zone's labels, so they are built again with the
+	// collectors when those change.
+	*zoneDescs
+}
 
+// zoneDescs describe the metrics a ZoneExporter exports about a zone's
+// collection.
+type zoneDescs struct {
 	componentProcessingTime *prometheus.Desc
 	overallProcessingTime   *prometheus.Desc
+	breakerState            *prometheus.Desc
+	scrapeSuccess           *prometheus.Desc
+	seriesEmitted           *prometheus.Desc
+	dataAge                 *prometheus.Desc
+	zoneInfo                *prometheus.Desc
+	planChangesTotal        *prometheus.Desc
+	planChangeTime          *prometheus.Desc
 }
 
-// NewZoneExporter returns an initialized ZoneExporter.
-func NewZoneExporter(api *cloudflare.API, zone cloudflare.Zone) *ZoneExporter {
-	dashboardMetricsLabels := []string{}
-	dashboardMetricsNamespace := namespace
-	dashboardMetricsHelpSuffix := ""
-
-	dnsDimensions := []string{"queryName", "responseCode", "origin", "tcp", "ipVersion"}
-	dnsMetrics := []string{"queryCount", "uncachedCount", "staleCount"}
-	dnsMetricsLabels := []string{"query_name", "response_code", "origin", "tcp", "ip_version"}
-	dnsMetricsNamespace := namespace
-	dnsMetricsHelpSuffix := ""
-
-	// Free plans:
-	// Dashboard Analytics is for Global Cloudflare network
-	// Dashboard Analytics Labels are empty
-	// Dashboard Analytics Namespace is "cloudflare"
-	// DNS Analytics is for Global Cloudflare network
-	// DNS Analytics Labels contain query_name, response_code, origin, tcp, ip_version
-	// DNS Analytics Dimensions contain queryName, responseCode, origin, tcp, ipVersion
-	// DNS Analytics Namespace is "cloudflare"
+// newZoneState returns the state of a ZoneExporter running the named
+// collectors for zone.
+func newZoneState(api collector.API, zone cloudflare.Zone, opts collector.Options, collectorNames ...string) (*zoneState, error) {
+	collectors, err := collector.New(api, zone, opts, collectorNames...)
+	if err != nil {
+		return nil, err
+	}
+	s := &zoneState{
+		zone:       zone,
+		collectors: collectors,
+		breakers:   make(map[string]*collector.Breaker, len(collectors)),
+		schedules:  make(map[string]*collector.Schedule, len(collectors)),
+		flights:    make(map[string]*flight, len(collectors)),
+		zoneDescs:  newZoneDescs(collector.ZoneLabels(zone, opts)),
+	}
+	for _, c := range collectors {
+		s.breakers[c.Name()] = collector.NewBreaker(opts.Breaker)
+		s.schedules[c.Name()] = opts.Schedule(c.Name())
+		s.flights[c.Name()] = &flight{}
+	}
+	return s, nil
+}
 
-	// Pro plans:
-	// Dashboard Analytics is for Global Cloudflare network
-	// Dashboard Analytics Labels are empty
-	// Dashboard Analytics Namespace is "cloudflare"
-	// DNS Analytics broken out by point of presence (PoP, sometimes also called "colo")
-	// DNS Analytics Labels contain query_name, response_code, response_cached, origin, tcp, ip_version, pop_id, pop_name, pop_region
-	// DNS Analytics Dimensions contain queryName, responseCode, origin, tcp, ipVersion, responseCached, coloName (really ID, name/region provided by statuspage)
-	// DNS Analytics Namespace is "cloudflare_pop"
+// planChange is a change of a zone's plan, after which its collectors were
+// rebuilt.
+type planChange struct {
+	from, to string
+	time     time.Time
+}
 
-	// Business plans:
-	// Dashboard Analytics is for Global Cloudflare network
-	// Dashboard Analytics Labels are empty
-	// Dashboard Analytics Namespace is "cloudflare"
-	// DNS Analytics broken out by point of presence (PoP, sometimes also called "colo")
-	// DNS Analytics Labels contain query_name, response_code, response_cached, origin, tcp, ip_version, query_type, pop_id, pop_name, pop_region
-	// DNS Analytics Dimensions contain queryName, responseCode, origin, tcp, ipVersion, responseCached, queryType, coloName (really ID, name/region provided by statuspage)
-	// DNS Analytics Namespace is "cloudflare_pop"
+// ZoneExporter collects metrics for a Cloudflare zone.
+type ZoneExporter struct {
+	api      collector.API
+	opts     collector.Options
+	timeouts map[string]time.Duration
+	budget   *collector.Budget
+
+	mu         sync.Mutex
+	current    *zoneState
+	status     map[string]*CollectorStatus
+	refreshed  time.Time
+	refreshing bool
+	// planChanges counts the plan changes seen by refreshing the zone, the
+	// last of which is lastPlanChange.
+	planChanges    int
+	lastPlanChange planChange
+}
 
-	// Enterprise plans:
-	// Dashboard Analytics broken out by point of presence (PoP, sometimes also called "colo")
-	// Dashboard Analytics Labels are pop_id, pop_name, pop_region
-	// Dashboard Analytics Namespace is "cloudflare_pop"
-	// DNS Analytics broken out by point of presence (PoP, sometimes also called "colo")
-	// DNS Analytics Labels contain query_name, response_code, response_cached, origin, tcp, ip_version, query_type, pop_id, pop_name, pop_region
-	// DNS Analytics Dimensions contain queryName, responseCode, origin, tcp, ipVersion, responseCached, queryType, coloName (really ID, name/region provided by statuspage)
-	// DNS Analytics Namespace is "cloudflare_pop"
+// errScrapeTimeout is returned for collectors cut off by the scrape deadline.
+var errScrapeTimeout = errors.New("scrape timeout reached")
 
-	if zone.Plan.LegacyID == "enterprise" {
-		dashboardMetricsHelpSuffix = "(broken out by point of presence (PoP))"
-		dashboardMetricsLabels = []string{"pop_id", "pop_name", "pop_region"}
-		dashboardMetricsNamespace = fmt.Sprintf("%s_pop", namespace)
+// errCollectorTimeout is returned for collectors cut off by their own
+// timeout, which unlike the scrape deadline counts as a failure.
+var errCollectorTimeout = errors.New("collector timeout reached")
 
-		dnsDimensions = []string{"queryName", "responseCode", "origin", "tcp", "ipVersion", "responseCached", "queryType", "coloName"}
-		dnsMetricsHelpSuffix = "(broken out by point of presence (PoP))"
-		dnsMetricsLabels = []string{"query_name", "response_code", "origin", "tcp", "ip_version", "response_cached", "query_type", "pop_id", "pop_name", "pop_region"}
-		dnsMetricsNamespace = fmt.Sprintf("%s_pop", namespace)
-	} else if zone.Plan.LegacyID == "business" {
-		dnsMetricsNamespace = fmt.Sprintf("%s_pop", namespace)
-		dnsDimensions = []string{"queryName", "responseCode", "origin", "tcp", "ipVersion", "responseCached", "queryType", "coloName"}
-		dnsMetricsHelpSuffix = "(broken out by point of presence (PoP))"
-		dnsMetricsLabels = []string{"query_name", "response_code", "origin", "tcp", "ip_version", "response_cached", "query_type", "pop_id", "pop_name", "pop_region"}
-	} else if zone.Plan.LegacyID == "pro" {
-		dnsMetricsNamespace = fmt.Sprintf("%s_pop", namespace)
-		dnsDimensions = []string{"queryName", "responseCode", "origin", "tcp", "ipVersion", "coloName"}
-		dnsMetricsHelpSuffix = "(broken out by point of presence (PoP))"
-		dnsMetricsLabels = []string{"query_name", "response_code", "origin", "tcp", "ip_version", "pop_id", "pop_name", "pop_region"}
+// NewZoneExporter returns an initialized ZoneExporter running the named
+// collectors, or all registered collectors if none are named.
+func NewZoneExporter(api collector.API, zone cloudflare.Zone, opts collector.Options, collectorNames ...string) (*ZoneExporter, error) {
+	current, err := newZoneState(api, zone, opts, collectorNames...)
+	if err != nil {
+		return nil, err
 	}
 
 	log.Debugf("Zone %s (%s) configured with plan %s", zone.Name, zone.ID, zone.Plan.LegacyID)
-	log.Debugf("Dashboard metrics namespace: '%s'", dashboardMetricsNamespace)
-	log.Debugf("Dashboard metrics labels: '%s'", strings.Join(dashboardMetricsLabels, ", "))
-
-	log.Debugf("DNS metrics namespace: '%s'", dnsMetricsNamespace)
-	log.Debugf("DNS metrics labels: '%s'", strings.Join(dnsMetricsLabels, ", "))
-	log.Debugf("DNS dimensions: '%s'", strings.Join(dnsDimensions, ", "))
-
-	constantLabels := prometheus.Labels{
-		"zone_id":      zone.ID,
-		"zone_name":    zone.Name,
-		"account_id":   zone.Account.ID,
-		"account_name": zone.Account.Name,
-		"owner_id":     zone.Owner.ID,
-	}
 
-	if zone.Owner.Name != "" {
-		constantLabels["owner_name"] = zone.Owner.Name
-	}
-
-	if zone.Owner.Email != "" {
-		constantLabels["owner_email"] = zone.Owner.Email
+	status := make(map[string]*CollectorStatus, len(current.collectors))
+	for _, c := range current.collectors {
+		status[c.Name()] = &CollectorStatus{Name: c.Name()}
 	}
 
 	return &ZoneExporter{
-		cf:            api,
-		zone:          zone,
-		dnsDimensions: dnsDimensions,
-		dnsMetrics:    dnsMetrics,
-		allRequests: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "requests", "total"),
-			fmt.Sprintf("Total number of requests served %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
-			constantLabels,
-		),
-		cachedRequests: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "requests", "cached"),
-			fmt.Sprintf("Total number of cached requests served %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
-			constantLabels,
-		),
-		uncachedRequests: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "requests", "uncached"),
-			fmt.Sprintf("Total number of requests served from the origin %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
-			constantLabels,
-		),
-		encryptedRequests: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "requests", "encrypted"),
-			fmt.Sprintf("The number of requests served over HTTPS %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
-			constantLabels,
-		),
-		unencryptedRequests: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "requests", "unencrypted"),
-			fmt.Sprintf("The number of requests served over HTTP %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
-			constantLabels,
-		),
-		byStatusRequests: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "requests", "by_status"),
-			fmt.Sprintf("The total number of requests broken out by status code %s", dashboardMetricsHelpSuffix),
-			append(dashboardMetricsLabels, "status_code"),
-			constantLabels,
-		),
-		byContentTypeRequests: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "requests", "by_content_type"),
-			fmt.Sprintf("The total number of requests broken out by content type %s", dashboardMetricsHelpSuffix),
-			append(dashboardMetricsLabels, "content_type"),
-			constantLabels,
-		),
-		byCountryRequests: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "requests", "by_country"),
-			fmt.Sprintf("The total number of requests broken out by country %s", dashboardMetricsHelpSuffix),
-			append(dashboardMetricsLabels, "country_code"),
-			constantLabels,
-		),
-		byIPClassRequests: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "requests", "by_ip_class"),
-			fmt.Sprintf("The total number of requests broken out by IP class %s", dashboardMetricsHelpSuffix),
-			append(dashboardMetricsLabels, "ip_class"),
-			constantLabels,
-		),
-
-		totalBandwidth: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "bandwidth", "total_bytes"),
-			fmt.Sprintf("The total number of bytes served within the time frame %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
-			constantLabels,
-		),
-		cachedBandwidth: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "bandwidth", "cached_bytes"),
-			fmt.Sprintf("The total number of bytes that were cached (and served) by Cloudflare %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
-			constantLabels,
-		),
-		uncachedBandwidth: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "bandwidth", "uncached_bytes"),
-			fmt.Sprintf("The total number of bytes that were fetched and served from the origin server %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
-			constantLabels,
-		),
-		encryptedBandwidth: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "bandwidth", "encrypted_bytes"),
-			fmt.Sprintf("The total number of bytes served over HTTPS %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
-			constantLabels,
-		),
-		unencryptedBandwidth: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "bandwidth", "unencrypted_bytes"),
-			fmt.Sprintf("The total number of bytes served over HTTP %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
-			constantLabels,
-		),
-		byContentTypeBandwidth: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "bandwidth", "by_content_type_bytes"),
-			fmt.Sprintf("The total number of bytes served broken out by content type %s", dashboardMetricsHelpSuffix),
-			append(dashboardMetricsLabels, "content_type"),
-			constantLabels,
-		),
-		byCountryBandwidth: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "bandwidth", "by_country_bytes"),
-			fmt.Sprintf("The total number of bytes served broken out by country %s", dashboardMetricsHelpSuffix),
-			append(dashboardMetricsLabels, "country_code"),
-			constantLabels,
-		),
+		api:       api,
+		opts:      opts,
+		timeouts:  opts.Timeouts,
+		budget:    opts.Budget,
+		current:   current,
+		status:    status,
+		refreshed: time.Now(),
+	}, nil
+}
 
-		allThreats: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "threats", "total"),
-			fmt.Sprintf("The total number of identifiable threats received %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
-			constantLabels,
-		),
-		byTypeThreats: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "threats", "by_type"),
-			fmt.Sprintf("The total number of identifiable threats received broken out by type %s", dashboardMetricsHelpSuffix),
-			append(dashboardMetricsLabels, "type"),
-			constantLabels,
-		),
-		byCountryThreats: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "threats", "by_country"),
-			fmt.Sprintf("The total number of identifiable threats received broken out by country %s", dashboardMetricsHelpSuffix),
-			append(dashboardMetricsLabels, "country_code"),
+// newZoneDescs returns the descriptors of a zone with the constant labels
+// constantLabels.
+func newZoneDescs(constantLabels prometheus.Labels) *zoneDescs {
+	return &zoneDescs{
+		componentProcessingTime: prometheus.NewDesc(
+			"cloudflare_exporter_component_processing_time_seconds",
+			"Component processing time in seconds",
+			[]string{"component"},
 			constantLabels,
 		),
-
-		allPageviews: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "pageviews", "total"),
-			fmt.Sprintf("The total number of pageviews served %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
+		overallProcessingTime: prometheus.NewDesc(
+			"cloudflare_exporter_processing_time_seconds",
+			"Processing time in seconds",
+			nil,
 			constantLabels,
 		),
-		bySearchEnginePageviews: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "pageviews", "by_search_engine"),
-			fmt.Sprintf("The total number of pageviews served broken out by search engine %s", dashboardMetricsHelpSuffix),
-			append(dashboardMetricsLabels, "search_engine"),
+		breakerState: prometheus.NewDesc(
+			"cloudflare_exporter_circuit_breaker_state",
+			"State of the circuit breaker guarding each component (0 closed, 1 open, 2 half-open)",
+			[]string{"component"},
 			constantLabels,
 		),
-
-		uniqueIPAddresses: prometheus.NewDesc(
-			prometheus.BuildFQName(dashboardMetricsNamespace, "unique_ip_addresses", "total"),
-			fmt.Sprintf("Total number of unique IP addresses %s", dashboardMetricsHelpSuffix),
-			dashboardMetricsLabels,
+		scrapeSuccess: prometheus.NewDesc(
+			"cloudflare_exporter_zone_scrape_success",
+			"Whether every component of the zone was collected successfully within the scrape timeout",
+			nil,
 			constantLabels,
 		),
-
-		dnsQueryTotal: prometheus.NewDesc(
-			prometheus.BuildFQName(dnsMetricsNamespace, "dns_record", "queries_total"),
-			fmt.Sprintf("Total number of DNS queries %s", dnsMetricsHelpSuffix),
-			dnsMetricsLabels,
+		seriesEmitted: prometheus.NewDesc(
+			"cloudflare_exporter_series_emitted",
+			"Number of series each component sent for the zone in this scrape, before the cardinality budget",
+			[]string{"component"},
 			constantLabels,
 		),
-		uncachedDNSQueries: prometheus.NewDesc(
-			prometheus.BuildFQName(dnsMetricsNamespace, "dns_record", "uncached_queries_total"),
-			fmt.Sprintf("Total number of uncached DNS queries %s", dnsMetricsHelpSuffix),
-			dnsMetricsLabels,
+		dataAge: prometheus.NewDesc(
+			"cloudflare_exporter_data_age_seconds",
+			"How long ago the metrics served for each component with an interval were collected",
+			[]string{"component"},
 			constantLabels,
 		),
-		staleDNSQueries: prometheus.NewDesc(
-			prometheus.BuildFQName(dnsMetricsNamespace, "dns_record", "stale_queries_total"),
-			fmt.Sprintf("Total number of DNS queries %s", dnsMetricsHelpSuffix),
-			dnsMetricsLabels,
+		zoneInfo: prometheus.NewDesc(
+			"cloudflare_zone_info",
+			"Account and hosting partner of the zone, with a constant '1' value",
+			[]string{"host"},
 			constantLabels,
 		),
-
-		componentProcessingTime: prometheus.NewDesc(
-			"cloudflare_exporter_component_processing_time_seconds",
-			"Component processing time in seconds",
-			[]string{"component"},
+		planChangesTotal: prometheus.NewDesc(
+			"cloudflare_exporter_zone_plan_changes_total",
+			"Plan changes of the zone seen since the exporter started, each of which rebuilt its collectors",
+			nil,
 			constantLabels,
 		),
-		overallProcessingTime: prometheus.NewDesc(
-			"cloudflare_exporter_processing_time_seconds",
-			"Processing time in seconds",
-			nil,
+		planChangeTime: prometheus.NewDesc(
+			"cloudflare_exporter_zone_plan_change_timestamp_seconds",
+			"When the zone's collectors were last rebuilt for a plan change, by old and new plan",
+			[]string{"from_plan", "to_plan"},
 			constantLabels,
 		),
 	}
@@ -328,166 +196,292 @@ func NewZoneExporter(api *cloudflare.API, zone cloudflare.Zone) *ZoneExporter {
 // Describe describes all the metrics exported by the cloudflare ZoneExporter. It
 // implements prometheus.Collector.
 func (e *ZoneExporter) Describe(ch chan<- *prometheus.Desc) {
-	ch <- e.allRequests
-	ch <- e.cachedRequests
-	ch <- e.uncachedRequests
-	ch <- e.encryptedRequests
-	ch <- e.unencryptedRequests
-	ch <- e.byStatusRequests
-	ch <- e.byContentTypeRequests
-	ch <- e.byCountryRequests
-	ch <- e.byIPClassRequests
-
-	ch <- e.totalBandwidth
-	ch <- e.cachedBandwidth
-	ch <- e.uncachedBandwidth
-	ch <- e.encryptedBandwidth
-	ch <- e.unencryptedBandwidth
-	ch <- e.byContentTypeBandwidth
-	ch <- e.byCountryBandwidth
-
-	ch <- e.allThreats
-	ch <- e.byTypeThreats
-	ch <- e.byCountryThreats
-
-	ch <- e.allPageviews
-	ch <- e.bySearchEnginePageviews
-
-	ch <- e.uniqueIPAddresses
-
-	ch <- e.dnsQueryTotal
-	ch <- e.uncachedDNSQueries
-	ch <- e.staleDNSQueries
+	s := e.state()
+	for _, c := range s.collectors {
+		c.Describe(ch)
+	}
 
-	ch <- e.componentProcessingTime
-	ch <- e.overallProcessingTime
+	ch <- s.componentProcessingTime
+	ch <- s.overallProcessingTime
+	ch <- s.breakerState
+	ch <- s.scrapeSuccess
+	ch <- s.seriesEmitted
+	ch <- s.dataAge
+	ch <- s.zoneInfo
+	ch <- s.planChangesTotal
+	ch <- s.planChangeTime
 }
 
 // Collect fetches the statistics for the configured Cloudflare zone, and
 // delivers them as Prometheus metrics. It implements prometheus.Collector.
 func (e *ZoneExporter) Collect(ch chan<- prometheus.Metric) {
-	start := time.Now()
-	log.Debugf("Getting data for zone %s (%s)", e.zone.Name, e.zone.ID)
-	e.collectDashboardAnalytics(ch)
-	e.collectDNSAnalytics(ch)
-	ch <- prometheus.MustNewConstMetric(e.overallProcessingTime, prometheus.GaugeValue, time.Since(start).Seconds())
+	e.collectOnly(context.Background(), ch, nil)
 }
 
-func (e *ZoneExporter) collectDashboardAnalytics(ch chan<- prometheus.Metric) {
-	now := time.Now()
-	sinceTime := now.Add(-10080 * time.Minute).UTC() // 7 days
-	if e.zone.Plan.LegacyID == "enterprise" {
-		sinceTime = now.Add(-30 * time.Minute).UTC() // Anything higher than business gets 1 minute resolution, minimum -30 minutes
-	} else if e.zone.Plan.LegacyID == "business" {
-		sinceTime = now.Add(-6 * time.Hour).UTC() // Business plans get 15 minute resolution, minimum -6 hours
-	} else if e.zone.Plan.LegacyID == "pro" {
-		sinceTime = now.Add(-24 * time.Hour).UTC() // Pro plans get 15 minute resolution, minimum -24 hours
-	}
-	continuous := true
-	opts := cloudflare.ZoneAnalyticsOptions{
-		Since:      &sinceTime,
-		Continuous: &continuous,
+// collectOnly is Collect running only the collectors in only, or all of
+// them if only is nil, within the deadline of ctx.
+func (e *ZoneExporter) collectOnly(ctx context.Context, ch chan<- prometheus.Metric, only map[string]bool) {
+	start := time.Now()
+	e.refreshIfStale()
+	s := e.state()
+	zone := s.zone
+	log.Debugf("Getting data for zone %s (%s)", zone.Name, zone.ID)
+	success := true
+	ch <- prometheus.MustNewConstMetric(s.zoneInfo, prometheus.GaugeValue, 1, zone.Host.Name)
+	e.mu.Lock()
+	planChanges, lastPlanChange := e.planChanges, e.lastPlanChange
+	e.mu.Unlock()
+	ch <- prometheus.MustNewConstMetric(s.planChangesTotal, prometheus.CounterValue, float64(planChanges))
+	if planChanges > 0 {
+		ch <- prometheus.MustNewConstMetric(s.planChangeTime, prometheus.GaugeValue, float64(lastPlanChange.time.Unix()), lastPlanChange.from, lastPlanChange.to)
 	}
-	var data []cloudflare.ZoneAnalyticsData
-	var err error
-	if e.zone.Plan.LegacyID == "enterprise" {
-		data, err = e.cf.ZoneAnalyticsByColocation(e.zone.ID, opts)
+
+	// With a cardinality budget, zone metrics pass through its filter before
+	// reaching ch.
+	out := ch
+	done := make(chan struct{})
+	if e.budget != nil {
+		filtered := make(chan prometheus.Metric)
+		out = filtered
+		go func() {
+			e.budget.Filter(zone, filtered, ch)
+			close(done)
+		}()
 	} else {
-		singleData, singleDataErr := e.cf.ZoneAnalyticsDashboard(e.zone.ID, opts)
-		err = singleDataErr
-		data = append(data, singleData)
+		close(done)
 	}
-	if err != nil {
-		log.Errorf("failed to get dashboard analytics from cloudflare for zone %s: %s", e.zone.Name, err)
-		return
-	}
-
-	for _, entry := range data {
-		labels := []string{}
 
-		if e.zone.Plan.LegacyID == "enterprise" {
-			pop := getPop(entry.ColocationID)
-			labels = append(labels, pop.Code, pop.Name, pop.Region)
+	for _, c := range s.collectors {
+		if only != nil && !only[c.Name()] {
+			continue
 		}
-
-		latestEntry := entry.Timeseries[len(entry.Timeseries)-1]
-
-		ch <- prometheus.MustNewConstMetric(e.allRequests, prometheus.GaugeValue, float64(latestEntry.Requests.All), labels...)
-		ch <- prometheus.MustNewConstMetric(e.cachedRequests, prometheus.GaugeValue, float64(latestEntry.Requests.Cached), labels...)
-		ch <- prometheus.MustNewConstMetric(e.uncachedRequests, prometheus.GaugeValue, float64(latestEntry.Requests.Uncached), labels...)
-		ch <- prometheus.MustNewConstMetric(e.encryptedRequests, prometheus.GaugeValue, float64(latestEntry.Requests.SSL.Encrypted), labels...)
-		ch <- prometheus.MustNewConstMetric(e.unencryptedRequests, prometheus.GaugeValue, float64(latestEntry.Requests.SSL.Unencrypted), labels...)
-		for code, count := range latestEntry.Requests.HTTPStatus {
-			ch <- prometheus.MustNewConstMetric(e.byStatusRequests, prometheus.GaugeValue, float64(count), append(labels, code)...)
+		breaker := s.breakers[c.Name()]
+		schedule := s.schedules[c.Name()]
+		componentStart := time.Now()
+		var kept *[]prometheus.Metric
+		if schedule != nil {
+			kept = &[]prometheus.Metric{}
 		}
-		for contentType, count := range latestEntry.Requests.ContentType {
-			ch <- prometheus.MustNewConstMetric(e.byContentTypeRequests, prometheus.GaugeValue, float64(count), append(labels, contentType)...)
+		collect := func(ch chan<- prometheus.Metric, kept *[]prometheus.Metric) (int, error) {
+			collectorCtx, cancel := collectorContext(ctx, e.timeouts[c.Name()])
+			defer cancel()
+			series, err := collectCounted(collectorCtx, c, zone, ch, kept)
+			if err == errScrapeTimeout && ctx.Err() == nil {
+				err = errCollectorTimeout
+			}
+			return series, err
 		}
-		for country, count := range latestEntry.Requests.Country {
-			ch <- prometheus.MustNewConstMetric(e.byCountryRequests, prometheus.GaugeValue, float64(count), append(labels, country)...)
+		emitted := 0
+		if !schedule.Due(componentStart) {
+			log.Debugf("Serving last run of %s collector for zone %s, next run is not due yet", c.Name(), zone.Name)
+			emitted = schedule.Replay(out)
+		} else if ctx.Err() != nil {
+			log.Debugf("Skipping %s collector for zone %s, scrape timeout reached", c.Name(), zone.Name)
+			success = false
+		} else if !breaker.Allow(componentStart) {
+			log.Debugf("Skipping %s collector for zone %s, circuit is open", c.Name(), zone.Name)
+			success = false
+		} else if series, shared, err := s.flights[c.Name()].do(ctx, out, kept, collect); err == errScrapeTimeout {
+			// Running out of time is not the collector's fault, so the
+			// breaker is left alone.
+			log.Warnf("Abandoned %s collector for zone %s, scrape timeout reached", c.Name(), zone.Name)
+			emitted = series
+			success = false
+		} else if err != nil {
+			emitted = series
+			success = false
+			// A run shared with a concurrent scrape is accounted for
+			// by that scrape.
+			if !shared {
+				breaker.Failure(time.Now())
+				e.recordFailure(c.Name(), err)
+				collectorErrors.reportZone(zone, c.Name(), err)
+			}
+		} else {
+			if !shared {
+				breaker.Success()
+				if kept != nil {
+					schedule.Record(componentStart, *kept)
+				}
+				e.recordSuccess(c.Name(), series)
+				collectorErrors.resolveZone(zone, c.Name())
+			}
+			emitted = series
+			ch <- prometheus.MustNewConstMetric(s.componentProcessingTime, prometheus.GaugeValue, time.Since(componentStart).Seconds(), c.Name())
 		}
-		for class, count := range latestEntry.Requests.IPClass {
-			ch <- prometheus.MustNewConstMetric(e.byIPClassRequests, prometheus.GaugeValue, float64(count), append(labels, class)...)
+		ch <- prometheus.MustNewConstMetric(s.breakerState, prometheus.GaugeValue, float64(breaker.State(time.Now())), c.Name())
+		ch <- prometheus.MustNewConstMetric(s.seriesEmitted, prometheus.GaugeValue, float64(emitted), c.Name())
+		if age, ok := schedule.Age(time.Now()); ok {
+			ch <- prometheus.MustNewConstMetric(s.dataAge, prometheus.GaugeValue, age.Seconds(), c.Name())
 		}
+	}
+	if e.budget != nil {
+		close(out)
+	}
+	<-done
+	ch <- prometheus.MustNewConstMetric(s.overallProcessingTime, prometheus.GaugeValue, time.Since(start).Seconds())
+	successValue := 0.0
+	if success {
+		successValue = 1
+	}
+	ch <- prometheus.MustNewConstMetric(s.scrapeSuccess, prometheus.GaugeValue, successValue)
+}
 
-		ch <- prometheus.MustNewConstMetric(e.totalBandwidth, prometheus.GaugeValue, float64(latestEntry.Bandwidth.All), labels...)
-		ch <- prometheus.MustNewConstMetric(e.cachedBandwidth, prometheus.GaugeValue, float64(latestEntry.Bandwidth.Cached), labels...)
-		ch <- prometheus.MustNewConstMetric(e.uncachedBandwidth, prometheus.GaugeValue, float64(latestEntry.Bandwidth.Uncached), labels...)
-		ch <- prometheus.MustNewConstMetric(e.encryptedBandwidth, prometheus.GaugeValue, float64(latestEntry.Bandwidth.SSL.Encrypted), labels...)
-		ch <- prometheus.MustNewConstMetric(e.unencryptedBandwidth, prometheus.GaugeValue, float64(latestEntry.Bandwidth.SSL.Unencrypted), labels...)
-		for contentType, count := range latestEntry.Bandwidth.ContentType {
-			ch <- prometheus.MustNewConstMetric(e.byContentTypeBandwidth, prometheus.GaugeValue, float64(count), append(labels, contentType)...)
-		}
-		for country, count := range latestEntry.Bandwidth.Country {
-			ch <- prometheus.MustNewConstMetric(e.byCountryBandwidth, prometheus.GaugeValue, float64(count), append(labels, country)...)
+// collectCounted runs c, forwarding its metrics to ch and appending them to
+// kept if it is not nil, and returns the number of metrics sent. When ctx is
+// done before c returns, c is abandoned with the metrics it sent so far and
+// errScrapeTimeout is returned; c keeps running in the background and the
+// rest of its metrics are dropped.
+func collectCounted(ctx context.Context, c collector.Collector, zone cloudflare.Zone, ch chan<- prometheus.Metric, kept *[]prometheus.Metric) (int, error) {
+	counted := make(chan prometheus.Metric)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Collect(ctx, zone, counted)
+		close(counted)
+	}()
+	n := 0
+	for {
+		select {
+		case m, ok := <-counted:
+			if !ok {
+				err := <-errc
+				if err != nil && ctx.Err() != nil {
+					return n, errScrapeTimeout
+				}
+				return n, err
+			}
+			ch <- m
+			if kept != nil {
+				*kept = append(*kept, m)
+			}
+			n++
+		case <-ctx.Done():
+			go func() {
+				for range counted {
+				}
+			}()
+			return n, errScrapeTimeout
 		}
+	}
+}
 
-		ch <- prometheus.MustNewConstMetric(e.allThreats, prometheus.GaugeValue, float64(latestEntry.Threats.All), labels...)
-		for threatType, count := range latestEntry.Threats.Type {
-			ch <- prometheus.MustNewConstMetric(e.byTypeThreats, prometheus.GaugeValue, float64(count), append(labels, threatType)...)
-		}
-		for country, count := range latestEntry.Threats.Country {
-			ch <- prometheus.MustNewConstMetric(e.byCountryThreats, prometheus.GaugeValue, float64(count), append(labels, country)...)
-		}
+func (e *ZoneExporter) recordSuccess(name string, series int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status[name].LastSuccess = time.Now()
+	e.status[name].Series = series
+}
 
-		ch <- prometheus.MustNewConstMetric(e.allPageviews, prometheus.GaugeValue, float64(latestEntry.Pageviews.All), labels...)
-		for searchEngine, count := range latestEntry.Pageviews.SearchEngines {
-			ch <- prometheus.MustNewConstMetric(e.bySearchEnginePageviews, prometheus.GaugeValue, float64(count), append(labels, searchEngine)...)
-		}
+func (e *ZoneExporter) recordFailure(name string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status[name].LastError = err.Error()
+	e.status[name].LastErrorTime = time.Now()
+}
+
+// Zone returns the zone the exporter collects metrics for, as of its last
+// refresh.
+func (e *ZoneExporter) Zone() cloudflare.Zone {
+	return e.state().zone
+}
 
-		ch <- prometheus.MustNewConstMetric(e.uniqueIPAddresses, prometheus.GaugeValue, float64(latestEntry.Uniques.All), labels...)
+// Status returns the status of the zone's collectors, in the order they run.
+func (e *ZoneExporter) Status() []CollectorStatus {
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	status := make([]CollectorStatus, 0, len(e.current.collectors))
+	for _, c := range e.current.collectors {
+		s := *e.status[c.Name()]
+		s.Breaker = e.current.breakers[c.Name()].State(now)
+		status = append(status, s)
 	}
-	ch <- prometheus.MustNewConstMetric(e.componentProcessingTime, prometheus.GaugeValue, time.Since(now).Seconds(), "dashboard_analytics")
+	return status
 }
 
-func (e *ZoneExporter) collectDNSAnalytics(ch chan<- prometheus.Metric) {
-	start := time.Now()
+func (e *ZoneExporter) state() *zoneState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.current
+}
 
-	data, err := e.cf.ZoneDNSAnalyticsByTime(e.zone.ID, cloudflare.ZoneDNSAnalyticsOptions{
-		Metrics:    e.dnsMetrics,
-		Dimensions: e.dnsDimensions,
-	})
-	if err != nil {
-		log.Errorf("failed to get dns analytics from cloudflare for zone %s: %s", e.zone.Name, err)
+// refreshIfStale starts refreshing the zone in the background if it was
+// last refreshed more than Options.ZoneRefresh ago. The scrape goes on with
+// the current zone and collectors.
+func (e *ZoneExporter) refreshIfStale() {
+	if e.opts.ZoneRefresh <= 0 {
 		return
 	}
+	e.mu.Lock()
+	stale := !e.refreshing && time.Since(e.refreshed) >= e.opts.ZoneRefresh
+	if stale {
+		e.refreshing = true
+	}
+	e.mu.Unlock()
+	if stale {
+		go e.refresh()
+	}
+}
 
-	for _, row := range data.Rows {
-		queryCount := row.Metrics[0][len(row.Metrics[0])-1]
-		uncachedCount := row.Metrics[1][len(row.Metrics[1])-1]
-		staleCount := row.Metrics[2][len(row.Metrics[2])-1]
-
-		labels := row.Dimensions
+// refresh gets the zone's details again, through the API cache, instead
+// of keeping the zone as listed at startup forever. When its plan changed,
+// which decides the collectors that run and the dimensions and namespaces
+// they export, the collectors are selected and built again. When the labels
+// identifying it changed, such as its name, the collectors are built again
+// with them.
+func (e *ZoneExporter) refresh() {
+	defer func() {
+		e.mu.Lock()
+		e.refreshing = false
+		e.refreshed = time.Now()
+		e.mu.Unlock()
+	}()
+	current := e.state()
+	zone, err := e.api.ZoneDetails(current.zone.ID)
+	if err != nil {
+		log.Errorf("failed to refresh zone %s: %s", current.zone.Name, err)
+		return
+	}
+	planChanged := zone.Plan.ID != current.zone.Plan.ID || zone.Plan.LegacyID != current.zone.Plan.LegacyID
+	if !planChanged && reflect.DeepEqual(collector.ZoneLabels(zone, e.opts), collector.ZoneLabels(current.zone, e.opts)) {
+		next := *current
+		next.zone = zone
+		e.mu.Lock()
+		e.current = &next
+		e.mu.Unlock()
+		return
+	}
 
-		if e.dnsDimensions[len(e.dnsDimensions)-1] == "coloName" {
-			labels = row.Dimensions[:len(row.Dimensions)-1]
-			pop := getPop(row.Dimensions[len(row.Dimensions)-1])
-			labels = append(labels, pop.Code, pop.Name, pop.Region)
+	var names []string
+	if planChanged {
+		log.Infof("Plan of zone %s changed from %s to %s, rebuilding its collectors", zone.Name, current.zone.Plan.LegacyID, zone.Plan.LegacyID)
+		if names, err = collector.Select(e.api, zone, e.opts); err != nil {
+			log.Errorf("failed to select collectors for zone %s: %s", zone.Name, err)
+			return
+		}
+	} else {
+		log.Infof("Labels of zone %s changed, rebuilding its collectors", zone.Name)
+		for _, c := range current.collectors {
+			names = append(names, c.Name())
+		}
+	}
+	next, err := newZoneState(e.api, zone, e.opts, names...)
+	if err != nil {
+		log.Errorf("failed to rebuild collectors for zone %s: %s", zone.Name, err)
+		return
+	}
+	// Scrapes register the exporter anew, so the next one picks up the
+	// descriptors of the rebuilt collectors.
+	e.mu.Lock()
+	e.current = next
+	if planChanged {
+		e.planChanges++
+		e.lastPlanChange = planChange{from: current.zone.Plan.LegacyID, to: zone.Plan.LegacyID, time: time.Now()}
+	}
+	for _, c := range next.collectors {
+		if _, ok := e.status[c.Name()]; !ok {
+			e.status[c.Name()] = &CollectorStatus{Name: c.Name()}
 		}
-
-		ch <- prometheus.MustNewConstMetric(e.dnsQueryTotal, prometheus.GaugeValue, queryCount, labels...)
-		ch <- prometheus.MustNewConstMetric(e.uncachedDNSQueries, prometheus.GaugeValue, uncachedCount, labels...)
-		ch <- prometheus.MustNewConstMetric(e.staleDNSQueries, prometheus.GaugeValue, staleCount, labels...)
 	}
-	ch <- prometheus.MustNewConstMetric(e.componentProcessingTime, prometheus.GaugeValue, time.Since(start).Seconds(), "dns_analytics")
+	e.mu.Unlock()
 }
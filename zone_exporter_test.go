@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robbiet480/cloudflare-go"
+	"github.com/robbiet480/cloudflare_exporter/collector"
+)
+
+// renamingAPI returns zone from ZoneDetails. Other calls are not made.
+type renamingAPI struct {
+	collector.API
+	zone cloudflare.Zone
+}
+
+func (a *renamingAPI) ZoneDetails(zoneID string) (cloudflare.Zone, error) {
+	return a.zone, nil
+}
+
+func TestZoneExporterRename(t *testing.T) {
+	zone := cloudflare.Zone{ID: "023e105f4ecef8ad9ca31a8372d0c353", Name: "example.com"}
+	zone.Plan.ID, zone.Plan.LegacyID = "free", "free"
+	api := &renamingAPI{zone: zone}
+	e, err := NewZoneExporter(api, zone, collector.Options{}, "dashboard_analytics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api.zone.Name = "example.org"
+	e.refresh()
+	if e.Zone().Name != "example.org" {
+		t.Fatalf("got zone %s after the refresh, want example.org", e.Zone().Name)
+	}
+	ch := make(chan *prometheus.Desc, 1000)
+	e.Describe(ch)
+	close(ch)
+	for desc := range ch {
+		if strings.Contains(desc.String(), `"example.com"`) {
+			t.Errorf("descriptor kept the old zone name after the rename: %s", desc)
+		}
+	}
+	if e.planChanges != 0 {
+		t.Errorf("got %d plan changes, want a rename not to count as one", e.planChanges)
+	}
+}